@@ -1,15 +1,29 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/trussworks/truss-aws-tools/internal/aws/session"
 	"github.com/trussworks/truss-aws-tools/internal/aws/ssm"
 	"github.com/trussworks/truss-aws-tools/pkg/awshealth"
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
+	"github.com/trussworks/truss-aws-tools/pkg/slackweb"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/health"
+	awsssm "github.com/aws/aws-sdk-go/service/ssm"
 	flag "github.com/jessevdk/go-flags"
 	"github.com/lytics/slackhook"
 	"go.uber.org/zap"
@@ -22,71 +36,523 @@ type Options struct {
 	SlackChannel       string `long:"slack-channel" description:"The Slack channel." required:"true" env:"SLACK_CHANNEL"`
 	SlackEmoji         string `long:"slack-emoji" description:"The Slack Emoji associated with the notifications." env:"SLACK_EMOJI" default:":boom:"`
 	SSMSlackWebhookURL string `long:"ssm-slack-webhook-url" description:"The name of the Slack Webhook Url in Parameter store." required:"false" env:"SSM_SLACK_WEBHOOK_URL"`
+	SSMSlackToken      string `long:"ssm-slack-token" description:"The name of a Slack bot token in Parameter store. When set, events are posted with Block Kit and updates are threaded." required:"false" env:"SSM_SLACK_TOKEN"`
+	DynamoDBTable      string `long:"dynamodb-table" description:"The DynamoDB table threads are stored in, keyed by EventARN, which holds the event ARN and the affected account of organization events. Threads are only kept in memory when empty." required:"false" env:"DYNAMODB_TABLE"`
+	SlackTemplate      string `long:"slack-template" description:"A text/template file, or ssm:<parameter name>, rendering the Slack attachment." required:"false" env:"SLACK_TEMPLATE"`
+	NotifierConfig     string `long:"notifier-config" description:"A JSON notifier config file, or ssm:<parameter name>, listing the sinks events are sent to instead of Slack." required:"false" env:"NOTIFIER_CONFIG"`
+	RoutingConfig      string `long:"routing-config" description:"A JSON routing config file, or ssm:<parameter name>, choosing the channel, color and mentions for each event, or dropping it." required:"false" env:"ROUTING_CONFIG"`
+	Lambda             bool   `short:"l" long:"lambda" description:"Run as an AWS lambda function, which is the default inside Lambda. Otherwise events are read from the files given as arguments, or stdin." required:"false" env:"LAMBDA"`
+	DryRun             bool   `long:"dry-run" description:"Print the rendered notification as JSON instead of sending it." required:"false" env:"DRY_RUN"`
+	DedupeTTL          int    `long:"dedupe-ttl" description:"Minutes a delivered event is remembered so repeat deliveries are skipped. 0 disables deduplication." required:"false" env:"DEDUPE_TTL" default:"1440"`
+	RateLimit          int    `long:"rate-limit" description:"The most events sent to a channel per rate limit window. Events past it are sent as one digest once the window ends, by the next invocation or a scheduled one. 0 disables rate limiting." required:"false" env:"RATE_LIMIT" default:"0"`
+	RateWindow         int    `long:"rate-window" description:"The rate limit window in seconds." required:"false" env:"RATE_WINDOW" default:"300"`
+	StateTable         string `long:"state-table" description:"The DynamoDB table dedupe keys and rate limit windows are stored in, keyed by Key. They are only kept in memory when empty." required:"false" env:"STATE_TABLE"`
+	Poll               bool   `long:"poll" description:"Pull events updated since the checkpoint from the AWS Health API instead of handling CloudWatch events." required:"false" env:"POLL"`
+	Organization       bool   `long:"organization" description:"Poll the organizational view of the AWS Health API, covering every account in the AWS Organization." required:"false" env:"ORGANIZATION"`
+	Checkpoint         string `long:"checkpoint" description:"A local file, or ssm:<parameter name>, the poll checkpoint is kept in." required:"false" env:"CHECKPOINT"`
+	PollLookback       int    `long:"poll-lookback" description:"Hours of events the first poll, before there is a checkpoint, notifies about." required:"false" env:"POLL_LOOKBACK" default:"24"`
 }
 
 var options Options
 var logger *zap.Logger
+var memoryThreads awshealth.MemoryThreadStore
+var memoryDedupe awshealth.MemoryDedupeStore
+var memoryRates awshealth.MemoryRateStore
 
-func sendNotification(event events.CloudWatchEvent) {
-	var health awshealth.Event
-	err := json.Unmarshal([]byte(event.Detail), &health)
+// heldRoutes are the routes of channels with events held back by the rate
+// limit, so a replay can send their digests when it is done.
+var heldRoutes = map[string]awshealth.Route{}
+
+// scheduledEventType is the detail type of EventBridge schedule rules, which
+// only flush the rate limit digests.
+const scheduledEventType = "Scheduled Event"
+
+// handleEvent notifies about a single CloudWatch event, then sends the
+// digests of rate limit windows that have ended. Events that are not health
+// events are returned as errors before anything else is done, so Lambda
+// retries them and sends them to its dead letter queue.
+func handleEvent(ctx context.Context, event events.CloudWatchEvent) error {
+	if event.DetailType == scheduledEventType {
+		return flushExpiredDigests(ctx, session.MustMakeSession(options.Region, options.Profile))
+	}
+
+	health, err := awshealth.ParseCloudWatchEvent(event)
 	if err != nil {
-		logger.Error("Unable to unmarshal health event", zap.Error(err))
+		logger.Error("invalid health event", zap.String("event-id", event.ID), zap.Error(err))
+		return err
 	}
 
-	eventURL := health.HealthEventURL()
 	awsSession := session.MustMakeSession(options.Region, options.Profile)
+	err = notifyEvent(ctx, awsSession, health)
+	if flushErr := flushExpiredDigests(ctx, awsSession); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// notifyEvent sends an event that was not already sent.
+func notifyEvent(ctx context.Context, awsSession *awssession.Session, health *awshealth.Event) error {
+	var dedupe awshealth.DedupeStore
+	if options.DedupeTTL > 0 {
+		dedupe = dedupeStore(awsSession)
+		seen, err := dedupe.MarkSeen(health.DedupeKey(), time.Now())
+		if err != nil {
+			logger.Error("failed to check for duplicate event", zap.Error(err))
+			return err
+		}
+		if seen {
+			logger.Info("skipping duplicate health event",
+				zap.String("event-arn", health.EventARN),
+				zap.String("dedupe-key", health.DedupeKey()),
+			)
+			return nil
+		}
+	}
+
+	err := sendNotification(ctx, awsSession, health)
+	if err != nil {
+		logger.Error("failed to send notification", zap.Error(err),
+			zap.String("event-arn", health.EventARN))
+		if dedupe != nil {
+			if forgetErr := dedupe.Forget(health.DedupeKey()); forgetErr != nil {
+				logger.Error("failed to forget dedupe key", zap.Error(forgetErr))
+			}
+		}
+	}
+	return err
+}
+
+// pollEvents notifies about the events updated in the Health API since the
+// checkpoint, oldest first. The checkpoint is advanced past the events that
+// were sent, so a failed poll resumes from the event that failed.
+func pollEvents(ctx context.Context) error {
+	awsSession := session.MustMakeSession(options.Region, options.Profile)
+	store := checkpointStore(awsSession)
+	since, ok, err := store.GetCheckpoint()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		since = time.Now().Add(-time.Duration(options.PollLookback) * time.Hour)
+	}
+
+	p := awshealth.Poller{
+		HealthClient: health.New(awsSession, aws.NewConfig().WithRegion(awshealth.HealthAPIRegion)),
+		Organization: options.Organization,
+	}
+	polled, err := p.Poll(ctx, since)
+	if err != nil {
+		return err
+	}
+	logger.Info("polled health events",
+		zap.Time("since", since),
+		zap.Int("events", len(polled)),
+	)
+
+	sent := 0
+	for _, h := range polled {
+		err = notifyEvent(ctx, awsSession, h)
+		if err != nil {
+			break
+		}
+		sent++
+	}
+	checkpoint := awshealth.ResumeCheckpoint(polled, sent, since)
+	if flushErr := flushDigests(ctx); err == nil {
+		err = flushErr
+	}
+	if !options.DryRun && checkpoint.After(since) {
+		if putErr := store.PutCheckpoint(checkpoint); putErr != nil {
+			return putErr
+		}
+	}
+	return err
+}
+
+func checkpointStore(awsSession *awssession.Session) awshealth.CheckpointStore {
+	if strings.HasPrefix(options.Checkpoint, slacktemplate.SSMPrefix) {
+		return &awshealth.SSMCheckpointStore{
+			SSMClient: awsssm.New(awsSession),
+			Name:      strings.TrimPrefix(options.Checkpoint, slacktemplate.SSMPrefix),
+		}
+	}
+	return &awshealth.FileCheckpointStore{Path: options.Checkpoint}
+}
+
+// dedupeStore is where dedupe keys are kept. Dry runs never write to the
+// state table.
+func dedupeStore(awsSession *awssession.Session) awshealth.DedupeStore {
+	if options.StateTable == "" || options.DryRun {
+		return &memoryDedupe
+	}
+	return &awshealth.DynamoDBDedupeStore{
+		DynamoDBClient: dynamodb.New(awsSession),
+		TableName:      options.StateTable,
+		TTL:            time.Duration(options.DedupeTTL) * time.Minute,
+	}
+}
+
+func rateLimiter(awsSession *awssession.Session) *awshealth.RateLimiter {
+	r := &awshealth.RateLimiter{
+		Limit:  options.RateLimit,
+		Window: time.Duration(options.RateWindow) * time.Second,
+		Store:  &memoryRates,
+	}
+	if options.StateTable != "" && !options.DryRun {
+		r.Store = &awshealth.DynamoDBRateStore{
+			DynamoDBClient: dynamodb.New(awsSession),
+			TableName:      options.StateTable,
+		}
+	}
+	return r
+}
+
+// limitRate applies the rate limit to an event on route, first sending the
+// digest of events held back in the channel's last window. It reports
+// whether the event itself may be sent.
+func limitRate(ctx context.Context, awsSession *awssession.Session, health *awshealth.Event, route awshealth.Route) (bool, error) {
+	allowed, digest, err := rateLimiter(awsSession).Allow(route.Channel, health, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if len(digest) > 0 {
+		err = sendDigest(ctx, awsSession, route, digest)
+		if err != nil {
+			return false, err
+		}
+	}
+	if !allowed {
+		heldRoutes[route.Channel] = route
+		logger.Info("holding back health event for the rate limit digest",
+			zap.String("event-arn", health.EventARN),
+			zap.String("slack-channel", route.Channel),
+		)
+	}
+	return allowed, nil
+}
+
+// flushDigests sends the digests of every channel with held back events.
+func flushDigests(ctx context.Context) error {
+	awsSession := session.MustMakeSession(options.Region, options.Profile)
+	limiter := rateLimiter(awsSession)
+	for channel, route := range heldRoutes {
+		held, err := limiter.Flush(channel)
+		if err != nil {
+			return err
+		}
+		if len(held) > 0 {
+			err = sendDigest(ctx, awsSession, route, held)
+			if err != nil {
+				return err
+			}
+		}
+		delete(heldRoutes, channel)
+	}
+	return nil
+}
+
+// flushExpiredDigests sends the digests of every routed channel whose rate
+// limit window has ended with events held back. Without it a channel's
+// digest waits for the channel's next event, which may never come. Channels
+// whose route is not in heldRoutes, such as after a cold start, get their
+// digest without mentions.
+func flushExpiredDigests(ctx context.Context, awsSession *awssession.Session) error {
+	if options.RateLimit <= 0 {
+		return nil
+	}
+	config, err := loadRoutingConfig(awsSession)
+	if err != nil {
+		return err
+	}
+
+	limiter := rateLimiter(awsSession)
+	now := time.Now()
+	for _, channel := range config.Channels(options.SlackChannel) {
+		held, err := limiter.FlushExpired(channel, now)
+		if err != nil {
+			return err
+		}
+		if len(held) == 0 {
+			continue
+		}
+		route, ok := heldRoutes[channel]
+		if !ok {
+			route = awshealth.Route{Channel: channel}
+		}
+		err = sendDigest(ctx, awsSession, route, held)
+		if err != nil {
+			return err
+		}
+		delete(heldRoutes, channel)
+	}
+	return nil
+}
+
+// sendDigest sends the digest of held back events the same way events are
+// sent, as a plain message.
+func sendDigest(ctx context.Context, awsSession *awssession.Session, route awshealth.Route, lines []string) error {
+	if options.NotifierConfig != "" {
+		message := awshealth.DigestNotifyMessage(lines, route)
+		if options.DryRun {
+			return printJSON(message)
+		}
+		notifiers, err := loadNotifiers(awsSession)
+		if err != nil {
+			return err
+		}
+		return notifiers.Notify(ctx, message)
+	}
+
+	text := awshealth.DigestText(lines)
+	if len(route.Mentions) > 0 {
+		text = strings.Join(route.Mentions, " ") + " " + text
+	}
+	if options.SSMSlackToken != "" {
+		message := &slackweb.Message{
+			Channel:   route.Channel,
+			Text:      text,
+			IconEmoji: options.SlackEmoji,
+		}
+		if options.DryRun {
+			return printJSON(message)
+		}
+		token, err := ssm.DecryptValue(awsSession, options.SSMSlackToken)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt slack token: %w", err)
+		}
+		client := slackweb.Client{Token: token}
+		_, err = client.PostMessage(ctx, message)
+		if err != nil {
+			return fmt.Errorf("failed to send digest to %s: %w", route.Channel, err)
+		}
+		return nil
+	}
+
+	message := &slackhook.Message{
+		Channel:   route.Channel,
+		IconEmoji: options.SlackEmoji,
+		Text:      text,
+	}
+	if options.DryRun {
+		return printJSON(message)
+	}
 	slackWebhookURL, err := ssm.DecryptValue(awsSession, options.SSMSlackWebhookURL)
 	if err != nil {
-		logger.Fatal("failed to decrypt slackWebhookURL", zap.Error(err))
-	}
-	slack := slackhook.New(slackWebhookURL)
-	description := "no description found in health check"
-	if len(health.Description) > 0 {
-		description = health.Description[0].Latest
-	}
-
-	attachment := slackhook.Attachment{
-		Title:     "AWS Health Notification",
-		TitleLink: awshealth.PersonalHealthDashboardURL,
-		Color:     "danger",
-		Fields: []slackhook.Field{
-			{
-				Title: "Service",
-				Value: health.Service,
-			},
-			{
-				Title: "Description",
-				Value: description,
-			},
-			{
-				Title: "EventTypeCode",
-				Value: health.EventTypeCode,
-			},
-			{
-				Title: "Link",
-				Value: eventURL,
-				Short: false,
-			},
-		},
+		return fmt.Errorf("failed to decrypt slackWebhookURL: %w", err)
+	}
+	err = slackhook.New(slackWebhookURL).Send(message)
+	if err != nil {
+		return fmt.Errorf("failed to send digest to %s: %w", route.Channel, err)
+	}
+	return nil
+}
+
+func sendNotification(ctx context.Context, awsSession *awssession.Session, health *awshealth.Event) error {
+	route, err := routeEvent(awsSession, health)
+	if err != nil {
+		return err
+	}
+	if route.Drop {
+		logger.Info("dropping health event",
+			zap.String("event-arn", health.EventARN),
+			zap.String("event-type-code", health.EventTypeCode),
+		)
+		return nil
+	}
+	if options.RateLimit > 0 {
+		allowed, err := limitRate(ctx, awsSession, health, route)
+		if err != nil || !allowed {
+			return err
+		}
+	}
+
+	data := awshealth.NewMessageData(health, aws.StringValue(awsSession.Config.Region))
+	if options.NotifierConfig != "" {
+		message := awshealth.NotifyMessage(data, route)
+		if options.DryRun {
+			return printJSON(message)
+		}
+		notifiers, err := loadNotifiers(awsSession)
+		if err != nil {
+			return err
+		}
+		err = notifiers.Notify(ctx, message)
+		if err != nil {
+			return err
+		}
+		logger.Info("successfully sent notification", zap.Int("notifiers", len(notifiers)))
+		return nil
+	}
+
+	if options.SSMSlackToken != "" {
+		return sendThreadedNotification(ctx, awsSession, health, route)
+	}
+
+	var tmpl *template.Template
+	if options.SlackTemplate != "" {
+		tmpl, err = slacktemplate.LoadTemplate(awsSession, options.SlackTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to load slack template: %w", err)
+		}
+	}
+	data.Color = route.Color
+	attachment, err := awshealth.SlackAttachment(tmpl, data)
+	if err != nil {
+		return fmt.Errorf("failed to render slack message: %w", err)
 	}
 
 	message := &slackhook.Message{
-		Channel:   options.SlackChannel,
+		Channel:   route.Channel,
 		IconEmoji: options.SlackEmoji,
+		Text:      strings.Join(route.Mentions, " "),
+	}
+	message.AddAttachment(attachment)
+	if options.DryRun {
+		return printJSON(message)
+	}
+
+	slackWebhookURL, err := ssm.DecryptValue(awsSession, options.SSMSlackWebhookURL)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt slackWebhookURL: %w", err)
+	}
+	err = slackhook.New(slackWebhookURL).Send(message)
+	if err != nil {
+		return fmt.Errorf("failed to send slack message to %s: %w", route.Channel, err)
 	}
-	message.AddAttachment(&attachment)
+	logger.Info("successfully sent slack message", zap.String("slack-channel", route.Channel))
+	return nil
+}
 
-	err = slack.Send(message)
+// loadNotifiers builds the notifiers listed in --notifier-config.
+func loadNotifiers(awsSession *awssession.Session) (notify.Multi, error) {
+	configs, err := notify.LoadConfig(awsSession, options.NotifierConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notifier config: %w", err)
+	}
+	notifiers, err := notify.NewMulti(awsSession, configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make notifiers: %w", err)
+	}
+	return notifiers, nil
+}
+
+// routeEvent applies the routing config, if there is one, to the event.
+func routeEvent(awsSession *awssession.Session, health *awshealth.Event) (awshealth.Route, error) {
+	config, err := loadRoutingConfig(awsSession)
+	if err != nil {
+		return awshealth.Route{}, err
+	}
+	return config.Route(health, options.SlackChannel), nil
+}
+
+// loadRoutingConfig loads --routing-config, or returns nil when it is unset.
+func loadRoutingConfig(awsSession *awssession.Session) (*awshealth.RoutingConfig, error) {
+	if options.RoutingConfig == "" {
+		return nil, nil
+	}
+	content, err := slacktemplate.Load(awsSession, options.RoutingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routing config: %w", err)
+	}
+	config, err := awshealth.ParseRoutingConfig([]byte(content))
 	if err != nil {
-		logger.Error("failed to send slack message", zap.Error(err),
-			zap.String("slack-channel", options.SlackChannel))
+		return nil, fmt.Errorf("failed to parse routing config: %w", err)
 	}
-	logger.Info("successfully sent slack message", zap.String("slack-channel", options.SlackChannel))
+	return config, nil
+}
+
+func sendThreadedNotification(ctx context.Context, awsSession *awssession.Session, health *awshealth.Event, route awshealth.Route) error {
+	n := awshealth.ThreadedNotifier{
+		Channel:  route.Channel,
+		Emoji:    options.SlackEmoji,
+		Logger:   logger,
+		Mentions: route.Mentions,
+		Store:    &memoryThreads,
+	}
+	if options.DryRun {
+		return printJSON(n.Message(health))
+	}
+
+	token, err := ssm.DecryptValue(awsSession, options.SSMSlackToken)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt slack token: %w", err)
+	}
+	n.Slack = &slackweb.Client{Token: token}
+	if options.DynamoDBTable != "" {
+		n.Store = &awshealth.DynamoDBThreadStore{
+			DynamoDBClient: dynamodb.New(awsSession),
+			TableName:      options.DynamoDBTable,
+		}
+	}
+
+	err = n.Notify(ctx, health)
+	if err != nil {
+		return fmt.Errorf("failed to send slack message to %s: %w", route.Channel, err)
+	}
+	return nil
+}
+
+// printJSON writes a rendered notification to stdout.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// replayEvents handles every event in the given files, reading stdin when
+// there are none or a file is "-".
+func replayEvents(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	for _, path := range paths {
+		cwEvents, err := readEventFile(path)
+		if err != nil {
+			return err
+		}
+		for _, event := range cwEvents {
+			err = handleEvent(ctx, event)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return flushDigests(ctx)
+}
+
+func readEventFile(path string) ([]events.CloudWatchEvent, error) {
+	if path == "-" {
+		cwEvents, err := awshealth.ReadCloudWatchEvents(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("stdin: %w", err)
+		}
+		return cwEvents, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cwEvents, err := awshealth.ReadCloudWatchEvents(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cwEvents, nil
+}
+
+// inLambda reports whether the tool runs as a Lambda function: with
+// --lambda, or inside Lambda, where AWS_LAMBDA_FUNCTION_NAME is always set.
+// Deployments from before the command line mode existed set neither flag.
+func inLambda() bool {
+	return options.Lambda || os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != ""
 }
 
 func lambdaHandler() {
-	lambda.Start(sendNotification)
+	if options.Poll {
+		lambda.Start(pollEvents)
+		return
+	}
+	lambda.Start(handleEvent)
 }
 
 func main() {
@@ -96,12 +562,32 @@ func main() {
 		log.Fatalf("can't initialize zap logger: %v", err)
 	}
 	parser := flag.NewParser(&options, flag.Default)
+	parser.Usage = "[OPTIONS] [EVENT-FILE...]"
 
-	_, err = parser.Parse()
+	args, err := parser.Parse()
 	if err != nil {
 		logger.Fatal("failed to parse flags", zap.Error(err))
 	}
 
-	logger.Info("Running Lambda handler.")
-	lambdaHandler()
+	if options.Poll && options.Checkpoint == "" {
+		logger.Fatal("--checkpoint is required with --poll")
+	}
+	memoryDedupe.TTL = time.Duration(options.DedupeTTL) * time.Minute
+
+	if inLambda() {
+		logger.Info("Running Lambda handler.")
+		lambdaHandler()
+		return
+	}
+	if options.Poll {
+		err = pollEvents(context.Background())
+		if err != nil {
+			logger.Fatal("failed to poll events", zap.Error(err))
+		}
+		return
+	}
+	err = replayEvents(context.Background(), args)
+	if err != nil {
+		logger.Fatal("failed to replay events", zap.Error(err))
+	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/trussworks/truss-aws-tools/pkg/awshealth"
+	"go.uber.org/zap"
+)
+
+// setupRateLimit runs the handler as a dry run with a rate limit of one
+// event, and holds an event back in #ops in a window that has ended.
+func setupRateLimit(t *testing.T) {
+	savedOptions, savedLogger := options, logger
+	t.Cleanup(func() {
+		options, logger = savedOptions, savedLogger
+		memoryRates = awshealth.MemoryRateStore{}
+	})
+	options = Options{
+		DryRun:       true,
+		RateLimit:    1,
+		RateWindow:   300,
+		Region:       "us-east-1",
+		SlackChannel: "#ops",
+	}
+	logger = zap.NewNop()
+
+	err := memoryRates.PutWindow("#ops", awshealth.RateWindow{
+		Start: time.Now().Add(-time.Hour),
+		Count: 1,
+		Held:  []string{"AWS Health: EC2 AWS_EC2_OPERATIONAL_ISSUE (open) in us-east-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFlushed(t *testing.T, channel string) {
+	t.Helper()
+	window, err := memoryRates.GetWindow(channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(window.Held) != 0 {
+		t.Errorf("%s still holds %v after its window ended", channel, window.Held)
+	}
+}
+
+// TestHandleEventFlushesExpiredDigests checks that a Lambda invocation for
+// an event routed to another channel sends the #ops digest.
+func TestHandleEventFlushesExpiredDigests(t *testing.T) {
+	setupRateLimit(t)
+	routing := filepath.Join(t.TempDir(), "routing.json")
+	err := os.WriteFile(routing, []byte(`{"default": {"channel": "#ops"}, "rules": [{"match": {"service": ["EC2"]}, "channel": "#ec2"}]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.RoutingConfig = routing
+
+	f, err := os.Open("../../pkg/awshealth/testdata/ec2_issue.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cwEvents, err := awshealth.ReadCloudWatchEvents(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handleEvent(context.Background(), cwEvents[0]); err != nil {
+		t.Fatal(err)
+	}
+	assertFlushed(t, "#ops")
+}
+
+// TestHandleScheduledEvent checks that a schedule rule invocation sends the
+// digests without a health event.
+func TestHandleScheduledEvent(t *testing.T) {
+	setupRateLimit(t)
+
+	err := handleEvent(context.Background(), events.CloudWatchEvent{
+		DetailType: scheduledEventType,
+		Source:     "aws.events",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlushed(t, "#ops")
+}
@@ -1,67 +1,105 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/trussworks/truss-aws-tools/internal/aws/session"
+	"github.com/trussworks/truss-aws-tools/pkg/ebsdelete"
+	"go.uber.org/zap"
 )
 
+// volumeIDs collects repeated -volume-id flags, each of which may also be a
+// comma separated list.
+type volumeIDs []string
+
+func (v *volumeIDs) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *volumeIDs) Set(value string) error {
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			*v = append(*v, id)
+		}
+	}
+	return nil
+}
+
+// parseTag splits a -tag selector of the form key or key=value.
+func parseTag(tag string) (key, value string) {
+	key, value, _ = strings.Cut(tag, "=")
+	return key, value
+}
+
 func main() {
-	var volumeID, profile, region string
+	var ids volumeIDs
+	var profile, region, tag string
+	var availableDays, concurrency int
 	dryRun := false
 	force := false
-	flag.StringVar(&volumeID, "volume-id",
-		"",
-		"The EBS volumeId to delete")
+	flag.Var(&ids, "volume-id",
+		"An EBS volumeId to delete. May be repeated or comma separated, and volume IDs may also be given as arguments.")
+	flag.IntVar(&availableDays, "available-days", 0,
+		"Delete volumes that are not attached to an instance and were created more than this many days ago.")
+	flag.StringVar(&tag, "tag", "",
+		"Delete volumes with this tag, given as key or key=value.")
+	flag.IntVar(&concurrency, "concurrency", ebsdelete.DefaultConcurrency,
+		"The number of volumes to snapshot and delete at once.")
 	flag.StringVar(&region, "region", "", "The AWS region to use.")
 	flag.StringVar(&profile, "profile", "", "The AWS profile to use.")
 	flag.BoolVar(&dryRun, "dry-run", false,
 		"Don't make any changes and log what would have happened.")
 	flag.BoolVar(&force, "force", false,
-		"Delete the volume even if it's part of a CloudFormation stack.")
+		"Delete volumes even if they're part of a CloudFormation stack.")
 	flag.Parse()
-	if volumeID == "" {
+	ids = append(ids, flag.Args()...)
+
+	selector := ebsdelete.Selector{
+		VolumeIDs:     ids,
+		AvailableDays: availableDays,
+	}
+	selector.TagKey, selector.TagValue = parseTag(tag)
+	if selector.Empty() {
 		flag.PrintDefaults()
 		return
 	}
-	ec2Client := makeEC2Client(region, profile)
-	volume, err := findVolume(ec2Client, volumeID)
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+
+	e := ebsdelete.EBSDelete{
+		Concurrency: concurrency,
+		DryRun:      dryRun,
+		EC2Client:   makeEC2Client(region, profile),
+		Force:       force,
+		Logger:      logger,
+	}
+	ctx := context.Background()
+	volumes, err := e.FindVolumes(ctx, selector, time.Now())
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("failed to find volumes", zap.Error(err))
 	}
-	cloudformed, stackName := isCloudFormed(volume)
-	if cloudformed && !force {
-		log.Println("Volume is cloudformed. Delete stack:", stackName)
+	if len(volumes) == 0 {
+		logger.Info("no volumes to delete")
 		return
 	}
-	var snapshot *ec2.Snapshot
-	if dryRun {
-		log.Println("Creating snapshot for volumeId:",
-			*volume.VolumeId)
-	} else {
-		snapshot, err = createSnapshotAndWaitUntilCompleted(ec2Client,
-			volume)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-	if dryRun {
-		log.Println("Deleting volume:", *volume.VolumeId)
-	} else {
-		err = deleteVolume(ec2Client, volume)
-		if err != nil {
-			log.Fatal(err)
-		}
+
+	results := e.Delete(ctx, volumes)
+	err = ebsdelete.WriteResults(os.Stdout, results)
+	if err != nil {
+		logger.Fatal("failed to write results", zap.Error(err))
 	}
-	if !dryRun {
-		fmt.Println(volume)
-		fmt.Println(snapshot)
+	if failed := ebsdelete.Failed(results); len(failed) > 0 {
+		logger.Fatal(fmt.Sprintf("failed to delete %d of %d volumes", len(failed), len(results)))
 	}
 }
 
@@ -71,103 +109,3 @@ func makeEC2Client(region, profile string) *ec2.EC2 {
 	ec2Client := ec2.New(sess)
 	return ec2Client
 }
-
-// createSnapshotAndWaitUntilCompleted takes a snapshot of an EC2 volume,
-// and returns when the snapshot has completed.
-func createSnapshotAndWaitUntilCompleted(client *ec2.EC2,
-	volume *ec2.Volume) (snapshot *ec2.Snapshot, err error) {
-	snapshotInput := &ec2.CreateSnapshotInput{
-		Description: volume.VolumeId,
-		VolumeId:    volume.VolumeId,
-	}
-	snapshot, err = client.CreateSnapshot(snapshotInput)
-	if err != nil {
-		return snapshot, err
-	}
-	err = copyVolumeTagsToSnapshot(client, volume, snapshot)
-	if err != nil {
-		return snapshot, err
-	}
-	describeSnapshotInput := &ec2.DescribeSnapshotsInput{
-		SnapshotIds: []*string{snapshot.SnapshotId},
-	}
-	err = client.WaitUntilSnapshotCompleted(describeSnapshotInput)
-	return snapshot, err
-}
-
-// copyVolumeTagsToSnapshot copies all tags from the volume to the snapshot
-func copyVolumeTagsToSnapshot(client *ec2.EC2,
-	volume *ec2.Volume,
-	snapshot *ec2.Snapshot) (err error) {
-	if volume.Tags != nil {
-		tags := copyTags(volume.Tags)
-		tagsInput := &ec2.CreateTagsInput{
-			Resources: []*string{snapshot.SnapshotId},
-			Tags:      tags,
-		}
-		_, err := client.CreateTags(tagsInput)
-		return err
-	}
-	return nil
-}
-
-// findVolume gets a Volume from a volumeid
-func findVolume(client *ec2.EC2, volumeID string) (*ec2.Volume, error) {
-	input := &ec2.DescribeVolumesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name: aws.String("volume-id"),
-				Values: []*string{
-					&volumeID,
-				},
-			},
-		},
-	}
-	res, err := client.DescribeVolumes(input)
-	if err != nil {
-		return nil, err
-	}
-	if len(res.Volumes) != 1 {
-		return nil, errors.New("No volumes found with volumeId: " + volumeID)
-	}
-	return res.Volumes[0], nil
-}
-
-// deleteVolume deletes the volume
-func deleteVolume(client *ec2.EC2, volume *ec2.Volume) (err error) {
-	deleteVolumeInput := &ec2.DeleteVolumeInput{
-		VolumeId: volume.VolumeId,
-	}
-	_, err = client.DeleteVolume(deleteVolumeInput)
-	return err
-}
-
-// copyTags takes a slice of Tags, and copys then into a new slice,
-// pre-pending the AWS owned Keys with X-.
-func copyTags(tags []*ec2.Tag) []*ec2.Tag {
-	retval := make([]*ec2.Tag, len(tags))
-	for i, tag := range tags {
-		// AWS claims ownership of any tag that starts with aws:
-		// so you can't just copy them across. Prefix with X-
-		// so we can still find them.
-		if strings.HasPrefix(*tag.Key, "aws:") {
-			newTag := ec2.Tag{
-				Key:   aws.String("X-" + *tag.Key),
-				Value: tag.Value,
-			}
-			retval[i] = &newTag
-		} else {
-			retval[i] = tag
-		}
-	}
-	return retval
-}
-
-func isCloudFormed(volume *ec2.Volume) (ok bool, stackName string) {
-	for _, tag := range volume.Tags {
-		if *tag.Key == "aws:cloudformation:stack-name" {
-			return true, *tag.Value
-		}
-	}
-	return false, ""
-}
@@ -3,79 +3,34 @@ package main
 import (
 	"reflect"
 	"testing"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
-func newCloudFormedVolume() *ec2.Volume {
-	v := &ec2.Volume{
-		Tags: []*ec2.Tag{
-			{
-				Key:   aws.String("aws:cloudformation:stack-name"),
-				Value: aws.String("TestStack"),
-			},
-		},
+func TestVolumeIDs(t *testing.T) {
+	var have volumeIDs
+	for _, value := range []string{"vol-1", "vol-2, vol-3", ""} {
+		if err := have.Set(value); err != nil {
+			t.Fatal(err)
+		}
 	}
-	return v
-}
-
-func newVolume() *ec2.Volume {
-	v := &ec2.Volume{}
-	return v
-}
-
-func newTags() []*ec2.Tag {
-	return []*ec2.Tag{
-		{
-			Key:   aws.String("aws:cloudformation:stack-name"),
-			Value: aws.String("TestStack"),
-		},
-		{
-			Key:   aws.String("monkey"),
-			Value: aws.String("monkey-value"),
-		},
-	}
-}
-
-func expectedTags() []*ec2.Tag {
-	return []*ec2.Tag{
-		{
-			Key:   aws.String("X-aws:cloudformation:stack-name"),
-			Value: aws.String("TestStack"),
-		},
-		{
-			Key:   aws.String("monkey"),
-			Value: aws.String("monkey-value"),
-		},
+	want := volumeIDs{"vol-1", "vol-2", "vol-3"}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v, want %v", have, want)
 	}
 }
 
-func TestIsCloudformed(t *testing.T) {
-	want := true
-	wantStackName := "TestStack"
-	have, haveStackName := isCloudFormed(newCloudFormedVolume())
-	if have != want {
-		t.Fatalf("isCloudFormed(cloudFormedVolume) = %v, want = %v",
-			have, want)
-	}
-	if haveStackName != wantStackName {
-		t.Fatalf("have StackName: %v, want %v",
-			haveStackName,
-			wantStackName)
-	}
-
-	want = false
-	have, _ = isCloudFormed(newVolume())
-	if have != want {
-		t.Fatalf("isCloudFormed(volume) = %v, want %v", have, want)
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag, key, value string
+	}{
+		{"", "", ""},
+		{"team", "team", ""},
+		{"team=infra", "team", "infra"},
+		{"url=a=b", "url", "a=b"},
 	}
-}
-
-func TestCopyTags(t *testing.T) {
-	have := copyTags(newTags())
-	want := expectedTags()
-	if !reflect.DeepEqual(have, want) {
-		t.Fatalf("have %v, want %v", have, want)
+	for _, tt := range tests {
+		key, value := parseTag(tt.tag)
+		if key != tt.key || value != tt.value {
+			t.Errorf("parseTag(%q) = %q, %q, want %q, %q", tt.tag, key, value, tt.key, tt.value)
+		}
 	}
 }
@@ -1,225 +1,326 @@
 package main
 
 import (
-	"encoding/csv"
-	"errors"
-	"fmt"
-	"io"
+	"context"
+	"encoding/json"
 	"log"
-	"sort"
-	"strings"
+	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/ses"
 
 	flag "github.com/jessevdk/go-flags"
-	"github.com/lytics/slackhook"
 	"github.com/trussworks/truss-aws-tools/internal/aws/session"
 	"github.com/trussworks/truss-aws-tools/internal/aws/ssm"
+	"github.com/trussworks/truss-aws-tools/pkg/iamkeys"
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
+	"github.com/trussworks/truss-aws-tools/pkg/slackweb"
 	"go.uber.org/zap"
 )
 
-func parseTimestamp(s string) (time.Time, error) {
-	return time.Parse("2006-01-02T15:04:05-07:00", s)
+// Options are the command line options
+type Options struct {
+	DocumentationURL   string   `short:"d" long:"documentation-url" description:"URL pointing to documentation on how-to rotate AWS access keys" required:"false" env:"DOCUMENTATION_URL"`
+	Profile            string   `short:"p" long:"profile" description:"The AWS profile to use." required:"false" env:"AWS_PROFILE"`
+	Region             string   `long:"region" description:"The AWS region to use." required:"false" env:"REGION"`
+	Lambda             bool     `short:"l" long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
+	MaxDays            uint     `long:"days" description:"The maximum age in days that a key can be active without triggering an alert." default:"90" env:"MAX_DAYS"`
+	KeyAgeSeverity     string   `long:"key-age-severity" description:"The severity of access keys older than --days." default:"warning" choice:"info" choice:"warning" choice:"critical" env:"KEY_AGE_SEVERITY"`
+	UnusedKeyDays      uint     `long:"unused-key-days" description:"Alert on active access keys unused for this many days. 0 disables the check." default:"0" env:"UNUSED_KEY_DAYS"`
+	UnusedKeySeverity  string   `long:"unused-key-severity" description:"The severity of unused access keys." default:"warning" choice:"info" choice:"warning" choice:"critical" env:"UNUSED_KEY_SEVERITY"`
+	PasswordDays       uint     `long:"password-days" description:"Alert on console passwords older than this many days. 0 disables the check." default:"0" env:"PASSWORD_DAYS"`
+	PasswordSeverity   string   `long:"password-severity" description:"The severity of old console passwords." default:"warning" choice:"info" choice:"warning" choice:"critical" env:"PASSWORD_SEVERITY"`
+	ConsoleMFA         bool     `long:"console-mfa" description:"Alert on console users, including root, without MFA." env:"CONSOLE_MFA"`
+	ConsoleMFASeverity string   `long:"console-mfa-severity" description:"The severity of console users without MFA." default:"warning" choice:"info" choice:"warning" choice:"critical" env:"CONSOLE_MFA_SEVERITY"`
+	RootKeys           bool     `long:"root-keys" description:"Alert when the root user has active access keys." env:"ROOT_KEYS"`
+	RootKeysSeverity   string   `long:"root-keys-severity" description:"The severity of root user access keys." default:"critical" choice:"info" choice:"warning" choice:"critical" env:"ROOT_KEYS_SEVERITY"`
+	Enforce            bool     `long:"enforce" description:"Warn about, deactivate and delete old access keys." env:"ENFORCE"`
+	DryRun             bool     `long:"dry-run" description:"Don't make any changes and log what would have happened." env:"DRY_RUN"`
+	WarnDays           uint     `long:"warn-days" description:"The access key age in days that triggers a warning naming the key." default:"75" env:"WARN_DAYS"`
+	DeactivateDays     uint     `long:"deactivate-days" description:"The access key age in days after which active keys are deactivated." default:"90" env:"DEACTIVATE_DAYS"`
+	DeleteGraceDays    uint     `long:"delete-grace-days" description:"The number of days inactive keys are kept after being deactivated before they are deleted." default:"30" env:"DELETE_GRACE_DAYS"`
+	ExemptUsers        []string `long:"exempt-user" description:"A user name exempt from enforcement. May be repeated." env:"EXEMPT_USERS" env-delim:","`
+	ExemptTagKey       string   `long:"exempt-tag-key" description:"Users with this IAM tag are exempt from enforcement." env:"EXEMPT_TAG_KEY"`
+	ExemptTagValue     string   `long:"exempt-tag-value" description:"The value --exempt-tag-key must have. Any value matches when empty." env:"EXEMPT_TAG_VALUE"`
+	NotifyOwners       bool     `long:"notify-owners" description:"Also notify each user's owner privately, read from the user's email and slack tags." env:"NOTIFY_OWNERS"`
+	EmailTagKey        string   `long:"email-tag-key" description:"The IAM user tag holding the owner's email address." default:"email" env:"EMAIL_TAG_KEY"`
+	SlackTagKey        string   `long:"slack-tag-key" description:"The IAM user tag holding the owner's Slack member ID." default:"slack" env:"SLACK_TAG_KEY"`
+	SSMSlackToken      string   `long:"ssm-slack-token" description:"The name of a Slack bot token in Parameter store, used to send owners direct messages. Owners are not messaged in Slack when empty." required:"false" env:"SSM_SLACK_TOKEN"`
+	SESSender          string   `long:"ses-sender" description:"The verified SES address owner emails are sent from. Owners are not emailed when empty." env:"SES_SENDER"`
+	AccountIDs         []string `long:"account-id" description:"An account to check by assuming --role-name in it. May be repeated." env:"ACCOUNT_IDS" env-delim:","`
+	OrgAccounts        bool     `long:"org-accounts" description:"Check every active account in the AWS Organization by assuming --role-name in it." env:"ORG_ACCOUNTS"`
+	RoleName           string   `long:"role-name" description:"The role assumed in each account checked with --account-id or --org-accounts." env:"ROLE_NAME"`
+	JSON               bool     `long:"json" description:"Write the findings to stdout as JSON." env:"JSON"`
+	PollInterval       uint     `long:"poll-interval" description:"The poll interval in milliseconds when checking if a credential report is available." default:"5000" env:"POLL_INTERVAL"`
+	MaxPollInterval    uint     `long:"max-poll-interval" description:"The longest poll interval in milliseconds; the interval doubles after each poll up to this." default:"60000" env:"MAX_POLL_INTERVAL"`
+	Timeout            uint     `long:"timeout" description:"The number of seconds to wait for the credential report before giving up." default:"300" env:"TIMEOUT"`
+	SlackEmoji         string   `long:"slack-emoji" description:"The Slack Emoji associated with the notifications." env:"SLACK_EMOJI" default:":key:"`
+	SlackTemplate      string   `long:"slack-template" description:"A text/template file, or ssm:<parameter name>, rendering the Slack alert attachment." required:"false" env:"SLACK_TEMPLATE"`
+	NotifierConfig     string   `long:"notifier-config" description:"A JSON notifier config file, or ssm:<parameter name>, listing the sinks findings and enforcement actions are sent to instead of the Slack webhook." required:"false" env:"NOTIFIER_CONFIG"`
+	SSMSlackWebhookURL string   `long:"ssm-slack-webhook-url" description:"The name of the Slack Webhook Url in Parameter store." required:"false" env:"SSM_SLACK_WEBHOOK_URL"`
+	SlackChannel       string   `long:"slack-channel" description:"The Slack channel." required:"true" env:"SLACK_CHANNEL"`
 }
 
-type stringSet map[string]struct{}
+var options Options
+var logger *zap.Logger
 
-func (s stringSet) len() int {
-	return len(s)
+// makeChecks turns the command line options into check configuration.
+func makeChecks() iamkeys.Checks {
+	return iamkeys.Checks{
+		AccessKeyAge: iamkeys.CheckConfig{
+			Enabled:  true,
+			MaxDays:  options.MaxDays,
+			Severity: iamkeys.Severity(options.KeyAgeSeverity),
+		},
+		AccessKeyUnused: iamkeys.CheckConfig{
+			Enabled:  options.UnusedKeyDays > 0,
+			MaxDays:  options.UnusedKeyDays,
+			Severity: iamkeys.Severity(options.UnusedKeySeverity),
+		},
+		PasswordAge: iamkeys.CheckConfig{
+			Enabled:  options.PasswordDays > 0,
+			MaxDays:  options.PasswordDays,
+			Severity: iamkeys.Severity(options.PasswordSeverity),
+		},
+		ConsoleWithoutMFA: iamkeys.CheckConfig{
+			Enabled:  options.ConsoleMFA,
+			Severity: iamkeys.Severity(options.ConsoleMFASeverity),
+		},
+		RootAccessKey: iamkeys.CheckConfig{
+			Enabled:  options.RootKeys,
+			Severity: iamkeys.Severity(options.RootKeysSeverity),
+		},
+	}
 }
 
-func (s stringSet) has(key string) bool {
-	_, ok := s[key]
-	return ok
-}
+func triggerCheck(ctx context.Context) {
+	sess := session.MustMakeSession(options.Region, options.Profile)
 
-func (s stringSet) add(key string) {
-	s[key] = struct{}{}
-}
+	var err error
+	var slackWebhookURL string
+	if options.NotifierConfig == "" || options.SSMSlackWebhookURL != "" {
+		slackWebhookURL, err = ssm.DecryptValue(sess, options.SSMSlackWebhookURL)
+		if err != nil {
+			logger.Fatal("failed to decrypt slackWebhookURL", zap.Error(err))
+		}
+	}
 
-func (s stringSet) toArray(sortValues bool) []string {
-	arr := sort.StringSlice(make([]string, 0, len(s)))
-	for k := range s {
-		arr = append(arr, k)
+	alert := iamkeys.SlackAlert{
+		Channel:          options.SlackChannel,
+		DocumentationURL: options.DocumentationURL,
+		Emoji:            options.SlackEmoji,
+		Region:           aws.StringValue(sess.Config.Region),
+		WebhookURL:       slackWebhookURL,
 	}
-	if sortValues {
-		arr.Sort()
+	if options.SlackTemplate != "" {
+		alert.Template, err = slacktemplate.LoadTemplate(sess, options.SlackTemplate)
+		if err != nil {
+			logger.Fatal("failed to load slack template", zap.Error(err))
+		}
 	}
-	return arr
-}
-
-func newstringSet() stringSet {
-	return stringSet(map[string]struct{}{})
-}
 
-// Options are the command line options
-type Options struct {
-	DocumentationURL   string `short:"d" long:"documentation-url" description:"URL pointing to documentation on how-to rotate AWS access keys" required:"false" env:"DOCUMENTATION_URL"`
-	Profile            string `short:"p" long:"profile" description:"The AWS profile to use." required:"false" env:"AWS_PROFILE"`
-	Region             string `long:"region" description:"The AWS region to use." required:"false" env:"REGION"`
-	Lambda             bool   `short:"l" long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
-	MaxDays            uint   `long:"days" description:"The maximum age in days that a key can be active without triggering an alert." default:"90" env:"MAX_DAYS"`
-	PollInterval       uint   `long:"poll-interval" description:"The poll interval in milliseconds when checking if a credential report is available." default:"5000" env:"POLL_INTERVAL"`
-	SlackEmoji         string `long:"slack-emoji" description:"The Slack Emoji associated with the notifications." env:"SLACK_EMOJI" default:":key:"`
-	SSMSlackWebhookURL string `long:"ssm-slack-webhook-url" description:"The name of the Slack Webhook Url in Parameter store." required:"false" env:"SSM_SLACK_WEBHOOK_URL"`
-	SlackChannel       string `long:"slack-channel" description:"The Slack channel." required:"true" env:"SLACK_CHANNEL"`
-}
+	if options.OrgAccounts || len(options.AccountIDs) > 0 {
+		checkAccounts(ctx, sess, alert)
+		return
+	}
 
-var options Options
-var logger *zap.Logger
+	c := iamkeys.IAMKeysCheck{
+		Checks:          makeChecks(),
+		IAMClient:       iam.New(sess),
+		Logger:          logger,
+		PollInterval:    time.Duration(options.PollInterval) * time.Millisecond,
+		MaxPollInterval: time.Duration(options.MaxPollInterval) * time.Millisecond,
+		Timeout:         time.Duration(options.Timeout) * time.Second,
+	}
 
-func rowToMap(header []string, row []string) map[string]string {
-	m := map[string]string{}
-	for i, h := range header {
-		m[strings.ToLower(h)] = row[i]
+	findings, err := c.CheckWithContext(ctx)
+	if err != nil {
+		logger.Fatal("failed to check access keys", zap.Error(err))
 	}
-	return m
-}
 
-func getCredentialReport(iamClient *iam.IAM, tries int, pollInterval int) (*iam.GetCredentialReportOutput, error) {
-	if tries <= 0 {
-		return &iam.GetCredentialReportOutput{}, errors.New("maxmimum number of tries to get credential report reached")
+	if options.JSON {
+		writeJSON(findings)
 	}
-	report, err := iamClient.GetCredentialReport(&iam.GetCredentialReportInput{})
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == iam.ErrCodeCredentialReportNotPresentException || aerr.Code() == iam.ErrCodeCredentialReportExpiredException {
-				_, err = iamClient.GenerateCredentialReport(&iam.GenerateCredentialReportInput{})
+
+	if len(findings) > 0 {
+		if options.NotifierConfig != "" {
+			notifiers := loadNotifiers(sess)
+			err = notifiers.Notify(ctx, alert.NotifyMessage(findings, c.Checks))
+			if err != nil {
+				logger.Fatal("failed to send alert", zap.Error(err))
+			}
+			logger.Info("successfully sent alert", zap.Int("notifiers", len(notifiers)))
+		} else {
+			err = alert.Send(findings, c.Checks)
+			if err != nil {
+				logger.Fatal("failed to send alert to slack", zap.Error(err))
+			}
+			logger.Info("successfully sent slack message", zap.String("slack-channel", options.SlackChannel))
+		}
+
+		if options.NotifyOwners {
+			n := iamkeys.OwnerNotify{
+				DocumentationURL: options.DocumentationURL,
+				EmailTagKey:      options.EmailTagKey,
+				IAMClient:        c.IAMClient,
+				Logger:           logger,
+				SESClient:        ses.New(sess),
+				Sender:           options.SESSender,
+				SlackEmoji:       options.SlackEmoji,
+				SlackTagKey:      options.SlackTagKey,
+			}
+			if options.SSMSlackToken != "" {
+				token, err := ssm.DecryptValue(sess, options.SSMSlackToken)
 				if err != nil {
-					if aerr, ok = err.(awserr.Error); ok {
-						if aerr.Code() == iam.ErrCodeLimitExceededException {
-							time.Sleep(time.Duration(pollInterval) * time.Millisecond)
-							return getCredentialReport(iamClient, tries-1, pollInterval)
-						}
-					}
-					return &iam.GetCredentialReportOutput{}, err
+					logger.Fatal("failed to decrypt slack token", zap.Error(err))
 				}
-			} else if aerr.Code() == iam.ErrCodeCredentialReportNotReadyException {
-				time.Sleep(time.Duration(pollInterval) * time.Millisecond)
-				return getCredentialReport(iamClient, tries-1, pollInterval)
+				n.SlackClient = &slackweb.Client{Token: token}
+			}
+			err = n.Notify(ctx, findings)
+			if err != nil {
+				logger.Fatal("failed to notify user owners", zap.Error(err))
 			}
 		}
-		return &iam.GetCredentialReportOutput{}, err
-	}
-	return report, nil
-}
-
-func sendAlertToSlack(slackWebhookURL string, usersSet stringSet, maxDays float64) error {
-	slack := slackhook.New(slackWebhookURL)
-	attachment := slackhook.Attachment{
-		Title:     "Expired IAM Access Keys",
-		Text:      fmt.Sprintf("IAM users with access keys older than %d days", int(maxDays)),
-		TitleLink: "https://console.aws.amazon.com/iam/home?region=us-west-2#/users",
-		Color:     "warn",
-		Footer:    "IAM Keys Check",
-		Fields: []slackhook.Field{
-			{
-				Title: "IAM Users",
-				Value: strings.Join(usersSet.toArray(true), ", "),
-			},
-		},
 	}
 
-	if options.DocumentationURL != "" {
-		attachment.Fields = append(attachment.Fields, slackhook.Field{
-			Title: "Access Key Rotation Instructions",
-			Value: options.DocumentationURL,
-		})
+	if options.Enforce {
+		enforceKeys(ctx, sess, alert)
 	}
+}
 
-	message := &slackhook.Message{
-		Channel:   options.SlackChannel,
-		IconEmoji: options.SlackEmoji,
+// loadNotifiers builds the notifiers listed in --notifier-config.
+func loadNotifiers(sess *awssession.Session) notify.Multi {
+	configs, err := notify.LoadConfig(sess, options.NotifierConfig)
+	if err != nil {
+		logger.Fatal("failed to load notifier config", zap.Error(err))
 	}
-	message.AddAttachment(&attachment)
-
-	err := slack.Send(message)
+	notifiers, err := notify.NewMulti(sess, configs)
 	if err != nil {
-		return err
+		logger.Fatal("failed to make notifiers", zap.Error(err))
 	}
-	logger.Info("successfully sent slack message", zap.String("slack-channel", options.SlackChannel))
-	return nil
+	return notifiers
 }
 
-func triggerCheck() {
-
-	maxDays := float64(options.MaxDays)
-
-	if maxDays == 0 {
-		logger.Fatal("days must be greater than 0.")
+// checkAccounts runs the checks in each requested account and sends one
+// combined alert grouped by account.
+func checkAccounts(ctx context.Context, sess *awssession.Session, alert iamkeys.SlackAlert) {
+	if options.RoleName == "" {
+		logger.Fatal("--role-name is required with --account-id or --org-accounts")
+	}
+	// Owners and enforcement are per account IAM changes that the
+	// combined multi-account report does not make.
+	if options.NotifyOwners || options.Enforce {
+		logger.Fatal("--notify-owners and --enforce are not supported with --account-id or --org-accounts")
 	}
 
-	sess := session.MustMakeSession(options.Region, options.Profile)
-	iamClient := iam.New(sess)
-
-	slackWebhookURL, err := ssm.DecryptValue(sess, options.SSMSlackWebhookURL)
-	if err != nil {
-		logger.Fatal("failed to decrypt slackWebhookURL", zap.Error(err))
+	accountIDs := options.AccountIDs
+	if options.OrgAccounts {
+		var err error
+		accountIDs, err = iamkeys.ListOrganizationAccounts(organizations.New(sess))
+		if err != nil {
+			logger.Fatal("failed to list organization accounts", zap.Error(err))
+		}
 	}
 
-	report, err := getCredentialReport(iamClient, 5, int(options.PollInterval))
+	m := iamkeys.MultiAccountCheck{
+		AccountIDs:      accountIDs,
+		Checks:          makeChecks(),
+		IAMClient:       iamkeys.AssumeRoleIAMClients(sess, options.RoleName),
+		Logger:          logger,
+		PollInterval:    time.Duration(options.PollInterval) * time.Millisecond,
+		MaxPollInterval: time.Duration(options.MaxPollInterval) * time.Millisecond,
+		Timeout:         time.Duration(options.Timeout) * time.Second,
+	}
+	reports, err := m.CheckWithContext(ctx)
 	if err != nil {
-		logger.Fatal("failed to get credential report", zap.Error(err))
+		logger.Fatal("failed to check accounts", zap.Error(err))
 	}
 
-	reader := csv.NewReader(strings.NewReader(string(report.Content)))
+	if options.JSON {
+		writeJSON(reports)
+	}
 
-	header, err := reader.Read()
-	if err != nil {
-		if err != io.EOF {
-			logger.Fatal("failed to read header from csv", zap.Error(err))
+	if options.NotifierConfig != "" {
+		message := alert.AccountsNotifyMessage(reports, m.Checks)
+		if message == nil {
+			return
+		}
+		notifiers := loadNotifiers(sess)
+		err = notifiers.Notify(ctx, message)
+		if err != nil {
+			logger.Fatal("failed to send alert", zap.Error(err))
 		}
+		logger.Info("successfully sent alert", zap.Int("notifiers", len(notifiers)))
+		return
 	}
 
-	usersSet := newstringSet()
-
-	for {
-
-		inRow, err := reader.Read()
+	message, err := alert.AccountsMessage(reports, m.Checks)
+	if err != nil {
+		logger.Fatal("failed to build slack message", zap.Error(err))
+	}
+	if len(message.Attachments) > 0 {
+		err = alert.SendAccounts(reports, m.Checks)
 		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				logger.Fatal("failed to read row from csv", zap.Error(err))
-			}
+			logger.Fatal("failed to send alert to slack", zap.Error(err))
 		}
+		logger.Info("successfully sent slack message", zap.String("slack-channel", options.SlackChannel))
+	}
+}
 
-		input := rowToMap(header, inRow)
+func writeJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		logger.Fatal("failed to write json", zap.Error(err))
+	}
+}
 
-		if input["access_key_1_active"] == "true" {
-			accessKey1LastRotated, err := parseTimestamp(input["access_key_1_last_rotated"])
-			if err != nil {
-				logger.Fatal("failed to parse access_key_1_last_rotated", zap.Error(err))
-			}
-			if days := report.GeneratedTime.Sub(accessKey1LastRotated).Hours() / 24; days > maxDays {
-				usersSet.add(input["user"])
-			}
-		}
+func enforceKeys(ctx context.Context, sess *awssession.Session, alert iamkeys.SlackAlert) {
+	e := iamkeys.IAMKeysEnforce{
+		DryRun:          options.DryRun,
+		IAMClient:       iam.New(sess),
+		Logger:          logger,
+		WarnDays:        options.WarnDays,
+		DeactivateDays:  options.DeactivateDays,
+		DeleteGraceDays: options.DeleteGraceDays,
+		ExemptUsers:     options.ExemptUsers,
+		ExemptTagKey:    options.ExemptTagKey,
+		ExemptTagValue:  options.ExemptTagValue,
+	}
 
-		if !usersSet.has(input["user"]) {
-			if input["access_key_2_active"] == "true" {
-				accessKey2LastRotated, err := parseTimestamp(input["access_key_2_last_rotated"])
-				if err != nil {
-					logger.Fatal("failed to parse access_key_2_last_rotated", zap.Error(err))
-				}
-				if days := report.GeneratedTime.Sub(accessKey2LastRotated).Hours() / 24; days > maxDays {
-					usersSet.add(input["user"])
-				}
-			}
-		}
+	actions, err := e.Enforce(time.Now())
+	if err != nil {
+		logger.Fatal("failed to enforce access key age", zap.Error(err))
+	}
 
+	err = iamkeys.WriteActionReport(os.Stdout, actions)
+	if err != nil {
+		logger.Fatal("failed to write action report", zap.Error(err))
 	}
 
-	if usersSet.len() > 0 {
-		err = sendAlertToSlack(slackWebhookURL, usersSet, maxDays)
+	if len(actions) == len(iamkeys.FilterActions(actions, iamkeys.ActionExempt)) {
+		return
+	}
+	if options.NotifierConfig != "" {
+		notifiers := loadNotifiers(sess)
+		err = notifiers.Notify(ctx, alert.EnforcementNotifyMessage(actions))
 		if err != nil {
-			logger.Fatal("failed to send alert to slack", zap.Error(err))
+			logger.Fatal("failed to send enforcement alert", zap.Error(err))
 		}
+		logger.Info("successfully sent enforcement alert", zap.Int("notifiers", len(notifiers)))
+	} else {
+		err = alert.SendEnforcement(actions)
+		if err != nil {
+			logger.Fatal("failed to send enforcement alert to slack", zap.Error(err))
+		}
+		logger.Info("successfully sent slack message", zap.String("slack-channel", options.SlackChannel))
 	}
-
 }
 
 func lambdaHandler() {
@@ -243,6 +344,6 @@ func main() {
 		logger.Info("running Lambda handler.")
 		lambdaHandler()
 	} else {
-		triggerCheck()
+		triggerCheck(context.Background())
 	}
 }
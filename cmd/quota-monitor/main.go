@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/support"
+
+	flag "github.com/jessevdk/go-flags"
+	"github.com/trussworks/truss-aws-tools/internal/aws/session"
+	"github.com/trussworks/truss-aws-tools/internal/aws/ssm"
+	"github.com/trussworks/truss-aws-tools/pkg/quotamonitor"
+	"github.com/trussworks/truss-aws-tools/pkg/tarefresh"
+	"go.uber.org/zap"
+)
+
+// Options are the command line options
+type Options struct {
+	Profile            string   `short:"p" long:"profile" description:"The AWS profile to use." required:"false" env:"AWS_PROFILE"`
+	Region             string   `long:"region" description:"The AWS region whose Service Quotas are checked." required:"false" env:"REGION"`
+	Lambda             bool     `short:"l" long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
+	Threshold          float64  `long:"threshold" description:"The percentage of a quota in use that triggers an alert. Trusted Advisor only reports service limits that are at least 80% used, whatever the threshold." default:"80" env:"THRESHOLD"`
+	ServiceCodes       []string `long:"service-code" description:"A Service Quotas service code, such as ec2, whose quotas are checked. May be repeated." default:"ec2" default:"vpc" env:"SERVICE_CODES" env-delim:","`
+	NoTrustedAdvisor   bool     `long:"no-trusted-advisor" description:"Don't read the Trusted Advisor service limits checks, which need a Business or Enterprise support plan." env:"NO_TRUSTED_ADVISOR"`
+	JSON               bool     `long:"json" description:"Write the quotas over the threshold to stdout as JSON." env:"JSON"`
+	SlackEmoji         string   `long:"slack-emoji" description:"The Slack Emoji associated with the notifications." env:"SLACK_EMOJI" default:":chart_with_upwards_trend:"`
+	SSMSlackWebhookURL string   `long:"ssm-slack-webhook-url" description:"The name of the Slack Webhook Url in Parameter store." required:"true" env:"SSM_SLACK_WEBHOOK_URL"`
+	SlackChannel       string   `long:"slack-channel" description:"The Slack channel." required:"true" env:"SLACK_CHANNEL"`
+}
+
+var options Options
+var logger *zap.Logger
+
+func triggerCheck(ctx context.Context) {
+	sess := session.MustMakeSession(options.Region, options.Profile)
+
+	m := quotamonitor.QuotaMonitor{
+		CloudWatchClient:    cloudwatch.New(sess),
+		Logger:              logger,
+		Region:              aws.StringValue(sess.Config.Region),
+		ServiceCodes:        options.ServiceCodes,
+		ServiceQuotasClient: servicequotas.New(sess),
+		Threshold:           options.Threshold,
+	}
+	if !options.NoTrustedAdvisor {
+		m.SupportClient = support.New(session.MustMakeSession(tarefresh.SupportRegion, options.Profile))
+	}
+
+	usages, err := m.Check(ctx, time.Now())
+	if err != nil {
+		logger.Fatal("failed to check quotas", zap.Error(err))
+	}
+	for _, u := range usages {
+		logger.Info("quota over threshold",
+			zap.String("source", u.Source),
+			zap.String("service", u.Service),
+			zap.String("quota-name", u.QuotaName),
+			zap.String("region", u.Region),
+			zap.Float64("limit", u.Limit),
+			zap.Float64("used", u.Used),
+		)
+	}
+
+	if options.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(usages); err != nil {
+			logger.Fatal("failed to write json", zap.Error(err))
+		}
+	}
+
+	if len(usages) == 0 {
+		return
+	}
+
+	slackWebhookURL, err := ssm.DecryptValue(sess, options.SSMSlackWebhookURL)
+	if err != nil {
+		logger.Fatal("failed to decrypt slackWebhookURL", zap.Error(err))
+	}
+	alert := quotamonitor.SlackAlert{
+		Channel:    options.SlackChannel,
+		Emoji:      options.SlackEmoji,
+		Region:     m.Region,
+		WebhookURL: slackWebhookURL,
+	}
+	err = alert.Send(usages, options.Threshold)
+	if err != nil {
+		logger.Fatal("failed to send alert to slack", zap.Error(err))
+	}
+	logger.Info("successfully sent slack message", zap.String("slack-channel", options.SlackChannel))
+}
+
+func lambdaHandler() {
+	lambda.Start(triggerCheck)
+}
+
+func main() {
+
+	parser := flag.NewParser(&options, flag.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err = zap.NewProduction()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+
+	if options.Lambda {
+		logger.Info("running Lambda handler.")
+		lambdaHandler()
+	} else {
+		triggerCheck(context.Background())
+	}
+}
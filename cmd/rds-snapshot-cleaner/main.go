@@ -8,6 +8,8 @@ import (
 	"github.com/trussworks/truss-aws-tools/pkg/rdsclean"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/docdb"
+	"github.com/aws/aws-sdk-go/service/neptune"
 	"github.com/aws/aws-sdk-go/service/rds"
 	flag "github.com/jessevdk/go-flags"
 	"go.uber.org/zap"
@@ -15,53 +17,66 @@ import (
 
 // Options are the command line options
 type Options struct {
-	DBInstanceIdentifier string `long:"db-instance-identifier" description:"The RDS database instance identifier." required:"true" env:"DB_INSTANCE_IDENTIFIER"`
+	DBClusterIdentifier  string `long:"db-cluster-identifier" description:"The Aurora, DocumentDB or Neptune cluster identifier." required:"false" env:"DB_CLUSTER_IDENTIFIER"`
+	DBInstanceIdentifier string `long:"db-instance-identifier" description:"The RDS database instance identifier." required:"false" env:"DB_INSTANCE_IDENTIFIER"`
 	DryRun               bool   `long:"dry-run" description:"Don't make any changes and log what would have happened." env:"DRY_RUN"`
 	Lambda               bool   `long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
 	MaxDBSnapshotCount   uint   `long:"max-snapshots" description:"The maximum number of manual snapshots allowed. This takes precedence over -retention-days." default:"0" env:"MAX_DB_SNAPSHOT_COUNT"`
 	Profile              string `long:"profile" description:"The AWS profile to use." required:"false" env:"PROFILE"`
+	Provider             string `long:"provider" description:"The kind of database whose snapshots to clean." default:"rds-instance" choice:"rds-instance" choice:"rds-cluster" choice:"docdb" choice:"neptune" env:"PROVIDER"`
 	Region               string `long:"region" description:"The AWS region to use." required:"false" env:"REGION"`
+	RetainTagKey         string `long:"retain-tag-key" description:"Never delete snapshots that carry this tag key." required:"false" env:"RETAIN_TAG_KEY"`
+	RetainTagValue       string `long:"retain-tag-value" description:"Only honor the retain tag when it has this value. Any value matches if unset." required:"false" env:"RETAIN_TAG_VALUE"`
 	RetentionDays        uint   `long:"retention-days" description:"The maximum retention age in days." default:"30" env:"RETENTION_DAYS"`
 }
 
 var options Options
 var logger *zap.Logger
 
-func makeRDSClient(region, profile string) *rds.RDS {
+// makeSnapshotProvider returns the snapshot provider for the named kind of
+// database along with the identifier it cleans: clusterID for the cluster
+// providers and instanceID for rds-instance.
+func makeSnapshotProvider(name, region, profile, clusterID, instanceID string) (rdsclean.SnapshotProvider, string) {
 	sess := session.MustMakeSession(region, profile)
-	rdsClient := rds.New(sess)
-	return rdsClient
+	switch name {
+	case rdsclean.ProviderRDSCluster:
+		return &rdsclean.DBClusterProvider{RDSClient: rds.New(sess)}, clusterID
+	case rdsclean.ProviderDocDB:
+		return &rdsclean.DocDBProvider{DocDBClient: docdb.New(sess)}, clusterID
+	case rdsclean.ProviderNeptune:
+		return &rdsclean.NeptuneProvider{NeptuneClient: neptune.New(sess)}, clusterID
+	default:
+		return &rdsclean.DBInstanceProvider{RDSClient: rds.New(sess)}, instanceID
+	}
 }
 
 func cleanRDSSnapshots() {
 	now := time.Now().UTC()
-	r := rdsclean.RDSManualSnapshotClean{
-		DBInstanceIdentifier: options.DBInstanceIdentifier,
-		DryRun:               options.DryRun,
-		ExpirationDate:       now.AddDate(0, 0, -int(options.RetentionDays)),
-		Logger:               logger,
-		MaxDBSnapshotCount:   options.MaxDBSnapshotCount,
-		RDSClient:            makeRDSClient(options.Region, options.Profile),
-	}
-
-	manualDBSnapshots, err := r.FindManualDBSnapshots()
-	if err != nil {
-		logger.Fatal("unable to find manual snapshots",
-			zap.Error(err))
+	provider, dbIdentifier := makeSnapshotProvider(options.Provider, options.Region, options.Profile,
+		options.DBClusterIdentifier, options.DBInstanceIdentifier)
+	if dbIdentifier == "" {
+		logger.Fatal("missing database identifier; use --db-instance-identifier for rds-instance and --db-cluster-identifier for cluster providers",
+			zap.String("provider", options.Provider))
 	}
 
-	dbSnapshotsToDelete, err := r.FindDBSnapshotsToDelete(manualDBSnapshots)
-	if err != nil {
-		logger.Fatal("unable to find snapshots to snapshots to delete",
-			zap.Error(err))
+	r := rdsclean.RDSManualSnapshotClean{
+		DBIdentifier:       dbIdentifier,
+		DryRun:             options.DryRun,
+		ExpirationDate:     now.AddDate(0, 0, -int(options.RetentionDays)),
+		Logger:             logger,
+		MaxDBSnapshotCount: options.MaxDBSnapshotCount,
+		Provider:           provider,
+		RetainTagKey:       options.RetainTagKey,
+		RetainTagValue:     options.RetainTagValue,
 	}
 
-	err = r.DeleteDBSnapshots(dbSnapshotsToDelete)
+	err := r.Clean()
 	if err != nil {
-		logger.Fatal("unable to delete snapshots",
+		logger.Fatal("unable to clean snapshots",
+			zap.String("provider", provider.Name()),
+			zap.String("db-identifier", dbIdentifier),
 			zap.Error(err))
 	}
-
 }
 
 func lambdaHandler() {
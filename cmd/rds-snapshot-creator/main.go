@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/trussworks/truss-aws-tools/internal/aws/session"
+	"github.com/trussworks/truss-aws-tools/pkg/rdsclean"
+	"github.com/trussworks/truss-aws-tools/pkg/rdssnapshot"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/rds"
+	flag "github.com/jessevdk/go-flags"
+	"go.uber.org/zap"
+)
+
+// Options are the command line options
+type Options struct {
+	DBClusterIdentifiers  []string `long:"db-cluster-identifier" description:"An Aurora cluster identifier to snapshot. May be repeated." required:"false" env:"DB_CLUSTER_IDENTIFIERS" env-delim:","`
+	DBInstanceIdentifiers []string `long:"db-instance-identifier" description:"An RDS database instance identifier to snapshot. May be repeated." required:"false" env:"DB_INSTANCE_IDENTIFIERS" env-delim:","`
+	DryRun                bool     `long:"dry-run" description:"Don't make any changes and log what would have happened." env:"DRY_RUN"`
+	Lambda                bool     `long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
+	MaxDBSnapshotCount    uint     `long:"max-snapshots" description:"The maximum number of manual snapshots allowed. This takes precedence over -retention-days." default:"0" env:"MAX_DB_SNAPSHOT_COUNT"`
+	Profile               string   `long:"profile" description:"The AWS profile to use." required:"false" env:"PROFILE"`
+	Region                string   `long:"region" description:"The AWS region to use." required:"false" env:"REGION"`
+	RetainTagKey          string   `long:"retain-tag-key" description:"Never delete snapshots that carry this tag key." required:"false" env:"RETAIN_TAG_KEY"`
+	RetainTagValue        string   `long:"retain-tag-value" description:"Only honor the retain tag when it has this value. Any value matches if unset." required:"false" env:"RETAIN_TAG_VALUE"`
+	RetentionDays         uint     `long:"retention-days" description:"The maximum retention age in days. Set to 0 to skip rotating old snapshots." default:"30" env:"RETENTION_DAYS"`
+	SnapshotPrefix        string   `long:"snapshot-prefix" description:"The prefix for created snapshot identifiers." default:"manual" env:"SNAPSHOT_PREFIX"`
+	TimeoutMinutes        uint     `long:"timeout" description:"The number of minutes to wait for each snapshot to become available." default:"60" env:"TIMEOUT"`
+}
+
+var options Options
+var logger *zap.Logger
+
+func makeRDSClient(region, profile string) *rds.RDS {
+	sess := session.MustMakeSession(region, profile)
+	rdsClient := rds.New(sess)
+	return rdsClient
+}
+
+func createRDSSnapshots() {
+	now := time.Now().UTC()
+	rdsClient := makeRDSClient(options.Region, options.Profile)
+
+	if len(options.DBInstanceIdentifiers) == 0 && len(options.DBClusterIdentifiers) == 0 {
+		logger.Fatal("must specify at least one db instance or db cluster identifier")
+	}
+
+	c := rdssnapshot.RDSManualSnapshotCreate{
+		DBClusterIdentifiers:  options.DBClusterIdentifiers,
+		DBInstanceIdentifiers: options.DBInstanceIdentifiers,
+		DryRun:                options.DryRun,
+		Logger:                logger,
+		RDSClient:             rdsClient,
+		SnapshotPrefix:        options.SnapshotPrefix,
+		Timeout:               time.Duration(options.TimeoutMinutes) * time.Minute,
+	}
+
+	created, err := c.CreateSnapshots(now)
+	if err != nil {
+		logger.Fatal("unable to create snapshots",
+			zap.Strings("created", created),
+			zap.Error(err))
+	}
+	logger.Info("created snapshots", zap.Strings("snapshots", created))
+
+	if options.RetentionDays == 0 && options.MaxDBSnapshotCount == 0 {
+		return
+	}
+
+	// Hand the instances and clusters off to the cleaner so old snapshots
+	// get rotated out in the same run.
+	expirationDate := now.AddDate(0, 0, -int(options.RetentionDays))
+	if options.RetentionDays == 0 {
+		expirationDate = time.Time{}
+	}
+	rotate := func(provider rdsclean.SnapshotProvider, id string) {
+		r := rdsclean.RDSManualSnapshotClean{
+			DBIdentifier:       id,
+			DryRun:             options.DryRun,
+			ExpirationDate:     expirationDate,
+			Logger:             logger,
+			MaxDBSnapshotCount: options.MaxDBSnapshotCount,
+			Provider:           provider,
+			RetainTagKey:       options.RetainTagKey,
+			RetainTagValue:     options.RetainTagValue,
+		}
+		err := r.Clean()
+		if err != nil {
+			logger.Fatal("unable to rotate snapshots",
+				zap.String("provider", provider.Name()),
+				zap.String("db-identifier", id),
+				zap.Error(err))
+		}
+	}
+
+	for _, id := range options.DBInstanceIdentifiers {
+		rotate(&rdsclean.DBInstanceProvider{RDSClient: rdsClient}, id)
+	}
+	for _, id := range options.DBClusterIdentifiers {
+		rotate(&rdsclean.DBClusterProvider{RDSClient: rdsClient}, id)
+	}
+}
+
+func lambdaHandler() {
+	lambda.Start(createRDSSnapshots)
+}
+
+func main() {
+	parser := flag.NewParser(&options, flag.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err = zap.NewProduction()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+
+	if options.Lambda {
+		logger.Info("Running Lambda handler.")
+		lambdaHandler()
+	} else {
+		createRDSSnapshots()
+	}
+
+}
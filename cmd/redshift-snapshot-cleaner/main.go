@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/trussworks/truss-aws-tools/internal/aws/session"
+	"github.com/trussworks/truss-aws-tools/pkg/redshiftclean"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/redshift"
+	flag "github.com/jessevdk/go-flags"
+	"go.uber.org/zap"
+)
+
+// Options are the command line options
+type Options struct {
+	ClusterIdentifier string `long:"cluster-identifier" description:"The Redshift cluster identifier." required:"true" env:"CLUSTER_IDENTIFIER"`
+	DryRun            bool   `long:"dry-run" description:"Don't make any changes and log what would have happened." env:"DRY_RUN"`
+	Lambda            bool   `long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
+	MaxSnapshotCount  uint   `long:"max-snapshots" description:"The maximum number of manual snapshots allowed. This takes precedence over -retention-days." default:"0" env:"MAX_SNAPSHOT_COUNT"`
+	Profile           string `long:"profile" description:"The AWS profile to use." required:"false" env:"PROFILE"`
+	Region            string `long:"region" description:"The AWS region to use." required:"false" env:"REGION"`
+	RetentionDays     uint   `long:"retention-days" description:"The maximum retention age in days." default:"30" env:"RETENTION_DAYS"`
+}
+
+var options Options
+var logger *zap.Logger
+
+func makeRedshiftClient(region, profile string) *redshift.Redshift {
+	sess := session.MustMakeSession(region, profile)
+	redshiftClient := redshift.New(sess)
+	return redshiftClient
+}
+
+func cleanRedshiftSnapshots() {
+	now := time.Now().UTC()
+	r := redshiftclean.RedshiftManualSnapshotClean{
+		ClusterIdentifier: options.ClusterIdentifier,
+		DryRun:            options.DryRun,
+		ExpirationDate:    now.AddDate(0, 0, -int(options.RetentionDays)),
+		Logger:            logger,
+		MaxSnapshotCount:  options.MaxSnapshotCount,
+		RedshiftClient:    makeRedshiftClient(options.Region, options.Profile),
+	}
+
+	manualSnapshots, err := r.FindManualSnapshots()
+	if err != nil {
+		logger.Fatal("unable to find manual snapshots",
+			zap.Error(err))
+	}
+
+	snapshotsToDelete, err := r.FindSnapshotsToDelete(manualSnapshots)
+	if err != nil {
+		logger.Fatal("unable to find snapshots to delete",
+			zap.Error(err))
+	}
+
+	err = r.DeleteSnapshots(snapshotsToDelete)
+	if err != nil {
+		logger.Fatal("unable to delete snapshots",
+			zap.Error(err))
+	}
+
+}
+
+func lambdaHandler() {
+	lambda.Start(cleanRedshiftSnapshots)
+}
+
+func main() {
+	parser := flag.NewParser(&options, flag.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err = zap.NewProduction()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+
+	if options.Lambda {
+		logger.Info("Running Lambda handler.")
+		lambdaHandler()
+	} else {
+		cleanRedshiftSnapshots()
+	}
+
+}
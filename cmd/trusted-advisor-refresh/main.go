@@ -1,69 +1,243 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/trussworks/truss-aws-tools/internal/aws/session"
+	"github.com/trussworks/truss-aws-tools/internal/aws/ssm"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
 	"github.com/trussworks/truss-aws-tools/pkg/tarefresh"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	awsssm "github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
 	flag "github.com/jessevdk/go-flags"
 	"go.uber.org/zap"
 )
 
 // Options are the command line options
 type Options struct {
-	Profile string `short:"p" long:"profile" description:"The AWS profile to use." required:"false" env:"AWS_PROFILE"`
-	Lambda  bool   `short:"l" long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
-	Region  string `long:"region" description:"The AWS region to use." required:"false" env:"AWS_REGION"`
+	Profile            string   `short:"p" long:"profile" description:"The AWS profile to use." required:"false" env:"AWS_PROFILE"`
+	Lambda             bool     `short:"l" long:"lambda" description:"Run as an AWS lambda function." required:"false" env:"LAMBDA"`
+	Region             string   `long:"region" description:"The AWS region the --state and --ssm-slack-webhook-url parameters are read from. The Support API is always called in us-east-1." required:"false" env:"AWS_REGION"`
+	Language           string   `long:"language" description:"The language Trusted Advisor checks are described in." default:"en" env:"LANGUAGE"`
+	IncludeCategories  []string `long:"include-category" description:"Only refresh and report checks in this category, or with an --include-check-id. May be repeated." choice:"cost_optimizing" choice:"security" choice:"fault_tolerance" choice:"performance" choice:"service_limits" env:"INCLUDE_CATEGORIES" env-delim:","`
+	ExcludeCategories  []string `long:"exclude-category" description:"Never refresh or report checks in this category. May be repeated." choice:"cost_optimizing" choice:"security" choice:"fault_tolerance" choice:"performance" choice:"service_limits" env:"EXCLUDE_CATEGORIES" env-delim:","`
+	IncludeCheckIDs    []string `long:"include-check-id" description:"Only refresh and report this check, or checks in an --include-category. May be repeated." env:"INCLUDE_CHECK_IDS" env-delim:","`
+	ExcludeCheckIDs    []string `long:"exclude-check-id" description:"Never refresh or report this check. May be repeated." env:"EXCLUDE_CHECK_IDS" env-delim:","`
+	Report             bool     `long:"report" description:"After refreshing, wait for the refreshes and report each check's result, alerting on checks whose status got worse." required:"false" env:"REPORT"`
+	State              string   `long:"state" description:"A local file, or ssm:<parameter name>, check statuses are kept in between reports. Every check that is not ok is alerted on when empty." required:"false" env:"STATE"`
+	PollInterval       uint     `long:"poll-interval" description:"The poll interval in milliseconds when waiting for refreshes." default:"5000" env:"POLL_INTERVAL"`
+	Timeout            uint     `long:"timeout" description:"The number of seconds to wait for refreshes before giving up." default:"600" env:"TIMEOUT"`
+	JSON               bool     `long:"json" description:"Write the report to stdout as JSON instead of a table." env:"JSON"`
+	SlackChannel       string   `long:"slack-channel" description:"The Slack channel worsened checks are posted to." required:"false" env:"SLACK_CHANNEL"`
+	SlackEmoji         string   `long:"slack-emoji" description:"The Slack Emoji associated with the notifications." env:"SLACK_EMOJI" default:":mag:"`
+	SSMSlackWebhookURL string   `long:"ssm-slack-webhook-url" description:"The name of the Slack Webhook Url in Parameter store. Worsened checks are only logged when empty." required:"false" env:"SSM_SLACK_WEBHOOK_URL"`
 }
 
-var options Options
 var logger *zap.Logger
 
-func makeSupportClient(region, profile string) *support.Support {
-	sess := session.MustMakeSession(region, profile)
-	supportClient := support.New(sess)
-	return supportClient
+// newSupportClient makes the Support API client. Tests replace it to check
+// which session the client is made from.
+var newSupportClient = func(sess *awssession.Session) supportiface.SupportAPI {
+	return support.New(sess)
 }
 
-func triggerRefresh() {
-	// Trusted Advisor only works in us-east-1
-	supportClient := makeSupportClient(options.Region, options.Profile)
+// makeSupportClient makes a Support API client from the profile option. The
+// region option is ignored because the Support API is only served from
+// tarefresh.SupportRegion.
+func makeSupportClient(options Options) (supportiface.SupportAPI, error) {
+	sess, err := session.MakeSession(tarefresh.SupportRegion, options.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make support session: %w", err)
+	}
+	return newSupportClient(sess), nil
+}
 
+// makeFilter builds the check filter from the include and exclude options.
+func makeFilter(options Options) tarefresh.Filter {
+	return tarefresh.Filter{
+		IncludeCategories: options.IncludeCategories,
+		ExcludeCategories: options.ExcludeCategories,
+		IncludeCheckIDs:   options.IncludeCheckIDs,
+		ExcludeCheckIDs:   options.ExcludeCheckIDs,
+	}
+}
+
+// refresh refreshes the checks the filter selects.
+func refresh(options Options, supportClient supportiface.SupportAPI) (*tarefresh.RefreshSummary, error) {
 	tar := tarefresh.TrustedAdvisorRefresh{
+		Filter:        makeFilter(options),
+		Language:      options.Language,
 		Logger:        logger,
 		SupportClient: supportClient,
 	}
-	err := tar.Refresh()
+	summary, err := tar.Refresh()
 	if err != nil {
-		logger.Fatal("failed to refresh trusted advisor", zap.Error(err))
+		return nil, fmt.Errorf("failed to refresh trusted advisor: %w", err)
 	}
+	logger.Info("refreshed trusted advisor",
+		zap.Int("refreshed", len(summary.Refreshed)),
+		zap.Int("skipped", len(summary.Skipped)),
+		zap.Int("failed", len(summary.Failed)),
+	)
+	return summary, nil
 }
 
-func lambdaHandler() {
-	lambda.Start(triggerRefresh)
+func triggerRefresh(options Options) error {
+	supportClient, err := makeSupportClient(options)
+	if err != nil {
+		return err
+	}
+	summary, err := refresh(options, supportClient)
+	if err != nil {
+		return err
+	}
+	return summary.Err()
 }
 
-func main() {
-	var options Options
+// triggerReport refreshes the checks, then reports their results and alerts
+// on the ones that got worse since the last report.
+func triggerReport(ctx context.Context, options Options) error {
+	sess, err := session.MakeSession(options.Region, options.Profile)
+	if err != nil {
+		return fmt.Errorf("failed to make session: %w", err)
+	}
+	supportClient, err := makeSupportClient(options)
+	if err != nil {
+		return err
+	}
+
+	summary, err := refresh(options, supportClient)
+	if err != nil {
+		return err
+	}
+	if err := summary.Err(); err != nil {
+		logger.Error("failed to refresh some trusted advisor checks", zap.Error(err))
+	}
+
+	report := tarefresh.TrustedAdvisorReport{
+		Filter:        makeFilter(options),
+		Language:      options.Language,
+		Logger:        logger,
+		SupportClient: supportClient,
+		PollInterval:  time.Duration(options.PollInterval) * time.Millisecond,
+		Timeout:       time.Duration(options.Timeout) * time.Second,
+	}
+	results, err := report.Report(ctx, summary.RefreshedIDs())
+	if err != nil {
+		return err
+	}
+	if options.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(results)
+	} else {
+		err = tarefresh.WriteReport(os.Stdout, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	var store tarefresh.StatusStore
+	var previous map[string]string
+	if options.State != "" {
+		store = statusStore(sess, options.State)
+		previous, err = store.GetStatuses()
+		if err != nil {
+			return err
+		}
+	}
+
+	worsened := tarefresh.Worsened(results, previous)
+	for _, r := range worsened {
+		logger.Info("trusted advisor check got worse",
+			zap.String("name", r.Name),
+			zap.String("id", r.CheckID),
+			zap.String("previous-status", r.PreviousStatus),
+			zap.String("status", r.Status),
+		)
+	}
+	if len(worsened) > 0 && options.SSMSlackWebhookURL != "" {
+		slackWebhookURL, err := ssm.DecryptValue(sess, options.SSMSlackWebhookURL)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt slackWebhookURL: %w", err)
+		}
+		alert := tarefresh.SlackAlert{
+			Channel:    options.SlackChannel,
+			Emoji:      options.SlackEmoji,
+			WebhookURL: slackWebhookURL,
+		}
+		err = alert.Send(worsened)
+		if err != nil {
+			return fmt.Errorf("failed to send slack message: %w", err)
+		}
+	}
+
+	if store != nil {
+		return store.PutStatuses(tarefresh.Statuses(previous, results))
+	}
+	return nil
+}
+
+func statusStore(sess *awssession.Session, state string) tarefresh.StatusStore {
+	if strings.HasPrefix(state, slacktemplate.SSMPrefix) {
+		return &tarefresh.SSMStatusStore{
+			SSMClient: awsssm.New(sess),
+			Name:      strings.TrimPrefix(state, slacktemplate.SSMPrefix),
+		}
+	}
+	return &tarefresh.FileStatusStore{Path: state}
+}
+
+// handle refreshes the checks, and reports on them with --report.
+func handle(ctx context.Context, options Options) error {
+	if options.Report {
+		return triggerReport(ctx, options)
+	}
+	return triggerRefresh(options)
+}
 
+// run parses the command line arguments and runs the tool, as a Lambda
+// handler with --lambda.
+func run(ctx context.Context, args []string) error {
+	var options Options
 	parser := flag.NewParser(&options, flag.Default)
-	_, err := parser.Parse()
+	_, err := parser.ParseArgs(args)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if options.Lambda {
+		logger.Info("Running Lambda handler.")
+		lambda.Start(func(ctx context.Context) error {
+			return handle(ctx, options)
+		})
+		return nil
 	}
+	return handle(ctx, options)
+}
 
+func main() {
+	var err error
 	logger, err = zap.NewProduction()
 	if err != nil {
 		log.Fatalf("can't initialize zap logger: %v", err)
 	}
 
-	if options.Lambda {
-		logger.Info("Running Lambda handler.")
-		lambdaHandler()
-	} else {
-		triggerRefresh()
+	err = run(context.Background(), os.Args[1:])
+	if flagsErr, ok := err.(*flag.Error); ok && flagsErr.Type == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		logger.Fatal("failed to run trusted advisor refresh", zap.Error(err))
 	}
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
+	"go.uber.org/zap"
+)
+
+type mockSupportClient struct {
+	supportiface.SupportAPI
+	Languages []string
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorChecks(input *support.DescribeTrustedAdvisorChecksInput) (*support.DescribeTrustedAdvisorChecksOutput, error) {
+	m.Languages = append(m.Languages, aws.StringValue(input.Language))
+	return &support.DescribeTrustedAdvisorChecksOutput{}, nil
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorChecksWithContext(ctx aws.Context, input *support.DescribeTrustedAdvisorChecksInput, opts ...request.Option) (*support.DescribeTrustedAdvisorChecksOutput, error) {
+	return m.DescribeTrustedAdvisorChecks(input)
+}
+
+// TestRunOptions checks that the command line options reach the Support
+// client, which always uses us-east-1 whatever --region is.
+func TestRunOptions(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config")
+	err := os.WriteFile(config, []byte("[profile ta-test]\naws_access_key_id = AKIDTATEST\naws_secret_access_key = secret\nregion = us-west-2\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", config)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	for _, name := range []string{"AWS_PROFILE", "AWS_REGION", "LAMBDA", "REPORT", "LANGUAGE"} {
+		// Setenv restores the variable after the test.
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	savedLogger, savedNewSupportClient := logger, newSupportClient
+	t.Cleanup(func() {
+		logger, newSupportClient = savedLogger, savedNewSupportClient
+	})
+	logger = zap.NewNop()
+	var sessions []*awssession.Session
+	m := &mockSupportClient{}
+	newSupportClient = func(sess *awssession.Session) supportiface.SupportAPI {
+		sessions = append(sessions, sess)
+		return m
+	}
+
+	err = run(context.Background(), []string{"--profile", "ta-test", "--region", "eu-west-1", "--language", "ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("support clients made = %d, want 1", len(sessions))
+	}
+	if region := aws.StringValue(sessions[0].Config.Region); region != "us-east-1" {
+		t.Errorf("support client region = %s, want us-east-1", region)
+	}
+	creds, err := sessions[0].Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "AKIDTATEST" {
+		t.Errorf("support client access key = %s, want the ta-test profile's", creds.AccessKeyID)
+	}
+	if len(m.Languages) != 1 || m.Languages[0] != "ja" {
+		t.Errorf("checks described in %v, want [ja]", m.Languages)
+	}
+}
@@ -0,0 +1,573 @@
+// Package fakerds provides an in-memory RDS backend for tests. It implements
+// the parts of rdsiface.RDSAPI the tools in this repo use and simulates the
+// snapshot lifecycle: snapshots are created in the "creating" state, become
+// "available" once a waiter sees them, move to "deleting" when deleted and
+// disappear once the deleted waiter runs.
+package fakerds
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+)
+
+// Snapshot lifecycle states used by the backend.
+const (
+	StatusAvailable = "available"
+	StatusCreating  = "creating"
+	StatusDeleting  = "deleting"
+)
+
+// Backend is an in-memory RDS API. Calls it doesn't implement panic through
+// the embedded nil interface, which makes missing coverage obvious in tests.
+type Backend struct {
+	rdsiface.RDSAPI
+
+	// Now returns the time stamped on created snapshots.
+	Now func() time.Time
+
+	mu               sync.Mutex
+	instances        map[string]*rds.DBInstance
+	clusters         map[string]*rds.DBCluster
+	snapshots        map[string]*rds.DBSnapshot
+	clusterSnapshots map[string]*rds.DBClusterSnapshot
+	sharedWith       map[string][]string
+	exportTasks      []*rds.ExportTask
+	deleted          []string
+}
+
+// New returns an empty Backend.
+func New() *Backend {
+	return &Backend{
+		Now:              time.Now,
+		instances:        map[string]*rds.DBInstance{},
+		clusters:         map[string]*rds.DBCluster{},
+		snapshots:        map[string]*rds.DBSnapshot{},
+		clusterSnapshots: map[string]*rds.DBClusterSnapshot{},
+		sharedWith:       map[string][]string{},
+	}
+}
+
+// AddDBInstance registers a DB instance with the given tags.
+func (b *Backend) AddDBInstance(id string, tags ...*rds.Tag) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.instances[id] = &rds.DBInstance{
+		DBInstanceArn:        aws.String(instanceArn(id)),
+		DBInstanceIdentifier: aws.String(id),
+		TagList:              tags,
+	}
+}
+
+// AddDBCluster registers a DB cluster with the given tags.
+func (b *Backend) AddDBCluster(id string, tags ...*rds.Tag) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clusters[id] = &rds.DBCluster{
+		DBClusterArn:        aws.String(clusterArn(id)),
+		DBClusterIdentifier: aws.String(id),
+		TagList:             tags,
+	}
+}
+
+// AddDBSnapshot stores a copy of s. The ARN, snapshot type and status are
+// filled in when they are unset.
+func (b *Backend) AddDBSnapshot(s *rds.DBSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := *s
+	if c.DBSnapshotArn == nil {
+		c.DBSnapshotArn = aws.String(snapshotArn(*c.DBSnapshotIdentifier))
+	}
+	if c.SnapshotType == nil {
+		c.SnapshotType = aws.String("manual")
+	}
+	if c.Status == nil {
+		c.Status = aws.String(StatusAvailable)
+	}
+	b.snapshots[*c.DBSnapshotIdentifier] = &c
+}
+
+// AddDBClusterSnapshot stores a copy of s. The ARN, snapshot type and
+// status are filled in when they are unset.
+func (b *Backend) AddDBClusterSnapshot(s *rds.DBClusterSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := *s
+	if c.DBClusterSnapshotArn == nil {
+		c.DBClusterSnapshotArn = aws.String(clusterSnapshotArn(*c.DBClusterSnapshotIdentifier))
+	}
+	if c.SnapshotType == nil {
+		c.SnapshotType = aws.String("manual")
+	}
+	if c.Status == nil {
+		c.Status = aws.String(StatusAvailable)
+	}
+	b.clusterSnapshots[*c.DBClusterSnapshotIdentifier] = &c
+}
+
+// ShareDBSnapshot grants the given accounts restore access to a snapshot.
+func (b *Backend) ShareDBSnapshot(id string, accounts ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sharedWith[id] = append(b.sharedWith[id], accounts...)
+}
+
+// ShareDBClusterSnapshot grants the given accounts restore access to a
+// cluster snapshot.
+func (b *Backend) ShareDBClusterSnapshot(id string, accounts ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sharedWith[id] = append(b.sharedWith[id], accounts...)
+}
+
+// AddExportTask records an S3 export task reading from sourceArn.
+func (b *Backend) AddExportTask(sourceArn, status string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.exportTasks = append(b.exportTasks, &rds.ExportTask{
+		ExportTaskIdentifier: aws.String(fmt.Sprintf("export-%d", len(b.exportTasks))),
+		SourceArn:            aws.String(sourceArn),
+		Status:               aws.String(status),
+	})
+}
+
+// DBSnapshotIdentifiers returns the sorted identifiers of all stored DB
+// snapshots, whatever their state.
+func (b *Backend) DBSnapshotIdentifiers() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var ids []string
+	for id := range b.snapshots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DBClusterSnapshotIdentifiers returns the sorted identifiers of all stored
+// DB cluster snapshots, whatever their state.
+func (b *Backend) DBClusterSnapshotIdentifiers() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var ids []string
+	for id := range b.clusterSnapshots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DeletedSnapshots returns the identifiers of every snapshot that has been
+// deleted, in the order the deletes were requested.
+func (b *Backend) DeletedSnapshots() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.deleted...)
+}
+
+// DBSnapshotStatus returns the current status of a DB snapshot, or an empty
+// string if it doesn't exist.
+func (b *Backend) DBSnapshotStatus(id string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.snapshots[id]; ok {
+		return aws.StringValue(s.Status)
+	}
+	return ""
+}
+
+// DescribeDBInstances returns the registered instances.
+func (b *Backend) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := &rds.DescribeDBInstancesOutput{}
+	for id, i := range b.instances {
+		if input.DBInstanceIdentifier != nil && *input.DBInstanceIdentifier != id {
+			continue
+		}
+		c := *i
+		out.DBInstances = append(out.DBInstances, &c)
+	}
+	if input.DBInstanceIdentifier != nil && len(out.DBInstances) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "DBInstance "+*input.DBInstanceIdentifier+" not found.", nil)
+	}
+	return out, nil
+}
+
+// DescribeDBClusters returns the registered clusters.
+func (b *Backend) DescribeDBClusters(input *rds.DescribeDBClustersInput) (*rds.DescribeDBClustersOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := &rds.DescribeDBClustersOutput{}
+	for id, cl := range b.clusters {
+		if input.DBClusterIdentifier != nil && *input.DBClusterIdentifier != id {
+			continue
+		}
+		c := *cl
+		out.DBClusters = append(out.DBClusters, &c)
+	}
+	if input.DBClusterIdentifier != nil && len(out.DBClusters) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, "DBCluster "+*input.DBClusterIdentifier+" not found.", nil)
+	}
+	return out, nil
+}
+
+// DescribeDBSnapshots returns the stored snapshots matching the instance,
+// snapshot identifier and snapshot type in the input.
+func (b *Backend) DescribeDBSnapshots(input *rds.DescribeDBSnapshotsInput) (*rds.DescribeDBSnapshotsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := &rds.DescribeDBSnapshotsOutput{}
+	for _, s := range b.sortedDBSnapshots() {
+		if input.DBInstanceIdentifier != nil && aws.StringValue(s.DBInstanceIdentifier) != *input.DBInstanceIdentifier {
+			continue
+		}
+		if input.DBSnapshotIdentifier != nil && aws.StringValue(s.DBSnapshotIdentifier) != *input.DBSnapshotIdentifier {
+			continue
+		}
+		if input.SnapshotType != nil && aws.StringValue(s.SnapshotType) != *input.SnapshotType {
+			continue
+		}
+		c := *s
+		out.DBSnapshots = append(out.DBSnapshots, &c)
+	}
+	if input.DBSnapshotIdentifier != nil && len(out.DBSnapshots) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot "+*input.DBSnapshotIdentifier+" not found.", nil)
+	}
+	return out, nil
+}
+
+// DescribeDBSnapshotsPages calls fn once with every matching snapshot.
+func (b *Backend) DescribeDBSnapshotsPages(input *rds.DescribeDBSnapshotsInput, fn func(*rds.DescribeDBSnapshotsOutput, bool) bool) error {
+	out, err := b.DescribeDBSnapshots(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// DescribeDBClusterSnapshots returns the stored cluster snapshots matching
+// the cluster, snapshot identifier and snapshot type in the input.
+func (b *Backend) DescribeDBClusterSnapshots(input *rds.DescribeDBClusterSnapshotsInput) (*rds.DescribeDBClusterSnapshotsOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := &rds.DescribeDBClusterSnapshotsOutput{}
+	for _, s := range b.sortedDBClusterSnapshots() {
+		if input.DBClusterIdentifier != nil && aws.StringValue(s.DBClusterIdentifier) != *input.DBClusterIdentifier {
+			continue
+		}
+		if input.DBClusterSnapshotIdentifier != nil && aws.StringValue(s.DBClusterSnapshotIdentifier) != *input.DBClusterSnapshotIdentifier {
+			continue
+		}
+		if input.SnapshotType != nil && aws.StringValue(s.SnapshotType) != *input.SnapshotType {
+			continue
+		}
+		c := *s
+		out.DBClusterSnapshots = append(out.DBClusterSnapshots, &c)
+	}
+	if input.DBClusterSnapshotIdentifier != nil && len(out.DBClusterSnapshots) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, "DBClusterSnapshot "+*input.DBClusterSnapshotIdentifier+" not found.", nil)
+	}
+	return out, nil
+}
+
+// DescribeDBClusterSnapshotsPages calls fn once with every matching cluster
+// snapshot.
+func (b *Backend) DescribeDBClusterSnapshotsPages(input *rds.DescribeDBClusterSnapshotsInput, fn func(*rds.DescribeDBClusterSnapshotsOutput, bool) bool) error {
+	out, err := b.DescribeDBClusterSnapshots(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// DescribeDBSnapshotAttributes reports the accounts a snapshot is shared
+// with through its restore attribute.
+func (b *Backend) DescribeDBSnapshotAttributes(input *rds.DescribeDBSnapshotAttributesInput) (*rds.DescribeDBSnapshotAttributesOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	if _, ok := b.snapshots[id]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot "+id+" not found.", nil)
+	}
+	return &rds.DescribeDBSnapshotAttributesOutput{
+		DBSnapshotAttributesResult: &rds.DBSnapshotAttributesResult{
+			DBSnapshotIdentifier: aws.String(id),
+			DBSnapshotAttributes: []*rds.DBSnapshotAttribute{{
+				AttributeName:   aws.String("restore"),
+				AttributeValues: aws.StringSlice(b.sharedWith[id]),
+			}},
+		},
+	}, nil
+}
+
+// DescribeDBClusterSnapshotAttributes reports the accounts a cluster
+// snapshot is shared with through its restore attribute.
+func (b *Backend) DescribeDBClusterSnapshotAttributes(input *rds.DescribeDBClusterSnapshotAttributesInput) (*rds.DescribeDBClusterSnapshotAttributesOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	if _, ok := b.clusterSnapshots[id]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, "DBClusterSnapshot "+id+" not found.", nil)
+	}
+	return &rds.DescribeDBClusterSnapshotAttributesOutput{
+		DBClusterSnapshotAttributesResult: &rds.DBClusterSnapshotAttributesResult{
+			DBClusterSnapshotIdentifier: aws.String(id),
+			DBClusterSnapshotAttributes: []*rds.DBClusterSnapshotAttribute{{
+				AttributeName:   aws.String("restore"),
+				AttributeValues: aws.StringSlice(b.sharedWith[id]),
+			}},
+		},
+	}, nil
+}
+
+// DescribeExportTasksPages calls fn once with every recorded export task.
+func (b *Backend) DescribeExportTasksPages(input *rds.DescribeExportTasksInput, fn func(*rds.DescribeExportTasksOutput, bool) bool) error {
+	b.mu.Lock()
+	out := &rds.DescribeExportTasksOutput{}
+	for _, t := range b.exportTasks {
+		if input.SourceArn != nil && aws.StringValue(t.SourceArn) != *input.SourceArn {
+			continue
+		}
+		c := *t
+		out.ExportTasks = append(out.ExportTasks, &c)
+	}
+	b.mu.Unlock()
+	fn(out, true)
+	return nil
+}
+
+// CreateDBSnapshot stores a new manual snapshot in the "creating" state.
+func (b *Backend) CreateDBSnapshot(input *rds.CreateDBSnapshotInput) (*rds.CreateDBSnapshotOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instanceID := aws.StringValue(input.DBInstanceIdentifier)
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	if _, ok := b.instances[instanceID]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "DBInstance "+instanceID+" not found.", nil)
+	}
+	if _, ok := b.snapshots[id]; ok {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotAlreadyExistsFault, "Cannot create the snapshot because a snapshot with the identifier "+id+" already exists.", nil)
+	}
+	s := &rds.DBSnapshot{
+		DBInstanceIdentifier: aws.String(instanceID),
+		DBSnapshotArn:        aws.String(snapshotArn(id)),
+		DBSnapshotIdentifier: aws.String(id),
+		SnapshotCreateTime:   aws.Time(b.Now()),
+		SnapshotType:         aws.String("manual"),
+		Status:               aws.String(StatusCreating),
+		TagList:              input.Tags,
+	}
+	b.snapshots[id] = s
+	c := *s
+	return &rds.CreateDBSnapshotOutput{DBSnapshot: &c}, nil
+}
+
+// CreateDBClusterSnapshot stores a new manual cluster snapshot in the
+// "creating" state.
+func (b *Backend) CreateDBClusterSnapshot(input *rds.CreateDBClusterSnapshotInput) (*rds.CreateDBClusterSnapshotOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clusterID := aws.StringValue(input.DBClusterIdentifier)
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	if _, ok := b.clusters[clusterID]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, "DBCluster "+clusterID+" not found.", nil)
+	}
+	if _, ok := b.clusterSnapshots[id]; ok {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, "Cannot create the cluster snapshot because one with the identifier "+id+" already exists.", nil)
+	}
+	s := &rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(clusterID),
+		DBClusterSnapshotArn:        aws.String(clusterSnapshotArn(id)),
+		DBClusterSnapshotIdentifier: aws.String(id),
+		SnapshotCreateTime:          aws.Time(b.Now()),
+		SnapshotType:                aws.String("manual"),
+		Status:                      aws.String(StatusCreating),
+		TagList:                     input.Tags,
+	}
+	b.clusterSnapshots[id] = s
+	c := *s
+	return &rds.CreateDBClusterSnapshotOutput{DBClusterSnapshot: &c}, nil
+}
+
+// DeleteDBSnapshot moves an available snapshot to the "deleting" state.
+func (b *Backend) DeleteDBSnapshot(input *rds.DeleteDBSnapshotInput) (*rds.DeleteDBSnapshotOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	s, ok := b.snapshots[id]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot "+id+" not found.", nil)
+	}
+	if aws.StringValue(s.Status) != StatusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBSnapshotStateFault, "Cannot delete the snapshot because it is not in available state.", nil)
+	}
+	s.Status = aws.String(StatusDeleting)
+	b.deleted = append(b.deleted, id)
+	c := *s
+	return &rds.DeleteDBSnapshotOutput{DBSnapshot: &c}, nil
+}
+
+// DeleteDBClusterSnapshot moves an available cluster snapshot to the
+// "deleting" state.
+func (b *Backend) DeleteDBClusterSnapshot(input *rds.DeleteDBClusterSnapshotInput) (*rds.DeleteDBClusterSnapshotOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	s, ok := b.clusterSnapshots[id]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, "DBClusterSnapshot "+id+" not found.", nil)
+	}
+	if aws.StringValue(s.Status) != StatusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, "Cannot delete the cluster snapshot because it is not in available state.", nil)
+	}
+	s.Status = aws.String(StatusDeleting)
+	b.deleted = append(b.deleted, id)
+	c := *s
+	return &rds.DeleteDBClusterSnapshotOutput{DBClusterSnapshot: &c}, nil
+}
+
+// WaitUntilDBSnapshotAvailable finishes creating the snapshot.
+func (b *Backend) WaitUntilDBSnapshotAvailable(input *rds.DescribeDBSnapshotsInput) error {
+	return b.WaitUntilDBSnapshotAvailableWithContext(context.Background(), input)
+}
+
+// WaitUntilDBSnapshotAvailableWithContext finishes creating the snapshot,
+// unless ctx is already done.
+func (b *Backend) WaitUntilDBSnapshotAvailableWithContext(ctx aws.Context, input *rds.DescribeDBSnapshotsInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return awserr.New(request.CanceledErrorCode, "waiter context canceled", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	s, ok := b.snapshots[id]
+	if !ok || aws.StringValue(s.Status) == StatusDeleting {
+		return awserr.New(request.WaiterResourceNotReadyErrorCode, "failed waiting for successful resource state", nil)
+	}
+	s.Status = aws.String(StatusAvailable)
+	return nil
+}
+
+// WaitUntilDBSnapshotDeleted finishes deleting the snapshot.
+func (b *Backend) WaitUntilDBSnapshotDeleted(input *rds.DescribeDBSnapshotsInput) error {
+	return b.WaitUntilDBSnapshotDeletedWithContext(context.Background(), input)
+}
+
+// WaitUntilDBSnapshotDeletedWithContext finishes deleting the snapshot,
+// unless ctx is already done.
+func (b *Backend) WaitUntilDBSnapshotDeletedWithContext(ctx aws.Context, input *rds.DescribeDBSnapshotsInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return awserr.New(request.CanceledErrorCode, "waiter context canceled", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	s, ok := b.snapshots[id]
+	if !ok {
+		return nil
+	}
+	if aws.StringValue(s.Status) != StatusDeleting {
+		return awserr.New(request.WaiterResourceNotReadyErrorCode, "failed waiting for successful resource state", nil)
+	}
+	delete(b.snapshots, id)
+	delete(b.sharedWith, id)
+	return nil
+}
+
+// WaitUntilDBClusterSnapshotAvailable finishes creating the cluster snapshot.
+func (b *Backend) WaitUntilDBClusterSnapshotAvailable(input *rds.DescribeDBClusterSnapshotsInput) error {
+	return b.WaitUntilDBClusterSnapshotAvailableWithContext(context.Background(), input)
+}
+
+// WaitUntilDBClusterSnapshotAvailableWithContext finishes creating the
+// cluster snapshot, unless ctx is already done.
+func (b *Backend) WaitUntilDBClusterSnapshotAvailableWithContext(ctx aws.Context, input *rds.DescribeDBClusterSnapshotsInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return awserr.New(request.CanceledErrorCode, "waiter context canceled", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	s, ok := b.clusterSnapshots[id]
+	if !ok || aws.StringValue(s.Status) == StatusDeleting {
+		return awserr.New(request.WaiterResourceNotReadyErrorCode, "failed waiting for successful resource state", nil)
+	}
+	s.Status = aws.String(StatusAvailable)
+	return nil
+}
+
+// WaitUntilDBClusterSnapshotDeleted finishes deleting the cluster snapshot.
+func (b *Backend) WaitUntilDBClusterSnapshotDeleted(input *rds.DescribeDBClusterSnapshotsInput) error {
+	return b.WaitUntilDBClusterSnapshotDeletedWithContext(context.Background(), input)
+}
+
+// WaitUntilDBClusterSnapshotDeletedWithContext finishes deleting the cluster
+// snapshot, unless ctx is already done.
+func (b *Backend) WaitUntilDBClusterSnapshotDeletedWithContext(ctx aws.Context, input *rds.DescribeDBClusterSnapshotsInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return awserr.New(request.CanceledErrorCode, "waiter context canceled", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	s, ok := b.clusterSnapshots[id]
+	if !ok {
+		return nil
+	}
+	if aws.StringValue(s.Status) != StatusDeleting {
+		return awserr.New(request.WaiterResourceNotReadyErrorCode, "failed waiting for successful resource state", nil)
+	}
+	delete(b.clusterSnapshots, id)
+	delete(b.sharedWith, id)
+	return nil
+}
+
+func (b *Backend) sortedDBSnapshots() []*rds.DBSnapshot {
+	var out []*rds.DBSnapshot
+	for _, s := range b.snapshots {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return *out[i].DBSnapshotIdentifier < *out[j].DBSnapshotIdentifier
+	})
+	return out
+}
+
+func (b *Backend) sortedDBClusterSnapshots() []*rds.DBClusterSnapshot {
+	var out []*rds.DBClusterSnapshot
+	for _, s := range b.clusterSnapshots {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return *out[i].DBClusterSnapshotIdentifier < *out[j].DBClusterSnapshotIdentifier
+	})
+	return out
+}
+
+func instanceArn(id string) string {
+	return "arn:aws:rds:us-west-2:123456789012:db:" + id
+}
+
+func clusterArn(id string) string {
+	return "arn:aws:rds:us-west-2:123456789012:cluster:" + id
+}
+
+func snapshotArn(id string) string {
+	return "arn:aws:rds:us-west-2:123456789012:snapshot:" + id
+}
+
+func clusterSnapshotArn(id string) string {
+	return "arn:aws:rds:us-west-2:123456789012:cluster-snapshot:" + id
+}
@@ -1,28 +1,173 @@
 package awshealth
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // PersonalHealthDashboardURL is the URL to view AWS's Personal Health Dashboard
 const PersonalHealthDashboardURL = "https://phd.aws.amazon.com/phd/home"
 
+// Event status codes.
+const (
+	StatusOpen     = "open"
+	StatusClosed   = "closed"
+	StatusUpcoming = "upcoming"
+)
+
+// Event type categories.
+const (
+	CategoryIssue               = "issue"
+	CategoryScheduledChange     = "scheduledChange"
+	CategoryAccountNotification = "accountNotification"
+	CategoryInvestigation       = "investigation"
+)
+
+// healthTimeFormats are the timestamp formats seen in health events.
+var healthTimeFormats = []string{
+	time.RFC1123,
+	time.RFC3339,
+}
+
+// Time is a health event timestamp. Events use RFC 1123 dates such as
+// "Sat, 05 Jun 2016 15:10:09 GMT"; RFC 3339 is accepted too.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Empty strings and null parse to
+// the zero time.
+func (t *Time) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	for _, format := range healthTimeFormats {
+		if parsed, err := time.Parse(format, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("unable to parse health event time %q", s)
+}
+
 // EventDescription is AWS Health Event Descripion
 type EventDescription struct {
 	Language string `json:"language"`
 	Latest   string `json:"latestDescription"`
 }
 
+// AffectedEntity is a resource, such as an instance or volume, affected by an
+// event.
+type AffectedEntity struct {
+	EntityValue string            `json:"entityValue"`
+	Tags        map[string]string `json:"tags"`
+}
+
 // Event defines relevant info out of the json payload from AWS Personal Health
 type Event struct {
+	AffectedAccount   string             `json:"affectedAccount"`
+	AffectedEntities  []AffectedEntity   `json:"affectedEntities"`
+	CommunicationID   string             `json:"communicationId"`
 	Description       []EventDescription `json:"eventDescription"`
+	EndTime           Time               `json:"endTime"`
 	EventARN          string             `json:"eventArn"`
+	EventRegion       string             `json:"eventRegion"`
+	EventScopeCode    string             `json:"eventScopeCode"`
 	EventTypeCategory string             `json:"eventTypeCategory"`
 	EventTypeCode     string             `json:"eventTypeCode"`
+	LastUpdatedTime   Time               `json:"lastUpdatedTime"`
 	Service           string             `json:"service"`
+	StartTime         Time               `json:"startTime"`
+	StatusCode        string             `json:"statusCode"`
 }
 
 // HealthEventURL returns the unique unescaped URL asscociated with an AWS health event
 func (h *Event) HealthEventURL() string {
 	return fmt.Sprintf("%s#/dashboard/open-issues?eventID=%s&eventTab=details&layout=horizontal", PersonalHealthDashboardURL, h.EventARN)
 }
+
+// EnglishDescription returns the latest description in English, falling
+// back to the first description, or "" if there is none.
+func (h *Event) EnglishDescription() string {
+	for _, d := range h.Description {
+		if d.Language == "en_US" {
+			return d.Latest
+		}
+	}
+	for _, d := range h.Description {
+		if strings.HasPrefix(d.Language, "en") {
+			return d.Latest
+		}
+	}
+	if len(h.Description) > 0 {
+		return h.Description[0].Latest
+	}
+	return ""
+}
+
+// IsOpen reports whether the event is still in progress.
+func (h *Event) IsOpen() bool {
+	return h.StatusCode == StatusOpen
+}
+
+// AffectedEntityValues returns the IDs of the affected resources.
+func (h *Event) AffectedEntityValues() []string {
+	var values []string
+	for _, e := range h.AffectedEntities {
+		if e.EntityValue != "" {
+			values = append(values, e.EntityValue)
+		}
+	}
+	return values
+}
+
+// Duration returns how long the event lasted, or has lasted so far at now if
+// it has no end time. It is zero when the event has no start time or has not
+// started yet.
+func (h *Event) Duration(now time.Time) time.Duration {
+	if h.StartTime.IsZero() {
+		return 0
+	}
+	end := now
+	if !h.EndTime.IsZero() {
+		end = h.EndTime.Time
+	}
+	if end.Before(h.StartTime.Time) {
+		return 0
+	}
+	return end.Sub(h.StartTime.Time)
+}
+
+// ReadableDuration formats Duration as days, hours and minutes, such as
+// "1d 2h 5m", or "less than a minute".
+func (h *Event) ReadableDuration(now time.Time) string {
+	return readableDuration(h.Duration(now))
+}
+
+func readableDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	if minutes == 0 {
+		return "less than a minute"
+	}
+	days, hours := minutes/(24*60), minutes/60%24
+	minutes %= 60
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return strings.Join(parts, " ")
+}
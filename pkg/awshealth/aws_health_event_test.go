@@ -0,0 +1,121 @@
+package awshealth
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// readEvent decodes the detail of a CloudWatch event fixture.
+func readEvent(t *testing.T, name string) *Event {
+	content, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cw events.CloudWatchEvent
+	if err := json.Unmarshal(content, &cw); err != nil {
+		t.Fatal(err)
+	}
+	var h Event
+	if err := json.Unmarshal(cw.Detail, &h); err != nil {
+		t.Fatal(err)
+	}
+	return &h
+}
+
+func TestEventSchema(t *testing.T) {
+	h := readEvent(t, "ec2_issue.json")
+
+	if h.Service != "EC2" || h.EventTypeCategory != CategoryIssue || h.EventScopeCode != "ACCOUNT_SPECIFIC" {
+		t.Errorf("event = %s/%s/%s", h.Service, h.EventTypeCategory, h.EventScopeCode)
+	}
+	if h.EventRegion != "us-west-2" || h.AffectedAccount != "123456789012" {
+		t.Errorf("region/account = %s/%s", h.EventRegion, h.AffectedAccount)
+	}
+	if !h.StartTime.Equal(time.Date(2016, 6, 5, 15, 10, 9, 0, time.UTC)) {
+		t.Errorf("start time = %v", h.StartTime)
+	}
+	if !h.EndTime.IsZero() {
+		t.Errorf("end time = %v, want zero", h.EndTime)
+	}
+	if !h.IsOpen() {
+		t.Error("IsOpen() = false, want true")
+	}
+	if want := []string{"i-abcd1111", "i-abcd2222"}; !reflect.DeepEqual(h.AffectedEntityValues(), want) {
+		t.Errorf("AffectedEntityValues() = %v, want %v", h.AffectedEntityValues(), want)
+	}
+	if h.AffectedEntities[0].Tags["stage"] != "prod" {
+		t.Errorf("entity tags = %v", h.AffectedEntities[0].Tags)
+	}
+	if have := h.EnglishDescription(); have != "A description of the event will be provided here" {
+		t.Errorf("EnglishDescription() = %q", have)
+	}
+	now := h.StartTime.Add(26*time.Hour + 5*time.Minute)
+	if have := h.ReadableDuration(now); have != "1d 2h 5m" {
+		t.Errorf("ReadableDuration() = %q, want 1d 2h 5m", have)
+	}
+}
+
+func TestEventSchemaClosed(t *testing.T) {
+	h := readEvent(t, "ebs_scheduled_change.json")
+
+	if h.IsOpen() || h.StatusCode != StatusClosed {
+		t.Errorf("status = %s, want closed", h.StatusCode)
+	}
+	// The end time bounds the duration no matter when it is asked.
+	if have := h.ReadableDuration(time.Now()); have != "2h 58m" {
+		t.Errorf("ReadableDuration() = %q, want 2h 58m", have)
+	}
+	if want := []string{"vol-0123456789abcdef0"}; !reflect.DeepEqual(h.AffectedEntityValues(), want) {
+		t.Errorf("AffectedEntityValues() = %v, want %v", h.AffectedEntityValues(), want)
+	}
+}
+
+func TestEventSchemaAccountNotification(t *testing.T) {
+	h := readEvent(t, "account_notification.json")
+
+	if h.EventTypeCategory != CategoryAccountNotification || h.StatusCode != StatusUpcoming {
+		t.Errorf("event = %s/%s", h.EventTypeCategory, h.StatusCode)
+	}
+	if len(h.AffectedEntityValues()) != 0 || h.AffectedAccount != "" {
+		t.Errorf("entities/account = %v/%q, want none", h.AffectedEntityValues(), h.AffectedAccount)
+	}
+	if have := h.ReadableDuration(h.StartTime.Add(-time.Hour)); have != "less than a minute" {
+		t.Errorf("ReadableDuration() before start = %q", have)
+	}
+}
+
+func TestTimeUnmarshal(t *testing.T) {
+	var v struct {
+		A Time `json:"a"`
+		B Time `json:"b"`
+		C Time `json:"c"`
+	}
+	err := json.Unmarshal([]byte(`{"a": "2023-01-27T09:01:22Z", "b": "", "c": null}`), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.A.Equal(time.Date(2023, 1, 27, 9, 1, 22, 0, time.UTC)) || !v.B.IsZero() || !v.C.IsZero() {
+		t.Errorf("times = %v %v %v", v.A, v.B, v.C)
+	}
+	if err := json.Unmarshal([]byte(`{"a": "yesterday"}`), &v); err == nil {
+		t.Error("Unmarshal() of an invalid time did not return an error")
+	}
+}
+
+func TestEventBlocks(t *testing.T) {
+	blocks := readEvent(t, "ebs_scheduled_change.json").Blocks()
+	if len(blocks) != 5 {
+		t.Fatalf("Blocks() has %d blocks, want 5", len(blocks))
+	}
+	if have := blocks[1].Fields[1].Text; have != "*Status*\nclosed after 2h 58m" {
+		t.Errorf("status field = %q", have)
+	}
+	if have := blocks[3].Text.Text; have != "*Affected resources*\nvol-0123456789abcdef0" {
+		t.Errorf("affected resources = %q", have)
+	}
+}
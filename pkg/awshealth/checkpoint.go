@@ -0,0 +1,114 @@
+package awshealth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// CheckpointStore remembers the last updated time of the newest polled event
+// that was notified about.
+type CheckpointStore interface {
+	// GetCheckpoint returns the checkpoint, or ok false before the first
+	// poll.
+	GetCheckpoint() (checkpoint time.Time, ok bool, err error)
+	PutCheckpoint(checkpoint time.Time) error
+}
+
+// ResumeCheckpoint returns the checkpoint after the first sent of events,
+// sorted oldest first, were notified about. Polls only return events updated
+// after the checkpoint, and events can share a last updated time, such as
+// the copies of an organization event for each account. So the checkpoint
+// stops short of the time of the first event that was not sent, and the
+// next poll returns every event at that time again.
+func ResumeCheckpoint(events []*Event, sent int, since time.Time) time.Time {
+	checkpoint := since
+	for _, h := range events[:sent] {
+		if sent < len(events) && !h.LastUpdatedTime.Before(events[sent].LastUpdatedTime.Time) {
+			break
+		}
+		checkpoint = h.LastUpdatedTime.Time
+	}
+	return checkpoint
+}
+
+// FileCheckpointStore keeps the checkpoint in a local file.
+type FileCheckpointStore struct {
+	Path string
+}
+
+// GetCheckpoint implements CheckpointStore.
+func (f *FileCheckpointStore) GetCheckpoint() (time.Time, bool, error) {
+	content, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	return parseCheckpoint(string(content))
+}
+
+// PutCheckpoint implements CheckpointStore.
+func (f *FileCheckpointStore) PutCheckpoint(checkpoint time.Time) error {
+	err := os.WriteFile(f.Path, []byte(formatCheckpoint(checkpoint)+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// SSMCheckpointStore keeps the checkpoint in a Parameter Store string
+// parameter.
+type SSMCheckpointStore struct {
+	SSMClient ssmiface.SSMAPI
+	Name      string
+}
+
+// GetCheckpoint implements CheckpointStore.
+func (s *SSMCheckpointStore) GetCheckpoint() (time.Time, bool, error) {
+	output, err := s.SSMClient.GetParameter(&ssm.GetParameterInput{
+		Name: aws.String(s.Name),
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get checkpoint parameter %s: %w", s.Name, err)
+	}
+	return parseCheckpoint(aws.StringValue(output.Parameter.Value))
+}
+
+// PutCheckpoint implements CheckpointStore.
+func (s *SSMCheckpointStore) PutCheckpoint(checkpoint time.Time) error {
+	_, err := s.SSMClient.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(s.Name),
+		Overwrite: aws.Bool(true),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Value:     aws.String(formatCheckpoint(checkpoint)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put checkpoint parameter %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+func parseCheckpoint(value string) (time.Time, bool, error) {
+	checkpoint, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return checkpoint, true, nil
+}
+
+func formatCheckpoint(checkpoint time.Time) string {
+	return checkpoint.UTC().Format(time.RFC3339Nano)
+}
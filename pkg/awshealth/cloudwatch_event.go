@@ -0,0 +1,71 @@
+package awshealth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// EventSource is the source of health events delivered by CloudWatch Events
+// and EventBridge.
+const EventSource = "aws.health"
+
+// ParseCloudWatchEvent returns the health event carried in the detail of a
+// CloudWatch or EventBridge event. Events from other sources, and details
+// that are not health events, are errors.
+func ParseCloudWatchEvent(event events.CloudWatchEvent) (*Event, error) {
+	if event.Source != "" && event.Source != EventSource {
+		return nil, fmt.Errorf("event %s has source %q, not %q", event.ID, event.Source, EventSource)
+	}
+	if len(bytes.TrimSpace(event.Detail)) == 0 {
+		return nil, fmt.Errorf("event %s has no detail", event.ID)
+	}
+
+	var h Event
+	if err := json.Unmarshal(event.Detail, &h); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal health event %s: %w", event.ID, err)
+	}
+	if h.EventARN == "" {
+		return nil, fmt.Errorf("health event %s has no eventArn", event.ID)
+	}
+	return &h, nil
+}
+
+// ReadCloudWatchEvents decodes the CloudWatch events in r. r may hold one
+// event, several concatenated events, or a JSON array of events.
+func ReadCloudWatchEvents(r io.Reader) ([]events.CloudWatchEvent, error) {
+	var cwEvents []events.CloudWatchEvent
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event: %w", err)
+		}
+
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			var list []events.CloudWatchEvent
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("failed to decode events: %w", err)
+			}
+			cwEvents = append(cwEvents, list...)
+			continue
+		}
+		var event events.CloudWatchEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %w", err)
+		}
+		cwEvents = append(cwEvents, event)
+	}
+	if len(cwEvents) == 0 {
+		return nil, errors.New("no events found")
+	}
+	return cwEvents, nil
+}
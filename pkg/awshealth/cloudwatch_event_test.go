@@ -0,0 +1,69 @@
+package awshealth
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestReadCloudWatchEvents(t *testing.T) {
+	single, err := os.ReadFile("testdata/ec2_issue.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := os.ReadFile("testdata/ebs_scheduled_change.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name  string
+		input string
+		count int
+	}{
+		{"single", string(single), 1},
+		{"concatenated", string(single) + "\n" + string(other), 2},
+		{"array", "[" + string(single) + "," + string(other) + "]", 2},
+	}
+	for _, test := range tests {
+		cwEvents, err := ReadCloudWatchEvents(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(cwEvents) != test.count {
+			t.Errorf("%s: read %d events, want %d", test.name, len(cwEvents), test.count)
+		}
+		for _, e := range cwEvents {
+			if _, err := ParseCloudWatchEvent(e); err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		}
+	}
+
+	for _, input := range []string{"", "{", "not json"} {
+		if _, err := ReadCloudWatchEvents(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadCloudWatchEvents(%q) returned no error", input)
+		}
+	}
+}
+
+func TestParseCloudWatchEventErrors(t *testing.T) {
+	var tests = []struct {
+		name  string
+		event events.CloudWatchEvent
+	}{
+		{"other source", events.CloudWatchEvent{Source: "aws.ec2", Detail: json.RawMessage(`{"eventArn": "arn"}`)}},
+		{"no detail", events.CloudWatchEvent{Source: EventSource}},
+		{"bad detail", events.CloudWatchEvent{Source: EventSource, Detail: json.RawMessage(`"text"`)}},
+		{"no arn", events.CloudWatchEvent{Source: EventSource, Detail: json.RawMessage(`{"service": "EC2"}`)}},
+	}
+	for _, test := range tests {
+		if _, err := ParseCloudWatchEvent(test.event); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
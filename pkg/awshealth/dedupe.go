@@ -0,0 +1,137 @@
+package awshealth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DefaultDedupeTTL is how long a delivered event is remembered.
+const DefaultDedupeTTL = 24 * time.Hour
+
+// DedupeKey identifies one delivery of an event. EventBridge redelivering
+// an event, or rules in several regions forwarding it, produce the same key;
+// a status change or new description does not. The copies of an
+// organization event for each affected account have their own keys.
+func (h *Event) DedupeKey() string {
+	key := fmt.Sprintf("%s|%s|%s", h.EventARN, h.StatusCode, h.LastUpdatedTime.UTC().Format(time.RFC3339))
+	if h.AffectedAccount != "" {
+		key += "|" + h.AffectedAccount
+	}
+	return key
+}
+
+// DedupeStore remembers which dedupe keys have been notified about.
+type DedupeStore interface {
+	// MarkSeen records key and reports whether it was already recorded
+	// and not yet expired.
+	MarkSeen(key string, now time.Time) (seen bool, err error)
+	// Forget removes key, so an event whose notification failed is sent
+	// again when it is retried.
+	Forget(key string) error
+}
+
+// MemoryDedupeStore keeps dedupe keys in memory for TTL, or DefaultDedupeTTL
+// when TTL is 0. It only catches duplicates seen by the same process.
+type MemoryDedupeStore struct {
+	TTL time.Duration
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// MarkSeen implements DedupeStore.
+func (m *MemoryDedupeStore) MarkSeen(key string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.expires == nil {
+		m.expires = map[string]time.Time{}
+	}
+	for k, expires := range m.expires {
+		if !now.Before(expires) {
+			delete(m.expires, k)
+		}
+	}
+	if _, ok := m.expires[key]; ok {
+		return true, nil
+	}
+	m.expires[key] = now.Add(dedupeTTL(m.TTL))
+	return false, nil
+}
+
+// Forget implements DedupeStore.
+func (m *MemoryDedupeStore) Forget(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.expires, key)
+	return nil
+}
+
+// DynamoDBDedupeStore keeps dedupe keys in a DynamoDB table whose partition
+// key is the string attribute Key. Items expire after TTL, or
+// DefaultDedupeTTL when TTL is 0; enable DynamoDB TTL on the ExpiresAt
+// attribute to have them removed.
+type DynamoDBDedupeStore struct {
+	DynamoDBClient dynamodbiface.DynamoDBAPI
+	TableName      string
+	TTL            time.Duration
+}
+
+// MarkSeen implements DedupeStore. The key is written with a conditional
+// put, so only one of several concurrent deliveries sees it as new.
+func (d *DynamoDBDedupeStore) MarkSeen(key string, now time.Time) (bool, error) {
+	_, err := d.DynamoDBClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(d.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"Key":       {S: aws.String(key)},
+			"ExpiresAt": {N: aws.String(unixString(now.Add(dedupeTTL(d.TTL))))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR ExpiresAt <= :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String("Key"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(unixString(now))},
+		},
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to put dedupe key %s: %w", key, err)
+	}
+	return false, nil
+}
+
+// Forget implements DedupeStore.
+func (d *DynamoDBDedupeStore) Forget(key string) error {
+	_, err := d.DynamoDBClient.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(d.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Key": {S: aws.String(key)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete dedupe key %s: %w", key, err)
+	}
+	return nil
+}
+
+func dedupeTTL(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return DefaultDedupeTTL
+	}
+	return ttl
+}
+
+func unixString(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
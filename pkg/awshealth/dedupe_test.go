@@ -0,0 +1,99 @@
+package awshealth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// mockStateTable is a DynamoDB table keyed by Key. Conditional puts fail
+// when the item exists and has not expired.
+type mockStateTable struct {
+	dynamodbiface.DynamoDBAPI
+	Items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockStateTable) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.Items[aws.StringValue(input.Key["Key"].S)]}, nil
+}
+
+func (m *mockStateTable) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	key := aws.StringValue(input.Item["Key"].S)
+	if existing, ok := m.Items[key]; ok && input.ConditionExpression != nil {
+		expires, _ := strconv.ParseInt(aws.StringValue(existing["ExpiresAt"].N), 10, 64)
+		now, _ := strconv.ParseInt(aws.StringValue(input.ExpressionAttributeValues[":now"].N), 10, 64)
+		if expires > now {
+			return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+		}
+	}
+	m.Items[key] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockStateTable) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	delete(m.Items, aws.StringValue(input.Key["Key"].S))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDedupeKey(t *testing.T) {
+	h := readEvent(t, "ec2_issue.json")
+	key := h.DedupeKey()
+	if want := h.EventARN + "|open|2016-06-05T15:10:09Z|123456789012"; key != want {
+		t.Errorf("DedupeKey() = %q, want %q", key, want)
+	}
+	h.StatusCode = StatusClosed
+	if h.DedupeKey() == key {
+		t.Error("DedupeKey() did not change with the status")
+	}
+	other := *h
+	other.AffectedAccount = "222222222222"
+	if other.DedupeKey() == h.DedupeKey() {
+		t.Error("DedupeKey() is the same for another account's copy of the event")
+	}
+}
+
+func TestDedupeStores(t *testing.T) {
+	stores := map[string]DedupeStore{
+		"memory": &MemoryDedupeStore{TTL: time.Hour},
+		"dynamodb": &DynamoDBDedupeStore{
+			DynamoDBClient: &mockStateTable{Items: map[string]map[string]*dynamodb.AttributeValue{}},
+			TableName:      "health-state",
+			TTL:            time.Hour,
+		},
+	}
+	now := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, store := range stores {
+		var tests = []struct {
+			key  string
+			now  time.Time
+			seen bool
+		}{
+			{"a", now, false},
+			{"a", now.Add(time.Minute), true},
+			{"b", now.Add(time.Minute), false},
+			{"a", now.Add(2 * time.Hour), false},
+		}
+		for i, test := range tests {
+			seen, err := store.MarkSeen(test.key, test.now)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if seen != test.seen {
+				t.Errorf("%s: MarkSeen %d (%s) = %v, want %v", name, i, test.key, seen, test.seen)
+			}
+		}
+
+		if err := store.Forget("b"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if seen, _ := store.MarkSeen("b", now.Add(2*time.Minute)); seen {
+			t.Errorf("%s: MarkSeen after Forget = true, want false", name)
+		}
+	}
+}
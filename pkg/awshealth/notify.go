@@ -0,0 +1,64 @@
+package awshealth
+
+import (
+	"strings"
+
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+)
+
+// CategorySeverity returns the notification severity of an event category.
+func CategorySeverity(category string) notify.Severity {
+	switch category {
+	case CategoryScheduledChange:
+		return notify.SeverityWarning
+	case CategoryAccountNotification:
+		return notify.SeverityInfo
+	}
+	return notify.SeverityCritical
+}
+
+// NotifyMessage builds the neutral notification for an event sent along
+// route.
+func NotifyMessage(data MessageData, route Route) *notify.Message {
+	h := data.Event
+	status := h.StatusCode
+	if status == "" {
+		status = "unknown"
+	}
+	m := &notify.Message{
+		Source:   "aws-health-notifier",
+		Title:    "AWS Health Notification",
+		Text:     data.LatestDescription,
+		Link:     data.EventURL,
+		Severity: CategorySeverity(h.EventTypeCategory),
+		Color:    route.Color,
+		Fields: []notify.Field{
+			{Title: "Service", Value: h.Service, Short: true},
+			{Title: "Status", Value: status, Short: true},
+			{Title: "EventTypeCode", Value: h.EventTypeCode},
+		},
+		Mentions: route.Mentions,
+		DedupKey: h.EventARN,
+	}
+	if h.EventRegion != "" {
+		m.Fields = append(m.Fields, notify.Field{Title: "Region", Value: h.EventRegion, Short: true})
+	}
+	if len(data.AffectedEntities) > 0 {
+		m.Fields = append(m.Fields, notify.Field{Title: "Affected Resources", Value: strings.Join(data.AffectedEntities, ", ")})
+	}
+	return m
+}
+
+// DigestNotifyMessage builds the neutral notification for the events held
+// back on route by the rate limit.
+func DigestNotifyMessage(lines []string, route Route) *notify.Message {
+	return &notify.Message{
+		Source:   "aws-health-notifier",
+		Title:    "AWS Health Digest",
+		Text:     DigestText(lines),
+		Link:     PersonalHealthDashboardURL,
+		Severity: notify.SeverityWarning,
+		Color:    route.Color,
+		Mentions: route.Mentions,
+	}
+}
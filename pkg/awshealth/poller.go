@@ -0,0 +1,215 @@
+package awshealth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/health"
+	"github.com/aws/aws-sdk-go/service/health/healthiface"
+)
+
+// HealthAPIRegion is the region of the global AWS Health API endpoint.
+const HealthAPIRegion = "us-east-1"
+
+// healthBatchSize is the most events DescribeEventDetails takes per call.
+const healthBatchSize = 10
+
+// Poller pulls events from the AWS Health API, for accounts that have no
+// EventBridge rule sending them to the notifier.
+type Poller struct {
+	HealthClient healthiface.HealthAPI
+	// Organization uses the organizational view, which returns one event
+	// per affected account in the AWS Organization.
+	Organization bool
+}
+
+// Poll returns the events last updated after since, oldest first, with
+// their descriptions and affected entities.
+func (p *Poller) Poll(ctx context.Context, since time.Time) ([]*Event, error) {
+	var events []*Event
+	var err error
+	if p.Organization {
+		events, err = p.pollOrganization(ctx, since)
+	} else {
+		events, err = p.pollAccount(ctx, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastUpdatedTime.Before(events[j].LastUpdatedTime.Time)
+	})
+	return events, nil
+}
+
+func (p *Poller) pollAccount(ctx context.Context, since time.Time) ([]*Event, error) {
+	var arns []*string
+	err := p.HealthClient.DescribeEventsPagesWithContext(ctx, &health.DescribeEventsInput{
+		Filter: &health.EventFilter{
+			LastUpdatedTimes: []*health.DateTimeRange{{From: aws.Time(since)}},
+		},
+	}, func(page *health.DescribeEventsOutput, lastPage bool) bool {
+		for _, e := range page.Events {
+			if aws.TimeValue(e.LastUpdatedTime).After(since) {
+				arns = append(arns, e.Arn)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe health events: %w", err)
+	}
+
+	var events []*Event
+	for start := 0; start < len(arns); start += healthBatchSize {
+		output, err := p.HealthClient.DescribeEventDetailsWithContext(ctx, &health.DescribeEventDetailsInput{
+			EventArns: arns[start:batchEnd(start, len(arns))],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe health event details: %w", err)
+		}
+		if len(output.FailedSet) > 0 {
+			f := output.FailedSet[0]
+			return nil, fmt.Errorf("failed to describe health event %s: %s",
+				aws.StringValue(f.EventArn), aws.StringValue(f.ErrorMessage))
+		}
+		for _, d := range output.SuccessfulSet {
+			events = append(events, newPolledEvent(d.Event, "", d.EventDescription))
+		}
+	}
+
+	for _, h := range events {
+		err := p.HealthClient.DescribeAffectedEntitiesPagesWithContext(ctx, &health.DescribeAffectedEntitiesInput{
+			Filter: &health.EntityFilter{EventArns: []*string{aws.String(h.EventARN)}},
+		}, func(page *health.DescribeAffectedEntitiesOutput, lastPage bool) bool {
+			h.AffectedEntities = appendEntities(h.AffectedEntities, page.Entities)
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe entities affected by %s: %w", h.EventARN, err)
+		}
+	}
+	return events, nil
+}
+
+func (p *Poller) pollOrganization(ctx context.Context, since time.Time) ([]*Event, error) {
+	var arns []string
+	err := p.HealthClient.DescribeEventsForOrganizationPagesWithContext(ctx, &health.DescribeEventsForOrganizationInput{
+		Filter: &health.OrganizationEventFilter{
+			LastUpdatedTime: &health.DateTimeRange{From: aws.Time(since)},
+		},
+	}, func(page *health.DescribeEventsForOrganizationOutput, lastPage bool) bool {
+		for _, e := range page.Events {
+			if aws.TimeValue(e.LastUpdatedTime).After(since) {
+				arns = append(arns, aws.StringValue(e.Arn))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe organization health events: %w", err)
+	}
+
+	// Public events have no affected accounts and are described once,
+	// without an account.
+	var filters []*health.EventAccountFilter
+	for _, arn := range arns {
+		var accounts []*string
+		err := p.HealthClient.DescribeAffectedAccountsForOrganizationPagesWithContext(ctx, &health.DescribeAffectedAccountsForOrganizationInput{
+			EventArn: aws.String(arn),
+		}, func(page *health.DescribeAffectedAccountsForOrganizationOutput, lastPage bool) bool {
+			accounts = append(accounts, page.AffectedAccounts...)
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe accounts affected by %s: %w", arn, err)
+		}
+		if len(accounts) == 0 {
+			filters = append(filters, &health.EventAccountFilter{EventArn: aws.String(arn)})
+		}
+		for _, account := range accounts {
+			filters = append(filters, &health.EventAccountFilter{EventArn: aws.String(arn), AwsAccountId: account})
+		}
+	}
+
+	var events []*Event
+	for start := 0; start < len(filters); start += healthBatchSize {
+		output, err := p.HealthClient.DescribeEventDetailsForOrganizationWithContext(ctx, &health.DescribeEventDetailsForOrganizationInput{
+			OrganizationEventDetailFilters: filters[start:batchEnd(start, len(filters))],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe organization health event details: %w", err)
+		}
+		if len(output.FailedSet) > 0 {
+			f := output.FailedSet[0]
+			return nil, fmt.Errorf("failed to describe health event %s in %s: %s",
+				aws.StringValue(f.EventArn), aws.StringValue(f.AwsAccountId), aws.StringValue(f.ErrorMessage))
+		}
+		for _, d := range output.SuccessfulSet {
+			events = append(events, newPolledEvent(d.Event, aws.StringValue(d.AwsAccountId), d.EventDescription))
+		}
+	}
+
+	for _, h := range events {
+		filter := &health.EventAccountFilter{EventArn: aws.String(h.EventARN)}
+		if h.AffectedAccount != "" {
+			filter.AwsAccountId = aws.String(h.AffectedAccount)
+		}
+		err := p.HealthClient.DescribeAffectedEntitiesForOrganizationPagesWithContext(ctx, &health.DescribeAffectedEntitiesForOrganizationInput{
+			OrganizationEntityFilters: []*health.EventAccountFilter{filter},
+		}, func(page *health.DescribeAffectedEntitiesForOrganizationOutput, lastPage bool) bool {
+			h.AffectedEntities = appendEntities(h.AffectedEntities, page.Entities)
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe entities affected by %s: %w", h.EventARN, err)
+		}
+	}
+	return events, nil
+}
+
+// newPolledEvent converts a Health API event to the event CloudWatch would
+// have delivered.
+func newPolledEvent(e *health.Event, account string, description *health.EventDescription) *Event {
+	h := &Event{
+		AffectedAccount:   account,
+		EndTime:           Time{aws.TimeValue(e.EndTime)},
+		EventARN:          aws.StringValue(e.Arn),
+		EventRegion:       aws.StringValue(e.Region),
+		EventScopeCode:    aws.StringValue(e.EventScopeCode),
+		EventTypeCategory: aws.StringValue(e.EventTypeCategory),
+		EventTypeCode:     aws.StringValue(e.EventTypeCode),
+		LastUpdatedTime:   Time{aws.TimeValue(e.LastUpdatedTime)},
+		Service:           aws.StringValue(e.Service),
+		StartTime:         Time{aws.TimeValue(e.StartTime)},
+		StatusCode:        aws.StringValue(e.StatusCode),
+	}
+	if description != nil && description.LatestDescription != nil {
+		h.Description = []EventDescription{{
+			Language: "en_US",
+			Latest:   aws.StringValue(description.LatestDescription),
+		}}
+	}
+	return h
+}
+
+func appendEntities(entities []AffectedEntity, page []*health.AffectedEntity) []AffectedEntity {
+	for _, e := range page {
+		entity := AffectedEntity{EntityValue: aws.StringValue(e.EntityValue)}
+		if len(e.Tags) > 0 {
+			entity.Tags = aws.StringValueMap(e.Tags)
+		}
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
+func batchEnd(start, length int) int {
+	if start+healthBatchSize < length {
+		return start + healthBatchSize
+	}
+	return length
+}
@@ -0,0 +1,253 @@
+package awshealth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/health"
+	"github.com/aws/aws-sdk-go/service/health/healthiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// mockHealthClient serves Events, with the affected accounts in Accounts.
+type mockHealthClient struct {
+	healthiface.HealthAPI
+	Events   []*health.Event
+	Accounts map[string][]string
+}
+
+func (m *mockHealthClient) DescribeEventsPagesWithContext(ctx aws.Context, input *health.DescribeEventsInput, fn func(*health.DescribeEventsOutput, bool) bool, opts ...request.Option) error {
+	from := aws.TimeValue(input.Filter.LastUpdatedTimes[0].From)
+	var page []*health.Event
+	for _, e := range m.Events {
+		if !aws.TimeValue(e.LastUpdatedTime).Before(from) {
+			page = append(page, e)
+		}
+	}
+	fn(&health.DescribeEventsOutput{Events: page}, true)
+	return nil
+}
+
+func (m *mockHealthClient) DescribeEventsForOrganizationPagesWithContext(ctx aws.Context, input *health.DescribeEventsForOrganizationInput, fn func(*health.DescribeEventsForOrganizationOutput, bool) bool, opts ...request.Option) error {
+	from := aws.TimeValue(input.Filter.LastUpdatedTime.From)
+	var page []*health.OrganizationEvent
+	for _, e := range m.Events {
+		if !aws.TimeValue(e.LastUpdatedTime).Before(from) {
+			page = append(page, &health.OrganizationEvent{Arn: e.Arn, LastUpdatedTime: e.LastUpdatedTime})
+		}
+	}
+	fn(&health.DescribeEventsForOrganizationOutput{Events: page}, true)
+	return nil
+}
+
+func (m *mockHealthClient) event(arn string) *health.Event {
+	for _, e := range m.Events {
+		if aws.StringValue(e.Arn) == arn {
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *mockHealthClient) DescribeEventDetailsWithContext(ctx aws.Context, input *health.DescribeEventDetailsInput, opts ...request.Option) (*health.DescribeEventDetailsOutput, error) {
+	if len(input.EventArns) > healthBatchSize {
+		return nil, awserr.New("ValidationException", "too many event ARNs", nil)
+	}
+	output := &health.DescribeEventDetailsOutput{}
+	for _, arn := range input.EventArns {
+		output.SuccessfulSet = append(output.SuccessfulSet, &health.EventDetails{
+			Event:            m.event(aws.StringValue(arn)),
+			EventDescription: &health.EventDescription{LatestDescription: aws.String("about " + aws.StringValue(arn))},
+		})
+	}
+	return output, nil
+}
+
+func (m *mockHealthClient) DescribeAffectedAccountsForOrganizationPagesWithContext(ctx aws.Context, input *health.DescribeAffectedAccountsForOrganizationInput, fn func(*health.DescribeAffectedAccountsForOrganizationOutput, bool) bool, opts ...request.Option) error {
+	fn(&health.DescribeAffectedAccountsForOrganizationOutput{
+		AffectedAccounts: aws.StringSlice(m.Accounts[aws.StringValue(input.EventArn)]),
+	}, true)
+	return nil
+}
+
+func (m *mockHealthClient) DescribeEventDetailsForOrganizationWithContext(ctx aws.Context, input *health.DescribeEventDetailsForOrganizationInput, opts ...request.Option) (*health.DescribeEventDetailsForOrganizationOutput, error) {
+	output := &health.DescribeEventDetailsForOrganizationOutput{}
+	for _, f := range input.OrganizationEventDetailFilters {
+		output.SuccessfulSet = append(output.SuccessfulSet, &health.OrganizationEventDetails{
+			AwsAccountId:     f.AwsAccountId,
+			Event:            m.event(aws.StringValue(f.EventArn)),
+			EventDescription: &health.EventDescription{LatestDescription: aws.String("about " + aws.StringValue(f.EventArn))},
+		})
+	}
+	return output, nil
+}
+
+func (m *mockHealthClient) DescribeAffectedEntitiesPagesWithContext(ctx aws.Context, input *health.DescribeAffectedEntitiesInput, fn func(*health.DescribeAffectedEntitiesOutput, bool) bool, opts ...request.Option) error {
+	arn := aws.StringValue(input.Filter.EventArns[0])
+	fn(&health.DescribeAffectedEntitiesOutput{Entities: []*health.AffectedEntity{
+		{EventArn: aws.String(arn), EntityValue: aws.String("i-" + arn)},
+	}}, true)
+	return nil
+}
+
+func (m *mockHealthClient) DescribeAffectedEntitiesForOrganizationPagesWithContext(ctx aws.Context, input *health.DescribeAffectedEntitiesForOrganizationInput, fn func(*health.DescribeAffectedEntitiesForOrganizationOutput, bool) bool, opts ...request.Option) error {
+	f := input.OrganizationEntityFilters[0]
+	fn(&health.DescribeAffectedEntitiesForOrganizationOutput{Entities: []*health.AffectedEntity{{
+		AwsAccountId: f.AwsAccountId,
+		EventArn:     f.EventArn,
+		EntityValue:  aws.String(fmt.Sprintf("i-%s-%s", aws.StringValue(f.EventArn), aws.StringValue(f.AwsAccountId))),
+	}}}, true)
+	return nil
+}
+
+func testHealthClient(since time.Time, count int) *mockHealthClient {
+	m := &mockHealthClient{Accounts: map[string][]string{}}
+	// Events are listed newest first, as the Health API returns them.
+	for i := count - 1; i >= 0; i-- {
+		m.Events = append(m.Events, &health.Event{
+			Arn:               aws.String(fmt.Sprint(i)),
+			EventTypeCategory: aws.String(CategoryIssue),
+			LastUpdatedTime:   aws.Time(since.Add(time.Duration(i) * time.Minute)),
+			Service:           aws.String("EC2"),
+			StatusCode:        aws.String(StatusOpen),
+		})
+	}
+	return m
+}
+
+func TestPollerAccount(t *testing.T) {
+	since := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	p := Poller{HealthClient: testHealthClient(since, 12)}
+
+	events, err := p.Poll(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The event updated exactly at since was sent by the last poll.
+	if len(events) != 11 {
+		t.Fatalf("Poll() returned %d events, want 11", len(events))
+	}
+	for i, h := range events {
+		arn := fmt.Sprint(i + 1)
+		if h.EventARN != arn {
+			t.Errorf("event %d ARN = %s, want %s", i, h.EventARN, arn)
+		}
+		if h.EnglishDescription() != "about "+arn {
+			t.Errorf("event %d description = %q", i, h.EnglishDescription())
+		}
+		if want := []string{"i-" + arn}; !reflect.DeepEqual(h.AffectedEntityValues(), want) {
+			t.Errorf("event %d entities = %v, want %v", i, h.AffectedEntityValues(), want)
+		}
+	}
+}
+
+func TestPollerOrganization(t *testing.T) {
+	since := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	m := testHealthClient(since, 3)
+	m.Accounts["1"] = []string{"111111111111", "222222222222"}
+	p := Poller{HealthClient: m, Organization: true}
+
+	events, err := p.Poll(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, h := range events {
+		have = append(have, fmt.Sprintf("%s %s %v", h.EventARN, h.AffectedAccount, h.AffectedEntityValues()))
+	}
+	want := []string{
+		"1 111111111111 [i-1-111111111111]",
+		"1 222222222222 [i-1-222222222222]",
+		"2  [i-2-]",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("Poll() = %v, want %v", have, want)
+	}
+}
+
+type mockSSMClient struct {
+	ssmiface.SSMAPI
+	Parameters map[string]string
+}
+
+func (m *mockSSMClient) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	value, ok := m.Parameters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(value)}}, nil
+}
+
+func (m *mockSSMClient) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	m.Parameters[aws.StringValue(input.Name)] = aws.StringValue(input.Value)
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func TestResumeCheckpoint(t *testing.T) {
+	since := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int, account string) *Event {
+		return &Event{
+			AffectedAccount: account,
+			LastUpdatedTime: Time{since.Add(time.Duration(minutes) * time.Minute)},
+		}
+	}
+	// Two accounts' copies of one organization event share a timestamp.
+	events := []*Event{at(1, ""), at(2, "111111111111"), at(2, "222222222222"), at(3, "")}
+
+	tests := []struct {
+		sent int
+		want time.Time
+	}{
+		{0, since},
+		{1, since.Add(time.Minute)},
+		{2, since.Add(time.Minute)},
+		{3, since.Add(2 * time.Minute)},
+		{4, since.Add(3 * time.Minute)},
+	}
+	for _, tt := range tests {
+		if have := ResumeCheckpoint(events, tt.sent, since); !have.Equal(tt.want) {
+			t.Errorf("ResumeCheckpoint() after sending %d = %v, want %v", tt.sent, have, tt.want)
+		}
+	}
+}
+
+func TestCheckpointStores(t *testing.T) {
+	stores := map[string]CheckpointStore{
+		"file": &FileCheckpointStore{Path: filepath.Join(t.TempDir(), "checkpoint")},
+		"ssm": &SSMCheckpointStore{
+			SSMClient: &mockSSMClient{Parameters: map[string]string{}},
+			Name:      "/aws-health-notifier/checkpoint",
+		},
+	}
+	want := time.Date(2023, 8, 1, 12, 30, 15, 500, time.UTC)
+
+	for name, store := range stores {
+		if _, ok, err := store.GetCheckpoint(); ok || err != nil {
+			t.Fatalf("%s: GetCheckpoint() before the first poll = %v, %v", name, ok, err)
+		}
+		if err := store.PutCheckpoint(want); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		have, ok, err := store.GetCheckpoint()
+		if err != nil || !ok || !have.Equal(want) {
+			t.Errorf("%s: GetCheckpoint() = %v, %v, %v, want %v", name, have, ok, err, want)
+		}
+	}
+
+	bad := FileCheckpointStore{Path: filepath.Join(t.TempDir(), "bad")}
+	if err := os.WriteFile(bad.Path, []byte("yesterday"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := bad.GetCheckpoint(); err == nil {
+		t.Error("GetCheckpoint() of an unparseable file returned no error")
+	}
+}
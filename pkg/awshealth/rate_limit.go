@@ -0,0 +1,195 @@
+package awshealth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// RateWindow is a channel's notifications in the current rate limit window.
+type RateWindow struct {
+	Start time.Time
+	Count int
+	// Held are the digest lines of events held back in the window.
+	Held []string
+}
+
+// RateStore keeps each channel's rate limit window.
+type RateStore interface {
+	// GetWindow returns the channel's window, or the zero window if it
+	// has none.
+	GetWindow(channel string) (RateWindow, error)
+	PutWindow(channel string, window RateWindow) error
+}
+
+// RateLimiter sends at most Limit events to a channel per Window. Events
+// past the limit are held back and sent as one digest with the channel's
+// first event of a later window, or when the limiter is flushed.
+type RateLimiter struct {
+	Limit  int
+	Window time.Duration
+	Store  RateStore
+}
+
+// Allow records an event for channel and reports whether it may be sent.
+// It also returns the lines of events held back in the channel's previous
+// window, which should be sent as a digest first.
+func (r *RateLimiter) Allow(channel string, h *Event, now time.Time) (bool, []string, error) {
+	window, err := r.Store.GetWindow(channel)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var digest []string
+	if window.Start.IsZero() || !now.Before(window.Start.Add(r.Window)) {
+		digest = window.Held
+		window = RateWindow{Start: now}
+	}
+	allowed := window.Count < r.Limit
+	if allowed {
+		window.Count++
+	} else {
+		window.Held = append(window.Held, h.DigestLine())
+	}
+	if err := r.Store.PutWindow(channel, window); err != nil {
+		return false, nil, err
+	}
+	return allowed, digest, nil
+}
+
+// Flush returns and clears the lines of events held back for channel, for
+// callers that will not see another event, such as a replay.
+func (r *RateLimiter) Flush(channel string) ([]string, error) {
+	window, err := r.Store.GetWindow(channel)
+	if err != nil || len(window.Held) == 0 {
+		return nil, err
+	}
+	return r.clearHeld(channel, window)
+}
+
+// FlushExpired is Flush for a channel whose window has ended by now. It
+// lets a Lambda send the digest of a channel that may not see another event.
+func (r *RateLimiter) FlushExpired(channel string, now time.Time) ([]string, error) {
+	window, err := r.Store.GetWindow(channel)
+	if err != nil || len(window.Held) == 0 || now.Before(window.Start.Add(r.Window)) {
+		return nil, err
+	}
+	return r.clearHeld(channel, window)
+}
+
+func (r *RateLimiter) clearHeld(channel string, window RateWindow) ([]string, error) {
+	held := window.Held
+	window.Held = nil
+	return held, r.Store.PutWindow(channel, window)
+}
+
+// DigestLine describes an event in a digest.
+func (h *Event) DigestLine() string {
+	return fmt.Sprintf("%s in %s: %s", h.summary(), h.EventRegion, h.HealthEventURL())
+}
+
+// DigestText is the text of the digest of held back events.
+func DigestText(lines []string) string {
+	return fmt.Sprintf("%d more AWS Health events were held back by the rate limit:\n%s",
+		len(lines), strings.Join(lines, "\n"))
+}
+
+// MemoryRateStore keeps rate limit windows in memory. It only limits
+// events seen by the same process, such as a warm Lambda.
+type MemoryRateStore struct {
+	mu      sync.Mutex
+	windows map[string]RateWindow
+}
+
+// GetWindow implements RateStore.
+func (m *MemoryRateStore) GetWindow(channel string) (RateWindow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.windows[channel], nil
+}
+
+// PutWindow implements RateStore.
+func (m *MemoryRateStore) PutWindow(channel string, window RateWindow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.windows == nil {
+		m.windows = map[string]RateWindow{}
+	}
+	m.windows[channel] = window
+	return nil
+}
+
+// DynamoDBRateStore keeps rate limit windows in the same table as
+// DynamoDBDedupeStore, under the key "rate:" followed by the channel.
+// Concurrent invocations may each send an event past the limit.
+type DynamoDBRateStore struct {
+	DynamoDBClient dynamodbiface.DynamoDBAPI
+	TableName      string
+}
+
+// GetWindow implements RateStore.
+func (d *DynamoDBRateStore) GetWindow(channel string) (RateWindow, error) {
+	output, err := d.DynamoDBClient.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(d.TableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Key": {S: aws.String(rateKey(channel))},
+		},
+	})
+	if err != nil {
+		return RateWindow{}, fmt.Errorf("failed to get rate limit window for %s: %w", channel, err)
+	}
+
+	var window RateWindow
+	if start, ok := attributeInt(output.Item, "Start"); ok {
+		window.Start = time.Unix(start, 0)
+	}
+	count, _ := attributeInt(output.Item, "Count")
+	window.Count = int(count)
+	if held, ok := output.Item["Held"]; ok {
+		for _, v := range held.L {
+			window.Held = append(window.Held, aws.StringValue(v.S))
+		}
+	}
+	return window, nil
+}
+
+// PutWindow implements RateStore.
+func (d *DynamoDBRateStore) PutWindow(channel string, window RateWindow) error {
+	held := []*dynamodb.AttributeValue{}
+	for _, line := range window.Held {
+		held = append(held, &dynamodb.AttributeValue{S: aws.String(line)})
+	}
+	_, err := d.DynamoDBClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(d.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"Key":   {S: aws.String(rateKey(channel))},
+			"Start": {N: aws.String(unixString(window.Start))},
+			"Count": {N: aws.String(strconv.Itoa(window.Count))},
+			"Held":  {L: held},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put rate limit window for %s: %w", channel, err)
+	}
+	return nil
+}
+
+func rateKey(channel string) string {
+	return "rate:" + channel
+}
+
+func attributeInt(item map[string]*dynamodb.AttributeValue, name string) (int64, bool) {
+	v, ok := item[name]
+	if !ok || v.N == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+	return n, err == nil
+}
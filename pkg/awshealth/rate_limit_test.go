@@ -0,0 +1,97 @@
+package awshealth
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestRateLimiter(t *testing.T) {
+	stores := map[string]RateStore{
+		"memory": &MemoryRateStore{},
+		"dynamodb": &DynamoDBRateStore{
+			DynamoDBClient: &mockStateTable{Items: map[string]map[string]*dynamodb.AttributeValue{}},
+			TableName:      "health-state",
+		},
+	}
+	start := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	event := func(n int) *Event {
+		return &Event{
+			EventARN:      fmt.Sprintf("arn:aws:health:us-east-1::event/EC2/%d", n),
+			EventRegion:   "us-east-1",
+			EventTypeCode: "AWS_EC2_OPERATIONAL_ISSUE",
+			Service:       "EC2",
+			StatusCode:    StatusOpen,
+		}
+	}
+
+	for name, store := range stores {
+		r := RateLimiter{Limit: 2, Window: 5 * time.Minute, Store: store}
+		var allowed []bool
+		for i := 0; i < 4; i++ {
+			ok, digest, err := r.Allow("#ops", event(i), start.Add(time.Duration(i)*time.Second))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(digest) != 0 {
+				t.Errorf("%s: digest in the first window = %v", name, digest)
+			}
+			allowed = append(allowed, ok)
+		}
+		if want := []bool{true, true, false, false}; !reflect.DeepEqual(allowed, want) {
+			t.Errorf("%s: allowed = %v, want %v", name, allowed, want)
+		}
+
+		// Other channels have their own windows.
+		if ok, _, _ := r.Allow("#other", event(9), start); !ok {
+			t.Errorf("%s: first event to another channel was held", name)
+		}
+
+		ok, digest, err := r.Allow("#ops", event(4), start.Add(10*time.Minute))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := []string{event(2).DigestLine(), event(3).DigestLine()}
+		if !ok || !reflect.DeepEqual(digest, want) {
+			t.Errorf("%s: next window Allow() = %v, %v, want true, %v", name, ok, digest, want)
+		}
+
+		r.Allow("#ops", event(5), start.Add(10*time.Minute))
+		r.Allow("#ops", event(6), start.Add(10*time.Minute))
+		held, err := r.Flush("#ops")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := []string{event(6).DigestLine()}; !reflect.DeepEqual(held, want) {
+			t.Errorf("%s: Flush() = %v, want %v", name, held, want)
+		}
+		if held, _ := r.Flush("#ops"); len(held) != 0 {
+			t.Errorf("%s: second Flush() = %v, want none", name, held)
+		}
+	}
+}
+
+func TestRateLimiterFlushExpired(t *testing.T) {
+	start := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	r := RateLimiter{Limit: 1, Window: 5 * time.Minute, Store: &MemoryRateStore{}}
+	event := &Event{EventARN: "arn:aws:health:us-east-1::event/EC2/1", Service: "EC2", StatusCode: StatusOpen}
+	r.Allow("#ops", event, start)
+	r.Allow("#ops", event, start)
+
+	if held, err := r.FlushExpired("#ops", start.Add(time.Minute)); len(held) != 0 || err != nil {
+		t.Errorf("FlushExpired() inside the window = %v, %v, want nothing", held, err)
+	}
+	held, err := r.FlushExpired("#ops", start.Add(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{event.DigestLine()}; !reflect.DeepEqual(held, want) {
+		t.Errorf("FlushExpired() after the window = %v, want %v", held, want)
+	}
+	if held, _ := r.FlushExpired("#ops", start.Add(5*time.Minute)); len(held) != 0 {
+		t.Errorf("second FlushExpired() = %v, want none", held)
+	}
+}
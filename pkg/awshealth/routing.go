@@ -0,0 +1,174 @@
+package awshealth
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+// Slack colors for each event category.
+const (
+	ColorIssue               = "danger"
+	ColorScheduledChange     = "warning"
+	ColorAccountNotification = "#439FE0"
+)
+
+// CategoryColor returns the attachment color for an event category: red for
+// issues, yellow for scheduled changes and blue for account notifications.
+// Unknown categories are red.
+func CategoryColor(category string) string {
+	switch category {
+	case CategoryScheduledChange:
+		return ColorScheduledChange
+	case CategoryAccountNotification:
+		return ColorAccountNotification
+	}
+	return ColorIssue
+}
+
+// Match selects events. Each list holds path.Match patterns such as
+// "AWS_EC2_*"; an empty list matches anything, and an event must match every
+// non-empty list.
+type Match struct {
+	Categories     []string `json:"category"`
+	EventTypeCodes []string `json:"eventTypeCode"`
+	Regions        []string `json:"region"`
+	Services       []string `json:"service"`
+}
+
+// Matches reports whether the event matches.
+func (m *Match) Matches(h *Event) bool {
+	return matchAny(m.Categories, h.EventTypeCategory) &&
+		matchAny(m.EventTypeCodes, h.EventTypeCode) &&
+		matchAny(m.Regions, h.EventRegion) &&
+		matchAny(m.Services, h.Service)
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule routes the events it matches. Empty Channel and Color fall back to
+// the default rule, then to the notifier's channel and the category color.
+type Rule struct {
+	Match    Match    `json:"match"`
+	Channel  string   `json:"channel"`
+	Color    string   `json:"color"`
+	Drop     bool     `json:"drop"`
+	Mentions []string `json:"mentions"`
+}
+
+// RoutingConfig is a list of rules; the first one matching an event wins.
+//
+//	{
+//	  "default": {"channel": "#aws-health"},
+//	  "rules": [
+//	    {"match": {"category": ["scheduledChange"]}, "channel": "#aws-maintenance"},
+//	    {"match": {"service": ["BILLING"]}, "drop": true},
+//	    {"match": {"category": ["issue"], "region": ["us-*"]}, "mentions": ["<!subteam^S0123>"]}
+//	  ]
+//	}
+type RoutingConfig struct {
+	Default Rule   `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Route is where and how to send one event.
+type Route struct {
+	Channel  string
+	Color    string
+	Drop     bool
+	Mentions []string
+}
+
+// ParseRoutingConfig parses a JSON routing config and checks its patterns.
+func ParseRoutingConfig(content []byte) (*RoutingConfig, error) {
+	var c RoutingConfig
+	if err := json.Unmarshal(content, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse routing config: %w", err)
+	}
+	for i, r := range c.Rules {
+		for _, patterns := range [][]string{r.Match.Categories, r.Match.EventTypeCodes, r.Match.Regions, r.Match.Services} {
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return nil, fmt.Errorf("rule %d has a bad pattern %q: %w", i, p, err)
+				}
+			}
+		}
+	}
+	return &c, nil
+}
+
+// Channels returns every channel the config can route an event to, in rule
+// order without duplicates. A nil config only routes to defaultChannel.
+func (c *RoutingConfig) Channels(defaultChannel string) []string {
+	if c == nil {
+		return []string{defaultChannel}
+	}
+	if c.Default.Channel != "" {
+		defaultChannel = c.Default.Channel
+	}
+
+	var channels []string
+	seen := map[string]bool{}
+	add := func(channel string) {
+		if channel == "" {
+			channel = defaultChannel
+		}
+		if !seen[channel] {
+			seen[channel] = true
+			channels = append(channels, channel)
+		}
+	}
+	for _, r := range c.Rules {
+		if !r.Drop {
+			add(r.Channel)
+		}
+	}
+	if !c.Default.Drop {
+		add(c.Default.Channel)
+	}
+	return channels
+}
+
+// Route returns the route for an event. A nil config sends every event to
+// defaultChannel in its category color.
+func (c *RoutingConfig) Route(h *Event, defaultChannel string) Route {
+	route := Route{
+		Channel: defaultChannel,
+		Color:   CategoryColor(h.EventTypeCategory),
+	}
+	if c == nil {
+		return route
+	}
+
+	rule := c.Default
+	for _, r := range c.Rules {
+		if r.Match.Matches(h) {
+			rule = r
+			break
+		}
+	}
+
+	if rule.Channel != "" {
+		route.Channel = rule.Channel
+	} else if c.Default.Channel != "" {
+		route.Channel = c.Default.Channel
+	}
+	if rule.Color != "" {
+		route.Color = rule.Color
+	} else if c.Default.Color != "" {
+		route.Color = c.Default.Color
+	}
+	route.Drop = rule.Drop
+	route.Mentions = rule.Mentions
+	return route
+}
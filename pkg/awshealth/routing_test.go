@@ -0,0 +1,135 @@
+package awshealth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+)
+
+const testRoutingConfig = `{
+  "default": {"channel": "#aws-health"},
+  "rules": [
+    {"match": {"service": ["BILLING"]}, "drop": true},
+    {"match": {"category": ["scheduledChange"]}, "channel": "#aws-maintenance"},
+    {"match": {"category": ["issue"], "eventTypeCode": ["AWS_EC2_*"], "region": ["us-*"]},
+     "channel": "#outages", "mentions": ["<!subteam^S0123>"]},
+    {"match": {"service": ["IAM"]}, "color": "#000000"}
+  ]
+}`
+
+func TestRoutingConfigRoute(t *testing.T) {
+	c, err := ParseRoutingConfig([]byte(testRoutingConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		event *Event
+		want  Route
+	}{
+		{
+			name:  "dropped",
+			event: readEvent(t, "account_notification.json"),
+			want:  Route{Channel: "#aws-health", Color: ColorAccountNotification, Drop: true},
+		},
+		{
+			name:  "scheduled change",
+			event: readEvent(t, "ebs_scheduled_change.json"),
+			want:  Route{Channel: "#aws-maintenance", Color: ColorScheduledChange},
+		},
+		{
+			name:  "ec2 issue with mentions",
+			event: readEvent(t, "ec2_issue.json"),
+			want:  Route{Channel: "#outages", Color: ColorIssue, Mentions: []string{"<!subteam^S0123>"}},
+		},
+		{
+			name:  "ec2 issue outside the matched regions",
+			event: &Event{Service: "EC2", EventTypeCategory: CategoryIssue, EventTypeCode: "AWS_EC2_X", EventRegion: "eu-west-1"},
+			want:  Route{Channel: "#aws-health", Color: ColorIssue},
+		},
+		{
+			name:  "color override keeps the default channel",
+			event: &Event{Service: "IAM", EventTypeCategory: CategoryAccountNotification},
+			want:  Route{Channel: "#aws-health", Color: "#000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if have := c.Route(tt.event, "#fallback"); !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("Route() = %+v, want %+v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutingConfigNil(t *testing.T) {
+	var c *RoutingConfig
+	have := c.Route(&Event{EventTypeCategory: CategoryScheduledChange}, "#fallback")
+	if want := (Route{Channel: "#fallback", Color: ColorScheduledChange}); !reflect.DeepEqual(have, want) {
+		t.Errorf("Route() = %+v, want %+v", have, want)
+	}
+}
+
+func TestRoutingConfigChannels(t *testing.T) {
+	c, err := ParseRoutingConfig([]byte(testRoutingConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"#aws-maintenance", "#outages", "#aws-health"}
+	if have := c.Channels("#ops"); !reflect.DeepEqual(have, want) {
+		t.Errorf("Channels() = %v, want %v", have, want)
+	}
+
+	var nilConfig *RoutingConfig
+	if have := nilConfig.Channels("#ops"); !reflect.DeepEqual(have, []string{"#ops"}) {
+		t.Errorf("nil config Channels() = %v, want [#ops]", have)
+	}
+}
+
+func TestParseRoutingConfigErrors(t *testing.T) {
+	if _, err := ParseRoutingConfig([]byte(`{"rules": [`)); err == nil {
+		t.Error("ParseRoutingConfig() of invalid json did not return an error")
+	}
+	if _, err := ParseRoutingConfig([]byte(`{"rules": [{"match": {"service": ["[EC2"]}}]}`)); err == nil {
+		t.Error("ParseRoutingConfig() with a bad pattern did not return an error")
+	}
+}
+
+func TestCategoryColor(t *testing.T) {
+	for category, want := range map[string]string{
+		CategoryIssue:               "danger",
+		CategoryScheduledChange:     "warning",
+		CategoryAccountNotification: "#439FE0",
+		"":                          "danger",
+	} {
+		if have := CategoryColor(category); have != want {
+			t.Errorf("CategoryColor(%q) = %q, want %q", category, have, want)
+		}
+	}
+}
+
+func TestNotifyMessage(t *testing.T) {
+	h := readEvent(t, "ec2_issue.json")
+	route := Route{Channel: "#outages", Color: ColorIssue, Mentions: []string{"<!subteam^S0123>"}}
+
+	m := NotifyMessage(NewMessageData(h, "us-west-2"), route)
+	if m.Severity != notify.SeverityCritical || m.DedupKey != h.EventARN {
+		t.Errorf("NotifyMessage() severity/dedup = %s/%s", m.Severity, m.DedupKey)
+	}
+	if m.Text != "A description of the event will be provided here" {
+		t.Errorf("NotifyMessage() text = %q", m.Text)
+	}
+	if !reflect.DeepEqual(m.Mentions, route.Mentions) {
+		t.Errorf("NotifyMessage() mentions = %v", m.Mentions)
+	}
+	last := m.Fields[len(m.Fields)-1]
+	if last.Title != "Affected Resources" || last.Value != "i-abcd1111, i-abcd2222" {
+		t.Errorf("NotifyMessage() last field = %+v", last)
+	}
+	if CategorySeverity(CategoryScheduledChange) != notify.SeverityWarning {
+		t.Errorf("scheduled change severity = %s", CategorySeverity(CategoryScheduledChange))
+	}
+}
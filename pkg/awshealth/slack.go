@@ -0,0 +1,67 @@
+package awshealth
+
+import (
+	"text/template"
+	"time"
+
+	"github.com/lytics/slackhook"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
+)
+
+// DefaultSlackTemplate is the notification attachment template used when no
+// other is configured.
+const DefaultSlackTemplate = `{
+  "title": "AWS Health Notification",
+  "title_link": {{ json .DashboardURL }},
+  "color": {{ json .Color }},
+  "fields": [
+    {"title": "Service", "value": {{ json .Event.Service }}},
+    {"title": "Description", "value": {{ json .LatestDescription }}},
+    {"title": "EventTypeCode", "value": {{ json .Event.EventTypeCode }}},
+    {"title": "Link", "value": {{ json .EventURL }}}
+  ]
+}`
+
+var defaultSlackTemplate = slacktemplate.Must("aws-health-notifier", DefaultSlackTemplate)
+
+// MessageData is what notification templates are executed with.
+type MessageData struct {
+	AffectedEntities []string
+	// Color is the attachment color, from the event category or a route.
+	Color        string
+	DashboardURL string
+	// Duration is how long the event has lasted, such as "2h 5m".
+	Duration          string
+	Event             *Event
+	EventURL          string
+	LatestDescription string
+	// Region is the region the notifier runs in.
+	Region string
+}
+
+// NewMessageData collects the template data for an event.
+func NewMessageData(h *Event, region string) MessageData {
+	description := h.EnglishDescription()
+	if description == "" {
+		description = "no description found in health check"
+	}
+	return MessageData{
+		AffectedEntities:  h.AffectedEntityValues(),
+		Color:             CategoryColor(h.EventTypeCategory),
+		DashboardURL:      PersonalHealthDashboardURL,
+		Duration:          h.ReadableDuration(time.Now()),
+		Event:             h,
+		EventURL:          h.HealthEventURL(),
+		LatestDescription: description,
+		Region:            region,
+	}
+}
+
+// SlackAttachment renders an event's data with tmpl, or
+// DefaultSlackTemplate when tmpl is nil.
+func SlackAttachment(tmpl *template.Template, data MessageData) (*slackhook.Attachment, error) {
+	if tmpl == nil {
+		tmpl = defaultSlackTemplate
+	}
+	return slacktemplate.Execute(tmpl, data)
+}
@@ -0,0 +1,49 @@
+package awshealth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lytics/slackhook"
+)
+
+func TestSlackAttachment(t *testing.T) {
+	h := &Event{
+		Description:   []EventDescription{{Language: "en_US", Latest: "EC2 is degraded"}},
+		EventARN:      "arn:aws:health:us-east-1::event/EC2/AWS_EC2_OPERATIONAL_ISSUE/1",
+		EventTypeCode: "AWS_EC2_OPERATIONAL_ISSUE",
+		Service:       "EC2",
+	}
+
+	attachment, err := SlackAttachment(nil, NewMessageData(h, "us-east-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &slackhook.Attachment{
+		Title:     "AWS Health Notification",
+		TitleLink: PersonalHealthDashboardURL,
+		Color:     "danger",
+		Fields: []slackhook.Field{
+			{Title: "Service", Value: "EC2"},
+			{Title: "Description", Value: "EC2 is degraded"},
+			{Title: "EventTypeCode", Value: "AWS_EC2_OPERATIONAL_ISSUE"},
+			{Title: "Link", Value: h.HealthEventURL()},
+		},
+	}
+	if !reflect.DeepEqual(attachment, want) {
+		t.Errorf("SlackAttachment() = %+v, want %+v", attachment, want)
+	}
+
+	h.Description = nil
+	h.EventTypeCategory = CategoryScheduledChange
+	attachment, err = SlackAttachment(nil, NewMessageData(h, "us-east-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.Fields[1].Value != "no description found in health check" {
+		t.Errorf("SlackAttachment() description = %q", attachment.Fields[1].Value)
+	}
+	if attachment.Color != ColorScheduledChange {
+		t.Errorf("SlackAttachment() color = %q, want %q", attachment.Color, ColorScheduledChange)
+	}
+}
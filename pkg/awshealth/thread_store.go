@@ -0,0 +1,100 @@
+package awshealth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Thread identifies the Slack message an event's updates are threaded under.
+type Thread struct {
+	Channel string
+	TS      string
+}
+
+// ThreadStore remembers the Slack thread of each event, by its ThreadKey.
+type ThreadStore interface {
+	// GetThread returns the event's thread, or ok false if it has none.
+	GetThread(key string) (thread Thread, ok bool, err error)
+	PutThread(key string, thread Thread) error
+}
+
+// MemoryThreadStore keeps threads in memory. It only threads updates seen by
+// the same process, such as a warm Lambda.
+type MemoryThreadStore struct {
+	mu      sync.Mutex
+	threads map[string]Thread
+}
+
+// GetThread implements ThreadStore.
+func (m *MemoryThreadStore) GetThread(key string) (Thread, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.threads[key]
+	return t, ok, nil
+}
+
+// PutThread implements ThreadStore.
+func (m *MemoryThreadStore) PutThread(key string, thread Thread) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.threads == nil {
+		m.threads = map[string]Thread{}
+	}
+	m.threads[key] = thread
+	return nil
+}
+
+// DynamoDBThreadStore keeps threads in a DynamoDB table whose partition key
+// is the string attribute EventARN, which holds the thread key.
+type DynamoDBThreadStore struct {
+	DynamoDBClient dynamodbiface.DynamoDBAPI
+	TableName      string
+}
+
+// GetThread implements ThreadStore.
+func (d *DynamoDBThreadStore) GetThread(key string) (Thread, bool, error) {
+	output, err := d.DynamoDBClient.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(d.TableName),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"EventARN": {S: aws.String(key)},
+		},
+	})
+	if err != nil {
+		return Thread{}, false, fmt.Errorf("failed to get thread for %s: %w", key, err)
+	}
+	if len(output.Item) == 0 {
+		return Thread{}, false, nil
+	}
+	return Thread{
+		Channel: attributeString(output.Item, "Channel"),
+		TS:      attributeString(output.Item, "TS"),
+	}, true, nil
+}
+
+// PutThread implements ThreadStore.
+func (d *DynamoDBThreadStore) PutThread(key string, thread Thread) error {
+	_, err := d.DynamoDBClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(d.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"EventARN": {S: aws.String(key)},
+			"Channel":  {S: aws.String(thread.Channel)},
+			"TS":       {S: aws.String(thread.TS)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put thread for %s: %w", key, err)
+	}
+	return nil
+}
+
+func attributeString(item map[string]*dynamodb.AttributeValue, name string) string {
+	if v, ok := item[name]; ok {
+		return aws.StringValue(v.S)
+	}
+	return ""
+}
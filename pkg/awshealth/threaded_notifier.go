@@ -0,0 +1,167 @@
+package awshealth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/trussworks/truss-aws-tools/pkg/slackweb"
+	"go.uber.org/zap"
+)
+
+// ThreadedNotifier posts each event once as a Block Kit message and threads
+// later updates for the same event under it, editing the original message
+// so it always shows the latest status.
+type ThreadedNotifier struct {
+	Channel string
+	Emoji   string
+	Logger  *zap.Logger
+	// Mentions, such as <!subteam^S0123> or <@U0123>, are added to the
+	// first post of an event.
+	Mentions []string
+	Slack    *slackweb.Client
+	Store    ThreadStore
+}
+
+// Slack rejects section text and fields longer than these many characters.
+const (
+	maxSectionText = 3000
+	maxFieldText   = 2000
+)
+
+// truncate shortens s to at most max characters, ending it with an ellipsis
+// and a link to the full event when it is cut.
+func truncate(s string, max int, eventURL string) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	more := []rune(fmt.Sprintf("… <%s|Read more in the console>", eventURL))
+	if len(more) >= max {
+		return string(runes[:max-1]) + "…"
+	}
+	return string(runes[:max-len(more)]) + string(more)
+}
+
+// Blocks returns the Block Kit layout for an event. Long text is truncated
+// to Slack's limits with a link to the event in the console.
+func (h *Event) Blocks() []slackweb.Block {
+	data := NewMessageData(h, "")
+	status := h.StatusCode
+	if status == "" {
+		status = "unknown"
+	}
+	if !h.StartTime.IsZero() {
+		if h.IsOpen() {
+			status += fmt.Sprintf(" for %s", data.Duration)
+		} else if !h.EndTime.IsZero() {
+			status += fmt.Sprintf(" after %s", data.Duration)
+		}
+	}
+	region := h.EventRegion
+	if region == "" {
+		region = "global"
+	}
+
+	field := func(s string) string { return truncate(s, maxFieldText, data.EventURL) }
+	section := func(s string) slackweb.Block {
+		return slackweb.Section(truncate(s, maxSectionText, data.EventURL))
+	}
+
+	blocks := []slackweb.Block{
+		slackweb.Header("AWS Health Notification"),
+		slackweb.Fields(
+			field(fmt.Sprintf("*Service*\n%s", h.Service)),
+			field(fmt.Sprintf("*Status*\n%s", status)),
+			field(fmt.Sprintf("*EventTypeCode*\n%s", h.EventTypeCode)),
+			field(fmt.Sprintf("*Region*\n%s", region)),
+		),
+		section(data.LatestDescription),
+	}
+	if len(data.AffectedEntities) > 0 {
+		blocks = append(blocks, section(
+			fmt.Sprintf("*Affected resources*\n%s", strings.Join(data.AffectedEntities, ", "))))
+	}
+	return append(blocks,
+		slackweb.Context(fmt.Sprintf("<%s|View in the Personal Health Dashboard>", data.EventURL)))
+}
+
+// summary is the notification fallback text.
+func (h *Event) summary() string {
+	return fmt.Sprintf("AWS Health: %s %s (%s)", h.Service, h.EventTypeCode, h.StatusCode)
+}
+
+// Message is the message that starts an event's thread, with the mentions
+// under its header.
+func (n *ThreadedNotifier) Message(h *Event) *slackweb.Message {
+	blocks := h.Blocks()
+	if len(n.Mentions) > 0 {
+		blocks = append(blocks[:1:1], append([]slackweb.Block{
+			slackweb.Section(strings.Join(n.Mentions, " ")),
+		}, blocks[1:]...)...)
+	}
+	return &slackweb.Message{
+		Channel:   n.Channel,
+		Text:      h.summary(),
+		Blocks:    blocks,
+		IconEmoji: n.Emoji,
+	}
+}
+
+// ThreadKey identifies the thread an event and its updates are posted in.
+// The copies of an organization event for each affected account have their
+// own threads, as they have their own dedupe keys.
+func (h *Event) ThreadKey() string {
+	if h.AffectedAccount != "" {
+		return h.EventARN + "|" + h.AffectedAccount
+	}
+	return h.EventARN
+}
+
+// Notify posts a new event, or replies to and updates the thread of an
+// event that was already posted.
+func (n *ThreadedNotifier) Notify(ctx context.Context, h *Event) error {
+	thread, ok, err := n.Store.GetThread(h.ThreadKey())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		resp, err := n.Slack.PostMessage(ctx, n.Message(h))
+		if err != nil {
+			return err
+		}
+		n.Logger.Info("posted health event",
+			zap.String("event-arn", h.EventARN),
+			zap.String("slack-channel", resp.Channel),
+			zap.String("slack-ts", resp.TS),
+		)
+		return n.Store.PutThread(h.ThreadKey(), Thread{Channel: resp.Channel, TS: resp.TS})
+	}
+
+	_, err = n.Slack.UpdateMessage(ctx, &slackweb.Message{
+		Channel: thread.Channel,
+		Text:    h.summary(),
+		Blocks:  h.Blocks(),
+		TS:      thread.TS,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = n.Slack.PostMessage(ctx, &slackweb.Message{
+		Channel:   thread.Channel,
+		Text:      fmt.Sprintf("Status is now *%s*: %s", h.StatusCode, NewMessageData(h, "").LatestDescription),
+		IconEmoji: n.Emoji,
+		ThreadTS:  thread.TS,
+	})
+	if err != nil {
+		return err
+	}
+	n.Logger.Info("updated health event thread",
+		zap.String("event-arn", h.EventARN),
+		zap.String("slack-channel", thread.Channel),
+		zap.String("slack-ts", thread.TS),
+	)
+	return nil
+}
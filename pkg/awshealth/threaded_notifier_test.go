@@ -0,0 +1,135 @@
+package awshealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/trussworks/truss-aws-tools/pkg/slackweb"
+	"go.uber.org/zap"
+)
+
+var logger, _ = zap.NewProduction()
+
+func TestThreadedNotifier(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackweb.Message
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		calls = append(calls, fmt.Sprintf("%s channel=%s ts=%s thread=%s", r.URL.Path, m.Channel, m.TS, m.ThreadTS))
+		fmt.Fprintf(w, `{"ok": true, "channel": "C123", "ts": "%d.0"}`, len(calls))
+	}))
+	defer server.Close()
+
+	n := ThreadedNotifier{
+		Channel: "#ops",
+		Logger:  logger,
+		Slack:   &slackweb.Client{BaseURL: server.URL + "/", Token: "xoxb-test"},
+		Store:   &MemoryThreadStore{},
+	}
+	h := &Event{EventARN: "arn:aws:health:us-east-1::event/EC2/1", Service: "EC2", StatusCode: "open"}
+	for _, status := range []string{"open", "upcoming", "closed"} {
+		h.StatusCode = status
+		if err := n.Notify(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"/chat.postMessage channel=#ops ts= thread=",
+		"/chat.update channel=C123 ts=1.0 thread=",
+		"/chat.postMessage channel=C123 ts= thread=1.0",
+		"/chat.update channel=C123 ts=1.0 thread=",
+		"/chat.postMessage channel=C123 ts= thread=1.0",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("slack calls = %v, want %v", calls, want)
+	}
+}
+
+func TestThreadKey(t *testing.T) {
+	h := &Event{EventARN: "arn:aws:health:us-east-1::event/EC2/1"}
+	if have := h.ThreadKey(); have != h.EventARN {
+		t.Errorf("ThreadKey() = %q, want the event ARN", have)
+	}
+	a := &Event{EventARN: h.EventARN, AffectedAccount: "111111111111"}
+	b := &Event{EventARN: h.EventARN, AffectedAccount: "222222222222"}
+	if a.ThreadKey() == b.ThreadKey() {
+		t.Error("ThreadKey() is the same for two accounts' copies of an organization event")
+	}
+}
+
+func TestBlocksTruncatesLongText(t *testing.T) {
+	h := &Event{
+		EventARN:    "arn:aws:health:us-east-1::event/EC2/1",
+		Service:     "EC2",
+		StatusCode:  "open",
+		Description: []EventDescription{{Language: "en_US", Latest: strings.Repeat("é", 5000)}},
+	}
+	for i := 0; i < 500; i++ {
+		h.AffectedEntities = append(h.AffectedEntities, AffectedEntity{EntityValue: fmt.Sprintf("i-%017d", i)})
+	}
+	h.EventTypeCode = strings.Repeat("X", 2500)
+
+	blocks := h.Blocks()
+	for _, f := range blocks[1].Fields {
+		if n := utf8.RuneCountInString(f.Text); n > maxFieldText {
+			t.Errorf("field has %d characters, want at most %d", n, maxFieldText)
+		}
+	}
+	for _, b := range blocks[2:4] {
+		if n := utf8.RuneCountInString(b.Text.Text); n > maxSectionText {
+			t.Errorf("section has %d characters, want at most %d", n, maxSectionText)
+		}
+		if !strings.HasSuffix(b.Text.Text, "… <"+h.HealthEventURL()+"|Read more in the console>") {
+			t.Errorf("truncated section does not end with an ellipsis and console link: %q", b.Text.Text[len(b.Text.Text)-100:])
+		}
+	}
+	if blocks[1].Fields[0].Text != "*Service*\nEC2" {
+		t.Errorf("short field = %q, want it unchanged", blocks[1].Fields[0].Text)
+	}
+}
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	Items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.Items[aws.StringValue(input.Key["EventARN"].S)]}, nil
+}
+
+func (m *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.Items[aws.StringValue(input.Item["EventARN"].S)] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDynamoDBThreadStore(t *testing.T) {
+	d := DynamoDBThreadStore{
+		DynamoDBClient: &mockDynamoDBClient{Items: map[string]map[string]*dynamodb.AttributeValue{}},
+		TableName:      "health-threads",
+	}
+
+	if _, ok, err := d.GetThread("arn:1"); ok || err != nil {
+		t.Fatalf("GetThread() of a new event = %v, %v", ok, err)
+	}
+	want := Thread{Channel: "C123", TS: "1.0"}
+	if err := d.PutThread("arn:1", want); err != nil {
+		t.Fatal(err)
+	}
+	have, ok, err := d.GetThread("arn:1")
+	if err != nil || !ok || have != want {
+		t.Errorf("GetThread() = %+v, %v, %v, want %+v", have, ok, err, want)
+	}
+}
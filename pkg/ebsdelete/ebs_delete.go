@@ -0,0 +1,296 @@
+// Package ebsdelete snapshots EBS volumes and then deletes them, skipping
+// volumes that are attached or belong to CloudFormation stacks.
+package ebsdelete
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"go.uber.org/zap"
+)
+
+// DefaultConcurrency is how many volumes are snapshotted and deleted at once
+// when no concurrency is set.
+const DefaultConcurrency = 4
+
+// stackNameTag is the tag CloudFormation puts on the resources it creates.
+const stackNameTag = "aws:cloudformation:stack-name"
+
+// What happened to a volume.
+const (
+	StatusDeleted = "deleted"
+	StatusDryRun  = "dry-run"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// Selector picks the volumes to delete. Every set field has to match.
+type Selector struct {
+	// VolumeIDs are the volumes to delete. Each one has to exist.
+	VolumeIDs []string
+	// AvailableDays only selects volumes that are available, meaning not
+	// attached to an instance, and were created more than this many days
+	// ago. EC2 doesn't record when a volume was detached, so the creation
+	// time is the closest there is. It is ignored when 0.
+	AvailableDays int
+	// TagKey only selects volumes with this tag.
+	TagKey string
+	// TagValue is the value TagKey has to have. Any value matches when it
+	// is empty.
+	TagValue string
+}
+
+// Empty reports whether the selector would select every volume.
+func (s Selector) Empty() bool {
+	return len(s.VolumeIDs) == 0 && s.AvailableDays == 0 && s.TagKey == ""
+}
+
+// filters are the DescribeVolumes filters for the selector.
+func (s Selector) filters() []*ec2.Filter {
+	var filters []*ec2.Filter
+	if len(s.VolumeIDs) > 0 {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("volume-id"),
+			Values: aws.StringSlice(s.VolumeIDs),
+		})
+	}
+	if s.AvailableDays > 0 {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("status"),
+			Values: aws.StringSlice([]string{ec2.VolumeStateAvailable}),
+		})
+	}
+	if s.TagKey != "" && s.TagValue != "" {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("tag:" + s.TagKey),
+			Values: aws.StringSlice([]string{s.TagValue}),
+		})
+	} else if s.TagKey != "" {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("tag-key"),
+			Values: aws.StringSlice([]string{s.TagKey}),
+		})
+	}
+	return filters
+}
+
+// Result is what happened to a volume.
+type Result struct {
+	VolumeID   string `json:"volume_id"`
+	SnapshotID string `json:"snapshot_id,omitempty"`
+	Status     string `json:"status"`
+	// Reason is why a volume was skipped or failed.
+	Reason string `json:"reason,omitempty"`
+	Err    error  `json:"-"`
+}
+
+// EBSDelete snapshots EBS volumes, copying their tags to the snapshots, and
+// deletes the volumes once the snapshots have completed.
+type EBSDelete struct {
+	// Concurrency is how many volumes are handled at once, or
+	// DefaultConcurrency when it is 0.
+	Concurrency int
+	DryRun      bool
+	EC2Client   ec2iface.EC2API
+	// Force deletes volumes that belong to CloudFormation stacks too.
+	Force  bool
+	Logger *zap.Logger
+}
+
+// FindVolumes returns the volumes the selector picks. It fails if any of the
+// selector's volume IDs doesn't exist or doesn't match the other filters.
+func (e *EBSDelete) FindVolumes(ctx context.Context, s Selector, now time.Time) ([]*ec2.Volume, error) {
+	if s.Empty() {
+		return nil, fmt.Errorf("refusing to select every volume, set volume IDs, available days or a tag")
+	}
+
+	var volumes []*ec2.Volume
+	cutoff := now.AddDate(0, 0, -s.AvailableDays)
+	err := e.EC2Client.DescribeVolumesPagesWithContext(ctx, &ec2.DescribeVolumesInput{
+		Filters: s.filters(),
+	}, func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+		for _, v := range page.Volumes {
+			if s.AvailableDays > 0 && !aws.TimeValue(v.CreateTime).Before(cutoff) {
+				continue
+			}
+			volumes = append(volumes, v)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe volumes: %w", err)
+	}
+
+	found := map[string]bool{}
+	for _, v := range volumes {
+		found[aws.StringValue(v.VolumeId)] = true
+	}
+	for _, id := range s.VolumeIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("no volume found with volume ID %s", id)
+		}
+	}
+	return volumes, nil
+}
+
+// Delete snapshots and deletes the volumes, Concurrency at a time, and
+// returns what happened to each in the order given.
+func (e *EBSDelete) Delete(ctx context.Context, volumes []*ec2.Volume) []Result {
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make([]Result, len(volumes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, v := range volumes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, v *ec2.Volume) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = e.deleteVolume(ctx, v)
+		}(i, v)
+	}
+	wg.Wait()
+	return results
+}
+
+// deleteVolume snapshots and deletes a single volume.
+func (e *EBSDelete) deleteVolume(ctx context.Context, volume *ec2.Volume) Result {
+	r := Result{VolumeID: aws.StringValue(volume.VolumeId)}
+	logger := e.Logger.With(zap.String("volume-id", r.VolumeID))
+
+	if cloudFormed, stackName := isCloudFormed(volume); cloudFormed && !e.Force {
+		r.Status = StatusSkipped
+		r.Reason = "part of CloudFormation stack " + stackName
+		logger.Info("skipping volume", zap.String("reason", r.Reason))
+		return r
+	}
+	// Only available volumes can be deleted, so don't snapshot the rest.
+	if state := aws.StringValue(volume.State); state != ec2.VolumeStateAvailable {
+		r.Status = StatusSkipped
+		r.Reason = fmt.Sprintf("volume is %s, not %s", state, ec2.VolumeStateAvailable)
+		logger.Info("skipping volume", zap.String("reason", r.Reason))
+		return r
+	}
+	if e.DryRun {
+		r.Status = StatusDryRun
+		logger.Info("would snapshot and delete volume")
+		return r
+	}
+
+	logger.Info("creating snapshot")
+	snapshot, err := e.createSnapshotAndWaitUntilCompleted(ctx, volume)
+	if snapshot != nil {
+		r.SnapshotID = aws.StringValue(snapshot.SnapshotId)
+	}
+	if err != nil {
+		return failed(r, logger, fmt.Errorf("failed to snapshot volume: %w", err))
+	}
+
+	logger.Info("deleting volume", zap.String("snapshot-id", r.SnapshotID))
+	_, err = e.EC2Client.DeleteVolumeWithContext(ctx, &ec2.DeleteVolumeInput{
+		VolumeId: volume.VolumeId,
+	})
+	if err != nil {
+		return failed(r, logger, fmt.Errorf("failed to delete volume: %w", err))
+	}
+	r.Status = StatusDeleted
+	return r
+}
+
+func failed(r Result, logger *zap.Logger, err error) Result {
+	logger.Error("failed to delete volume", zap.Error(err))
+	r.Status = StatusFailed
+	r.Reason = err.Error()
+	r.Err = err
+	return r
+}
+
+// createSnapshotAndWaitUntilCompleted takes a snapshot of an EBS volume,
+// tagged like the volume, and returns when the snapshot has completed.
+func (e *EBSDelete) createSnapshotAndWaitUntilCompleted(ctx context.Context, volume *ec2.Volume) (*ec2.Snapshot, error) {
+	input := &ec2.CreateSnapshotInput{
+		Description: volume.VolumeId,
+		VolumeId:    volume.VolumeId,
+	}
+	if len(volume.Tags) > 0 {
+		input.TagSpecifications = []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeSnapshot),
+			Tags:         copyTags(volume.Tags),
+		}}
+	}
+	snapshot, err := e.EC2Client.CreateSnapshotWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	err = e.EC2Client.WaitUntilSnapshotCompletedWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		SnapshotIds: []*string{snapshot.SnapshotId},
+	})
+	return snapshot, err
+}
+
+// Failed returns the results of the volumes that could not be deleted.
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Status == StatusFailed {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// WriteResults writes a table mapping each volume to its snapshot.
+func WriteResults(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "VOLUME\tSNAPSHOT\tSTATUS\tREASON")
+	for _, r := range results {
+		snapshotID := r.SnapshotID
+		if snapshotID == "" {
+			snapshotID = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.VolumeID, snapshotID, r.Status, r.Reason)
+	}
+	return tw.Flush()
+}
+
+// copyTags takes a slice of Tags, and copys then into a new slice,
+// pre-pending the AWS owned Keys with X-.
+func copyTags(tags []*ec2.Tag) []*ec2.Tag {
+	retval := make([]*ec2.Tag, len(tags))
+	for i, tag := range tags {
+		// AWS claims ownership of any tag that starts with aws:
+		// so you can't just copy them across. Prefix with X-
+		// so we can still find them.
+		if strings.HasPrefix(*tag.Key, "aws:") {
+			newTag := ec2.Tag{
+				Key:   aws.String("X-" + *tag.Key),
+				Value: tag.Value,
+			}
+			retval[i] = &newTag
+		} else {
+			retval[i] = tag
+		}
+	}
+	return retval
+}
+
+func isCloudFormed(volume *ec2.Volume) (ok bool, stackName string) {
+	for _, tag := range volume.Tags {
+		if *tag.Key == stackNameTag {
+			return true, *tag.Value
+		}
+	}
+	return false, ""
+}
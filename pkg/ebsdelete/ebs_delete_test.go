@@ -0,0 +1,247 @@
+package ebsdelete
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"go.uber.org/zap"
+)
+
+var logger, _ = zap.NewProduction()
+
+type mockEC2Client struct {
+	ec2iface.EC2API
+	Volumes []*ec2.Volume
+	Filters []*ec2.Filter
+	// FailDelete is a volume whose deletion fails.
+	FailDelete string
+
+	mu          sync.Mutex
+	Snapshotted map[string][]*ec2.Tag
+	Deleted     []string
+	running     int
+	MaxRunning  int
+}
+
+func (m *mockEC2Client) DescribeVolumesPagesWithContext(ctx aws.Context, input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool, opts ...request.Option) error {
+	m.Filters = input.Filters
+	fn(&ec2.DescribeVolumesOutput{Volumes: m.Volumes}, true)
+	return nil
+}
+
+func (m *mockEC2Client) CreateSnapshotWithContext(ctx aws.Context, input *ec2.CreateSnapshotInput, opts ...request.Option) (*ec2.Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running++
+	if m.running > m.MaxRunning {
+		m.MaxRunning = m.running
+	}
+	var tags []*ec2.Tag
+	for _, spec := range input.TagSpecifications {
+		tags = append(tags, spec.Tags...)
+	}
+	if m.Snapshotted == nil {
+		m.Snapshotted = map[string][]*ec2.Tag{}
+	}
+	m.Snapshotted[aws.StringValue(input.VolumeId)] = tags
+	return &ec2.Snapshot{SnapshotId: aws.String("snap-" + strings.TrimPrefix(aws.StringValue(input.VolumeId), "vol-"))}, nil
+}
+
+func (m *mockEC2Client) WaitUntilSnapshotCompletedWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.WaiterOption) error {
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func (m *mockEC2Client) DeleteVolumeWithContext(ctx aws.Context, input *ec2.DeleteVolumeInput, opts ...request.Option) (*ec2.DeleteVolumeOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running--
+	if aws.StringValue(input.VolumeId) == m.FailDelete {
+		return nil, errors.New("VolumeInUse")
+	}
+	m.Deleted = append(m.Deleted, aws.StringValue(input.VolumeId))
+	return &ec2.DeleteVolumeOutput{}, nil
+}
+
+func newCloudFormedVolume() *ec2.Volume {
+	v := &ec2.Volume{
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("aws:cloudformation:stack-name"),
+				Value: aws.String("TestStack"),
+			},
+		},
+	}
+	return v
+}
+
+func newVolume() *ec2.Volume {
+	v := &ec2.Volume{}
+	return v
+}
+
+func newTags() []*ec2.Tag {
+	return []*ec2.Tag{
+		{
+			Key:   aws.String("aws:cloudformation:stack-name"),
+			Value: aws.String("TestStack"),
+		},
+		{
+			Key:   aws.String("monkey"),
+			Value: aws.String("monkey-value"),
+		},
+	}
+}
+
+func expectedTags() []*ec2.Tag {
+	return []*ec2.Tag{
+		{
+			Key:   aws.String("X-aws:cloudformation:stack-name"),
+			Value: aws.String("TestStack"),
+		},
+		{
+			Key:   aws.String("monkey"),
+			Value: aws.String("monkey-value"),
+		},
+	}
+}
+
+func TestIsCloudformed(t *testing.T) {
+	want := true
+	wantStackName := "TestStack"
+	have, haveStackName := isCloudFormed(newCloudFormedVolume())
+	if have != want {
+		t.Fatalf("isCloudFormed(cloudFormedVolume) = %v, want = %v",
+			have, want)
+	}
+	if haveStackName != wantStackName {
+		t.Fatalf("have StackName: %v, want %v",
+			haveStackName,
+			wantStackName)
+	}
+
+	want = false
+	have, _ = isCloudFormed(newVolume())
+	if have != want {
+		t.Fatalf("isCloudFormed(volume) = %v, want %v", have, want)
+	}
+}
+
+func TestCopyTags(t *testing.T) {
+	have := copyTags(newTags())
+	want := expectedTags()
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+}
+
+func TestFindVolumes(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := &mockEC2Client{Volumes: []*ec2.Volume{
+		{VolumeId: aws.String("vol-old"), CreateTime: aws.Time(now.AddDate(0, 0, -40))},
+		{VolumeId: aws.String("vol-new"), CreateTime: aws.Time(now.AddDate(0, 0, -2))},
+	}}
+	e := EBSDelete{EC2Client: m, Logger: logger}
+
+	volumes, err := e.FindVolumes(context.Background(), Selector{AvailableDays: 30, TagKey: "team"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 1 || aws.StringValue(volumes[0].VolumeId) != "vol-old" {
+		t.Errorf("FindVolumes() = %v, want vol-old", volumes)
+	}
+	var filters []string
+	for _, f := range m.Filters {
+		filters = append(filters, aws.StringValue(f.Name)+"="+strings.Join(aws.StringValueSlice(f.Values), ","))
+	}
+	if want := []string{"status=available", "tag-key=team"}; !reflect.DeepEqual(filters, want) {
+		t.Errorf("filters = %v, want %v", filters, want)
+	}
+
+	if _, err := e.FindVolumes(context.Background(), Selector{VolumeIDs: []string{"vol-old", "vol-gone"}}, now); err == nil {
+		t.Error("FindVolumes() with a missing volume ID returned no error")
+	}
+	if _, err := e.FindVolumes(context.Background(), Selector{}, now); err == nil {
+		t.Error("FindVolumes() with an empty selector returned no error")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	m := &mockEC2Client{FailDelete: "vol-4"}
+	e := EBSDelete{Concurrency: 2, EC2Client: m, Logger: logger}
+	volumes := []*ec2.Volume{
+		{VolumeId: aws.String("vol-1"), Tags: newTags()[1:]},
+		newCloudFormedVolume(),
+		{VolumeId: aws.String("vol-3")},
+		{VolumeId: aws.String("vol-4")},
+		{VolumeId: aws.String("vol-5")},
+	}
+	volumes[1].VolumeId = aws.String("vol-2")
+	for _, v := range volumes {
+		v.State = aws.String(ec2.VolumeStateAvailable)
+	}
+	volumes = append(volumes, &ec2.Volume{VolumeId: aws.String("vol-6"), State: aws.String(ec2.VolumeStateInUse)})
+
+	results := e.Delete(context.Background(), volumes)
+	var have []string
+	for _, r := range results {
+		have = append(have, r.VolumeID+":"+r.SnapshotID+":"+r.Status)
+	}
+	want := []string{
+		"vol-1:snap-1:deleted",
+		"vol-2::skipped",
+		"vol-3:snap-3:deleted",
+		"vol-4:snap-4:failed",
+		"vol-5:snap-5:deleted",
+		"vol-6::skipped",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("Delete() = %v, want %v", have, want)
+	}
+	if m.MaxRunning > 2 {
+		t.Errorf("%d volumes handled at once, want at most 2", m.MaxRunning)
+	}
+	if !reflect.DeepEqual(m.Snapshotted["vol-1"], newTags()[1:]) {
+		t.Errorf("snapshot tags = %v, want %v", m.Snapshotted["vol-1"], newTags()[1:])
+	}
+	if _, ok := m.Snapshotted["vol-6"]; ok {
+		t.Error("in-use vol-6 was snapshotted")
+	}
+	if results[5].Reason != "volume is in-use, not available" {
+		t.Errorf("vol-6 skip reason = %q", results[5].Reason)
+	}
+	if failed := Failed(results); len(failed) != 1 || failed[0].Err == nil {
+		t.Errorf("Failed() = %+v, want vol-4", failed)
+	}
+
+	var b bytes.Buffer
+	if err := WriteResults(&b, results[:2]); err != nil {
+		t.Fatal(err)
+	}
+	wantTable := "VOLUME  SNAPSHOT  STATUS   REASON\nvol-1   snap-1    deleted  \nvol-2   -         skipped  part of CloudFormation stack TestStack\n"
+	if b.String() != wantTable {
+		t.Errorf("WriteResults() = %q, want %q", b.String(), wantTable)
+	}
+}
+
+func TestDeleteDryRun(t *testing.T) {
+	m := &mockEC2Client{}
+	e := EBSDelete{DryRun: true, EC2Client: m, Logger: logger}
+	results := e.Delete(context.Background(), []*ec2.Volume{{VolumeId: aws.String("vol-1"), State: aws.String(ec2.VolumeStateAvailable)}})
+	if len(results) != 1 || results[0].Status != StatusDryRun {
+		t.Errorf("Delete() = %+v, want a dry run", results)
+	}
+	if len(m.Snapshotted) > 0 || len(m.Deleted) > 0 {
+		t.Errorf("dry run snapshotted %v and deleted %v", m.Snapshotted, m.Deleted)
+	}
+}
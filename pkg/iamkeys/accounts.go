@@ -0,0 +1,153 @@
+package iamkeys
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+	"go.uber.org/zap"
+)
+
+// Account identifies an AWS account by ID and its IAM account alias.
+type Account struct {
+	ID    string `json:"id"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// Name returns the account alias, or the ID when the account has no alias.
+func (a Account) Name() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	return a.ID
+}
+
+// AccountReport holds the findings for a single account. Err is set when the
+// account could not be checked.
+type AccountReport struct {
+	Account  Account   `json:"account"`
+	Findings []Finding `json:"findings"`
+	Err      error     `json:"-"`
+	// Error is the text of Err, for structured output.
+	Error string `json:"error,omitempty"`
+}
+
+// ListOrganizationAccounts returns the IDs of the active accounts in the
+// caller's AWS Organization.
+func ListOrganizationAccounts(orgClient organizationsiface.OrganizationsAPI) ([]string, error) {
+	var ids []string
+	err := orgClient.ListAccountsPages(&organizations.ListAccountsInput{},
+		func(page *organizations.ListAccountsOutput, lastPage bool) bool {
+			for _, a := range page.Accounts {
+				if aws.StringValue(a.Status) == organizations.AccountStatusActive {
+					ids = append(ids, aws.StringValue(a.Id))
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization accounts: %w", err)
+	}
+	return ids, nil
+}
+
+// AccountAlias returns the IAM account alias, or "" if there is none.
+func AccountAlias(iamClient iamiface.IAMAPI) (string, error) {
+	output, err := iamClient.ListAccountAliases(&iam.ListAccountAliasesInput{})
+	if err != nil {
+		return "", err
+	}
+	if len(output.AccountAliases) == 0 {
+		return "", nil
+	}
+	return aws.StringValue(output.AccountAliases[0]), nil
+}
+
+// AssumeRoleIAMClients returns a function making IAM clients that assume
+// roleName in the given account.
+func AssumeRoleIAMClients(sess *session.Session, roleName string) func(accountID string) iamiface.IAMAPI {
+	return func(accountID string) iamiface.IAMAPI {
+		roleArn := fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, roleName)
+		return iam.New(sess, &aws.Config{Credentials: stscreds.NewCredentials(sess, roleArn)})
+	}
+}
+
+// MultiAccountCheck runs the credential checks in several accounts.
+type MultiAccountCheck struct {
+	AccountIDs []string
+	Checks     Checks
+	// IAMClient makes the IAM client used for an account, usually with
+	// AssumeRoleIAMClients.
+	IAMClient       func(accountID string) iamiface.IAMAPI
+	Logger          *zap.Logger
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	// Timeout limits how long to wait for each account's report.
+	Timeout time.Duration
+}
+
+// Check is CheckWithContext with a background context.
+func (m *MultiAccountCheck) Check() ([]AccountReport, error) {
+	return m.CheckWithContext(context.Background())
+}
+
+// CheckWithContext returns one report per account, in AccountIDs order. An
+// account that fails is logged and reported with Err set so the other
+// accounts are still checked.
+func (m *MultiAccountCheck) CheckWithContext(ctx context.Context) ([]AccountReport, error) {
+	if err := m.Checks.Validate(); err != nil {
+		return nil, err
+	}
+
+	var reports []AccountReport
+	for _, id := range m.AccountIDs {
+		report := m.checkAccount(ctx, id)
+		if report.Err != nil {
+			report.Error = report.Err.Error()
+			m.Logger.Error("failed to check account",
+				zap.String("account-id", id),
+				zap.Error(report.Err),
+			)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (m *MultiAccountCheck) checkAccount(ctx context.Context, id string) AccountReport {
+	report := AccountReport{Account: Account{ID: id}}
+	iamClient := m.IAMClient(id)
+
+	alias, err := AccountAlias(iamClient)
+	if err != nil {
+		report.Err = fmt.Errorf("failed to get account alias: %w", err)
+		return report
+	}
+	report.Account.Alias = alias
+
+	c := IAMKeysCheck{
+		Checks:          m.Checks,
+		IAMClient:       iamClient,
+		Logger:          m.Logger.With(zap.String("account-id", id), zap.String("account-alias", alias)),
+		PollInterval:    m.PollInterval,
+		MaxPollInterval: m.MaxPollInterval,
+		Timeout:         m.Timeout,
+	}
+	findings, err := c.CheckWithContext(ctx)
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	for i := range findings {
+		findings[i].Account = &report.Account
+	}
+	report.Findings = findings
+	return report
+}
@@ -0,0 +1,137 @@
+package iamkeys
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+)
+
+type mockOrganizationsClient struct {
+	organizationsiface.OrganizationsAPI
+}
+
+func (m *mockOrganizationsClient) ListAccountsPages(input *organizations.ListAccountsInput, fn func(*organizations.ListAccountsOutput, bool) bool) error {
+	fn(&organizations.ListAccountsOutput{Accounts: []*organizations.Account{
+		{Id: aws.String("111111111111"), Status: aws.String(organizations.AccountStatusActive)},
+		{Id: aws.String("222222222222"), Status: aws.String(organizations.AccountStatusSuspended)},
+	}}, false)
+	fn(&organizations.ListAccountsOutput{Accounts: []*organizations.Account{
+		{Id: aws.String("333333333333"), Status: aws.String(organizations.AccountStatusActive)},
+	}}, true)
+	return nil
+}
+
+// mockAccountIAMClient is a mockIAMClient with an account alias.
+type mockAccountIAMClient struct {
+	*mockIAMClient
+	Alias    string
+	AliasErr error
+}
+
+func (m *mockAccountIAMClient) ListAccountAliases(input *iam.ListAccountAliasesInput) (*iam.ListAccountAliasesOutput, error) {
+	if m.AliasErr != nil {
+		return nil, m.AliasErr
+	}
+	output := &iam.ListAccountAliasesOutput{}
+	if m.Alias != "" {
+		output.AccountAliases = []*string{aws.String(m.Alias)}
+	}
+	return output, nil
+}
+
+func TestListOrganizationAccounts(t *testing.T) {
+	ids, err := ListOrganizationAccounts(&mockOrganizationsClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"111111111111", "333333333333"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ListOrganizationAccounts() = %v, want %v", ids, want)
+	}
+}
+
+func TestMultiAccountCheck(t *testing.T) {
+	content := readFixture(t, "credential_report.csv")
+	clients := map[string]iamiface.IAMAPI{
+		"111111111111": &mockAccountIAMClient{mockIAMClient: &mockIAMClient{Content: content}, Alias: "prod"},
+		"222222222222": &mockAccountIAMClient{mockIAMClient: &mockIAMClient{}, AliasErr: errors.New("access denied")},
+		"333333333333": &mockAccountIAMClient{mockIAMClient: &mockIAMClient{Content: content}},
+	}
+	m := MultiAccountCheck{
+		AccountIDs: []string{"111111111111", "222222222222", "333333333333"},
+		Checks:     Checks{AccessKeyAge: CheckConfig{Enabled: true, MaxDays: 90, Severity: SeverityWarning}},
+		IAMClient: func(accountID string) iamiface.IAMAPI {
+			return clients[accountID]
+		},
+		Logger:       logger,
+		PollInterval: time.Millisecond,
+		Timeout:      time.Second,
+	}
+
+	reports, err := m.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("Check() returned %d reports, want 3", len(reports))
+	}
+	if reports[0].Account.Name() != "prod" || len(reports[0].Findings) != 2 {
+		t.Errorf("report 0 = %s with %d findings, want prod with 2", reports[0].Account.Name(), len(reports[0].Findings))
+	}
+	if reports[0].Findings[0].Account.Alias != "prod" {
+		t.Errorf("finding account = %+v, want alias prod", reports[0].Findings[0].Account)
+	}
+	if reports[1].Err == nil || !strings.Contains(reports[1].Error, "access denied") {
+		t.Errorf("report 1 error = %v, want access denied", reports[1].Err)
+	}
+	if reports[2].Account.Name() != "333333333333" {
+		t.Errorf("report 2 name = %s, want the account ID", reports[2].Account.Name())
+	}
+
+	encoded, err := json.Marshal(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"alias":"prod"`) {
+		t.Errorf("json report = %s, want the account alias", encoded)
+	}
+
+	message, err := (&SlackAlert{Channel: "#ops"}).AccountsMessage(reports, m.Checks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, a := range message.Attachments {
+		titles = append(titles, a.Title)
+	}
+	want := []string{
+		"prod (111111111111): Expired IAM Access Keys",
+		"222222222222 (222222222222): check failed",
+		"333333333333 (333333333333): Expired IAM Access Keys",
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("AccountsMessage() titles = %v, want %v", titles, want)
+	}
+
+	n := (&SlackAlert{}).AccountsNotifyMessage(reports, m.Checks)
+	titles = nil
+	for _, f := range n.Fields {
+		titles = append(titles, f.Title)
+	}
+	want = []string{"prod (111111111111)", "222222222222 (222222222222): check failed", "333333333333 (333333333333)"}
+	if !reflect.DeepEqual(titles, want) || n.Severity != notify.SeverityCritical {
+		t.Errorf("AccountsNotifyMessage() fields = %v with severity %s, want %v with critical", titles, n.Severity, want)
+	}
+	if (&SlackAlert{}).AccountsNotifyMessage(nil, m.Checks) != nil {
+		t.Error("AccountsNotifyMessage() without reports is not nil")
+	}
+}
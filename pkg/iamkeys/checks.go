@@ -0,0 +1,183 @@
+package iamkeys
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Severity ranks how urgent a finding is.
+type Severity string
+
+// Severities a check can be configured with.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// rank orders severities so the worst finding can pick the alert color.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+// Names of the checks run against the credential report.
+const (
+	CheckAccessKeyAge      = "access-key-age"
+	CheckAccessKeyUnused   = "access-key-unused"
+	CheckPasswordAge       = "password-age"
+	CheckConsoleWithoutMFA = "console-without-mfa"
+	CheckRootAccessKey     = "root-access-key"
+)
+
+// CheckConfig turns a single check on and sets its threshold and severity.
+// MaxDays is ignored by checks that have no age threshold.
+type CheckConfig struct {
+	Enabled  bool
+	MaxDays  uint
+	Severity Severity
+}
+
+// Checks configures every credential hygiene check.
+type Checks struct {
+	// AccessKeyAge flags active keys last rotated more than MaxDays ago.
+	AccessKeyAge CheckConfig
+	// AccessKeyUnused flags active keys not used for more than MaxDays.
+	// Keys that were never used count from when they were created.
+	AccessKeyUnused CheckConfig
+	// PasswordAge flags console passwords changed more than MaxDays ago.
+	PasswordAge CheckConfig
+	// ConsoleWithoutMFA flags users, including root, who can sign in to
+	// the console without MFA.
+	ConsoleWithoutMFA CheckConfig
+	// RootAccessKey flags any active access key on the root user.
+	RootAccessKey CheckConfig
+}
+
+// Validate returns an error if an enabled age check has no threshold.
+func (c Checks) Validate() error {
+	aged := []struct {
+		name string
+		cfg  CheckConfig
+	}{
+		{CheckAccessKeyAge, c.AccessKeyAge},
+		{CheckAccessKeyUnused, c.AccessKeyUnused},
+		{CheckPasswordAge, c.PasswordAge},
+	}
+	for _, a := range aged {
+		if a.cfg.Enabled && a.cfg.MaxDays == 0 {
+			return fmt.Errorf("%s days must be greater than 0", a.name)
+		}
+	}
+	if !c.AccessKeyAge.Enabled && !c.AccessKeyUnused.Enabled && !c.PasswordAge.Enabled &&
+		!c.ConsoleWithoutMFA.Enabled && !c.RootAccessKey.Enabled {
+		return errors.New("no checks are enabled")
+	}
+	return nil
+}
+
+// Evaluate runs every enabled check over the report in a single pass and
+// returns the findings in report order.
+func (c Checks) Evaluate(report *CredentialReport) []Finding {
+	var findings []Finding
+	now := report.GeneratedTime
+
+	for _, u := range report.Users {
+		if c.RootAccessKey.Enabled && u.IsRoot() {
+			for i, k := range u.AccessKeys {
+				if k.Active {
+					findings = append(findings, Finding{
+						Check:     CheckRootAccessKey,
+						Severity:  c.RootAccessKey.Severity,
+						User:      u.User,
+						AccessKey: i + 1,
+					})
+				}
+			}
+		}
+
+		if c.ConsoleWithoutMFA.Enabled && !u.MFAActive && (u.PasswordEnabled || u.IsRoot()) {
+			findings = append(findings, Finding{
+				Check:    CheckConsoleWithoutMFA,
+				Severity: c.ConsoleWithoutMFA.Severity,
+				User:     u.User,
+			})
+		}
+
+		if c.PasswordAge.Enabled && u.PasswordEnabled && !u.PasswordLastChanged.IsZero() {
+			if days := ageInDays(now, u.PasswordLastChanged); days > float64(c.PasswordAge.MaxDays) {
+				findings = append(findings, Finding{
+					Check:    CheckPasswordAge,
+					Severity: c.PasswordAge.Severity,
+					User:     u.User,
+					Since:    u.PasswordLastChanged,
+					AgeDays:  int(days),
+				})
+			}
+		}
+
+		for i, k := range u.AccessKeys {
+			if !k.Active || k.LastRotated.IsZero() {
+				continue
+			}
+			if c.AccessKeyAge.Enabled {
+				if days := ageInDays(now, k.LastRotated); days > float64(c.AccessKeyAge.MaxDays) {
+					findings = append(findings, Finding{
+						Check:     CheckAccessKeyAge,
+						Severity:  c.AccessKeyAge.Severity,
+						User:      u.User,
+						AccessKey: i + 1,
+						Since:     k.LastRotated,
+						AgeDays:   int(days),
+					})
+				}
+			}
+			if c.AccessKeyUnused.Enabled {
+				lastUsed := k.LastUsedDate
+				if lastUsed.IsZero() {
+					lastUsed = k.LastRotated
+				}
+				if days := ageInDays(now, lastUsed); days > float64(c.AccessKeyUnused.MaxDays) {
+					findings = append(findings, Finding{
+						Check:     CheckAccessKeyUnused,
+						Severity:  c.AccessKeyUnused.Severity,
+						User:      u.User,
+						AccessKey: i + 1,
+						Since:     lastUsed,
+						AgeDays:   int(days),
+					})
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// ageInDays returns how many days before now t was.
+func ageInDays(now, t time.Time) float64 {
+	return now.Sub(t).Hours() / 24
+}
+
+// CheckTitle returns a human readable title for a check.
+func CheckTitle(check string) string {
+	switch check {
+	case CheckAccessKeyAge:
+		return "IAM Users"
+	case CheckAccessKeyUnused:
+		return "Unused Access Keys"
+	case CheckPasswordAge:
+		return "Old Console Passwords"
+	case CheckConsoleWithoutMFA:
+		return "Console Users Without MFA"
+	case CheckRootAccessKey:
+		return "Root Account Access Keys"
+	}
+	return check
+}
@@ -0,0 +1,117 @@
+package iamkeys
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func findingKeys(findings []Finding) []string {
+	var keys []string
+	for _, f := range findings {
+		keys = append(keys, fmt.Sprintf("%s:%s:%d", f.Check, f.User, f.AccessKey))
+	}
+	return keys
+}
+
+func TestChecksEvaluate(t *testing.T) {
+	report, err := ParseCredentialReport(readFixture(t, "credential_report.csv"), generatedTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		checks Checks
+		want   []string
+	}{
+		{
+			name:   "access key age",
+			checks: Checks{AccessKeyAge: CheckConfig{Enabled: true, MaxDays: 90}},
+			want:   []string{"access-key-age:bob:1", "access-key-age:carol:2"},
+		},
+		{
+			name:   "unused access keys count from rotation when never used",
+			checks: Checks{AccessKeyUnused: CheckConfig{Enabled: true, MaxDays: 30}},
+			want:   []string{"access-key-unused:bob:2", "access-key-unused:carol:2"},
+		},
+		{
+			name:   "password age",
+			checks: Checks{PasswordAge: CheckConfig{Enabled: true, MaxDays: 90}},
+			want:   []string{"password-age:bob:0"},
+		},
+		{
+			name:   "console without mfa",
+			checks: Checks{ConsoleWithoutMFA: CheckConfig{Enabled: true}},
+			want:   []string{"console-without-mfa:bob:0"},
+		},
+		{
+			name:   "root access key",
+			checks: Checks{RootAccessKey: CheckConfig{Enabled: true}},
+		},
+		{
+			name: "all checks",
+			checks: Checks{
+				AccessKeyAge:      CheckConfig{Enabled: true, MaxDays: 90},
+				AccessKeyUnused:   CheckConfig{Enabled: true, MaxDays: 30},
+				PasswordAge:       CheckConfig{Enabled: true, MaxDays: 90},
+				ConsoleWithoutMFA: CheckConfig{Enabled: true},
+				RootAccessKey:     CheckConfig{Enabled: true},
+			},
+			want: []string{
+				"console-without-mfa:bob:0",
+				"password-age:bob:0",
+				"access-key-age:bob:1",
+				"access-key-unused:bob:2",
+				"access-key-age:carol:2",
+				"access-key-unused:carol:2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have := findingKeys(tt.checks.Evaluate(report))
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksEvaluateRoot(t *testing.T) {
+	now := time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC)
+	report := &CredentialReport{
+		GeneratedTime: now,
+		Users: []*User{{
+			User:       RootAccountUser,
+			AccessKeys: [2]AccessKey{{Active: true, LastRotated: now.AddDate(0, 0, -1)}},
+		}},
+	}
+	checks := Checks{
+		ConsoleWithoutMFA: CheckConfig{Enabled: true, Severity: SeverityWarning},
+		RootAccessKey:     CheckConfig{Enabled: true, Severity: SeverityCritical},
+	}
+
+	findings := checks.Evaluate(report)
+	want := []string{"root-access-key:<root_account>:1", "console-without-mfa:<root_account>:0"}
+	if have := findingKeys(findings); !reflect.DeepEqual(have, want) {
+		t.Fatalf("Evaluate() = %v, want %v", have, want)
+	}
+	if findings[0].Severity != SeverityCritical {
+		t.Errorf("root access key severity = %q, want critical", findings[0].Severity)
+	}
+}
+
+func TestChecksValidate(t *testing.T) {
+	if err := (Checks{}).Validate(); err == nil {
+		t.Error("Validate() with no checks enabled did not return an error")
+	}
+	if err := (Checks{PasswordAge: CheckConfig{Enabled: true}}).Validate(); err == nil {
+		t.Error("Validate() with a zero password age did not return an error")
+	}
+	if err := (Checks{RootAccessKey: CheckConfig{Enabled: true}}).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
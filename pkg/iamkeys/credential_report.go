@@ -0,0 +1,231 @@
+package iamkeys
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+const (
+	// RFC8601 is the date/time format used in IAM credential reports.
+	RFC8601 = "2006-01-02T15:04:05-07:00"
+	// RootAccountUser is the user name the credential report uses for the
+	// account's root user.
+	RootAccountUser = "<root_account>"
+)
+
+// ParseTimestamp parses a credential report timestamp. The report uses N/A,
+// no_information and not_supported for dates that don't exist; those parse
+// to the zero time without an error.
+func ParseTimestamp(s string) (time.Time, error) {
+	switch s {
+	case "", "N/A", "no_information", "not_supported":
+		return time.Time{}, nil
+	}
+	return time.Parse(RFC8601, s)
+}
+
+// AccessKey is one of the two access key slots a user has in the report.
+type AccessKey struct {
+	Active       bool
+	LastRotated  time.Time
+	LastUsedDate time.Time
+}
+
+// User is a single row of the credential report.
+type User struct {
+	User                string
+	Arn                 string
+	UserCreationTime    time.Time
+	PasswordEnabled     bool
+	PasswordLastUsed    time.Time
+	PasswordLastChanged time.Time
+	MFAActive           bool
+	AccessKeys          [2]AccessKey
+}
+
+// IsRoot reports whether the row describes the account's root user.
+func (u *User) IsRoot() bool {
+	return u.User == RootAccountUser
+}
+
+// CredentialReport is a parsed IAM credential report.
+type CredentialReport struct {
+	GeneratedTime time.Time
+	Users         []*User
+}
+
+// Default credential report polling intervals.
+const (
+	DefaultPollInterval    = 5 * time.Second
+	DefaultMaxPollInterval = time.Minute
+)
+
+// Backoff is how long to wait between credential report polls. The wait
+// starts at Interval and doubles after every poll, up to MaxInterval.
+type Backoff struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// next returns the wait after one of length d.
+func (b Backoff) next(d time.Duration) time.Duration {
+	d *= 2
+	if d > b.MaxInterval {
+		return b.MaxInterval
+	}
+	return d
+}
+
+// GetCredentialReport fetches the account's credential report, asking IAM
+// to generate one when it is missing or expired and polling with
+// exponential backoff until it is ready. It gives up when ctx is done.
+func GetCredentialReport(ctx context.Context, iamClient iamiface.IAMAPI, backoff Backoff) (*iam.GetCredentialReportOutput, error) {
+	if backoff.Interval <= 0 {
+		backoff.Interval = DefaultPollInterval
+	}
+	if backoff.MaxInterval < backoff.Interval {
+		backoff.MaxInterval = backoff.Interval
+	}
+
+	wait := backoff.Interval
+	for {
+		report, err := iamClient.GetCredentialReportWithContext(ctx, &iam.GetCredentialReportInput{})
+		if err == nil {
+			return report, nil
+		}
+
+		ready, err := handleCredentialReportError(ctx, iamClient, err)
+		if err != nil {
+			return nil, err
+		}
+		if ready {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("timed out waiting for credential report: %w", ctx.Err())
+		case <-timer.C:
+		}
+		wait = backoff.next(wait)
+	}
+}
+
+// handleCredentialReportError decides what to do after GetCredentialReport
+// fails. It starts generating a missing or expired report and returns
+// ready when generation already finished, so the report can be fetched
+// again right away. Errors that polling won't fix are returned.
+func handleCredentialReportError(ctx context.Context, iamClient iamiface.IAMAPI, err error) (bool, error) {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false, err
+	}
+
+	switch aerr.Code() {
+	case iam.ErrCodeCredentialReportNotReadyException:
+		return false, nil
+	case iam.ErrCodeCredentialReportNotPresentException, iam.ErrCodeCredentialReportExpiredException:
+		output, err := iamClient.GenerateCredentialReportWithContext(ctx, &iam.GenerateCredentialReportInput{})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeLimitExceededException {
+				return false, nil
+			}
+			return false, err
+		}
+		return aws.StringValue(output.State) == iam.ReportStateTypeComplete, nil
+	}
+	return false, err
+}
+
+// ParseCredentialReport parses the CSV content of a credential report into
+// typed per-user records.
+func ParseCredentialReport(content []byte, generatedTime time.Time) (*CredentialReport, error) {
+	report := &CredentialReport{GeneratedTime: generatedTime}
+	reader := csv.NewReader(strings.NewReader(string(content)))
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return report, nil
+		}
+		return nil, fmt.Errorf("failed to read header from csv: %w", err)
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row from csv: %w", err)
+		}
+
+		user, err := parseUser(rowToMap(header, row))
+		if err != nil {
+			return nil, err
+		}
+		report.Users = append(report.Users, user)
+	}
+
+	return report, nil
+}
+
+func rowToMap(header []string, row []string) map[string]string {
+	m := map[string]string{}
+	for i, h := range header {
+		if i < len(row) {
+			m[strings.ToLower(h)] = row[i]
+		}
+	}
+	return m
+}
+
+// timestampColumn ties a report column to the User field it is parsed into.
+type timestampColumn struct {
+	column string
+	dest   *time.Time
+}
+
+// parseUser converts a row keyed by column name into a User.
+func parseUser(row map[string]string) (*User, error) {
+	u := &User{
+		User:            row["user"],
+		Arn:             row["arn"],
+		PasswordEnabled: row["password_enabled"] == "true",
+		MFAActive:       row["mfa_active"] == "true",
+	}
+
+	timestamps := []timestampColumn{
+		{"user_creation_time", &u.UserCreationTime},
+		{"password_last_used", &u.PasswordLastUsed},
+		{"password_last_changed", &u.PasswordLastChanged},
+	}
+	for i := range u.AccessKeys {
+		u.AccessKeys[i].Active = row[fmt.Sprintf("access_key_%d_active", i+1)] == "true"
+		timestamps = append(timestamps,
+			timestampColumn{fmt.Sprintf("access_key_%d_last_rotated", i+1), &u.AccessKeys[i].LastRotated},
+			timestampColumn{fmt.Sprintf("access_key_%d_last_used_date", i+1), &u.AccessKeys[i].LastUsedDate},
+		)
+	}
+
+	for _, ts := range timestamps {
+		t, err := ParseTimestamp(row[ts.column])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s for user %s: %w", ts.column, u.User, err)
+		}
+		*ts.dest = t
+	}
+
+	return u, nil
+}
@@ -0,0 +1,229 @@
+package iamkeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+var generatedTime = time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func readFixture(t *testing.T, name string) []byte {
+	content, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestParseTimestamp(t *testing.T) {
+
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2018-07-11T19:19:08+00:00", time.Date(2018, 7, 11, 19, 19, 8, 0, time.UTC)},
+		{"2018-06-25T19:05:23+00:00", time.Date(2018, 6, 25, 19, 05, 23, 0, time.UTC)},
+		{"N/A", time.Time{}},
+		{"no_information", time.Time{}},
+		{"not_supported", time.Time{}},
+	}
+	for _, c := range cases {
+		got, err := ParseTimestamp(c.in)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q) through an error %q", c.in, err)
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("ParseTimestamp(%q) == %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	if _, err := ParseTimestamp("yesterday"); err == nil {
+		t.Errorf("ParseTimestamp(%q) did not return an error", "yesterday")
+	}
+}
+
+func TestParseCredentialReport(t *testing.T) {
+	report, err := ParseCredentialReport(readFixture(t, "credential_report.csv"), generatedTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Users) != 5 {
+		t.Fatalf("ParseCredentialReport() returned %d users, want 5", len(report.Users))
+	}
+
+	root := report.Users[0]
+	if !root.IsRoot() || !root.MFAActive || root.PasswordEnabled {
+		t.Errorf("root user = %+v, want root with MFA and no password", root)
+	}
+
+	want := &User{
+		User:                "bob",
+		Arn:                 "arn:aws:iam::123456789012:user/bob",
+		UserCreationTime:    time.Date(2017, 2, 3, 10, 0, 0, 0, time.UTC),
+		PasswordEnabled:     true,
+		PasswordLastChanged: time.Date(2017, 2, 3, 10, 0, 0, 0, time.UTC),
+		AccessKeys: [2]AccessKey{
+			{
+				Active:       true,
+				LastRotated:  time.Date(2017, 2, 3, 10, 5, 0, 0, time.UTC),
+				LastUsedDate: time.Date(2019, 6, 29, 12, 0, 0, 0, time.UTC),
+			},
+			{
+				Active:      true,
+				LastRotated: time.Date(2019, 5, 15, 10, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	// time.Parse gives UTC offsets a fixed zone, so compare in UTC.
+	have := *report.Users[2]
+	have.UserCreationTime = have.UserCreationTime.UTC()
+	have.PasswordLastChanged = have.PasswordLastChanged.UTC()
+	for i := range have.AccessKeys {
+		have.AccessKeys[i].LastRotated = utcOrZero(have.AccessKeys[i].LastRotated)
+		have.AccessKeys[i].LastUsedDate = utcOrZero(have.AccessKeys[i].LastUsedDate)
+	}
+	if !reflect.DeepEqual(&have, want) {
+		t.Errorf("ParseCredentialReport() user = %+v, want %+v", have, *want)
+	}
+}
+
+func utcOrZero(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC()
+}
+
+func TestParseCredentialReportErrors(t *testing.T) {
+	cases := map[string]string{
+		"bad timestamp": "user,arn,access_key_1_active,access_key_1_last_rotated\nbob,arn,true,yesterday\n",
+		"bad csv":       "user,arn\nbob,arn,extra\n",
+	}
+	for name, content := range cases {
+		if _, err := ParseCredentialReport([]byte(content), generatedTime); err == nil {
+			t.Errorf("ParseCredentialReport(%s) did not return an error", name)
+		}
+	}
+
+	report, err := ParseCredentialReport(nil, generatedTime)
+	if err != nil || len(report.Users) != 0 {
+		t.Errorf("ParseCredentialReport(empty) = %v, %v, want no users", report, err)
+	}
+}
+
+func TestGetCredentialReport(t *testing.T) {
+	notPresent := awserr.New(iam.ErrCodeCredentialReportNotPresentException, "not present", nil)
+	expired := awserr.New(iam.ErrCodeCredentialReportExpiredException, "expired", nil)
+	notReady := awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil)
+	limitExceeded := awserr.New(iam.ErrCodeLimitExceededException, "limit exceeded", nil)
+	serviceFailure := awserr.New(iam.ErrCodeServiceFailureException, "failure", nil)
+
+	tests := []struct {
+		name          string
+		client        *mockIAMClient
+		wantErr       bool
+		wantFetched   int
+		wantGenerated int
+	}{
+		{
+			name:        "ready",
+			client:      &mockIAMClient{},
+			wantFetched: 1,
+		},
+		{
+			name:          "not present then started",
+			client:        &mockIAMClient{Errors: []error{notPresent, notReady}},
+			wantFetched:   3,
+			wantGenerated: 1,
+		},
+		{
+			name:          "expired then in progress",
+			client:        &mockIAMClient{Errors: []error{expired}, GenerateState: iam.ReportStateTypeInprogress},
+			wantFetched:   2,
+			wantGenerated: 1,
+		},
+		{
+			name:          "expired then complete",
+			client:        &mockIAMClient{Errors: []error{expired}, GenerateState: iam.ReportStateTypeComplete},
+			wantFetched:   2,
+			wantGenerated: 1,
+		},
+		{
+			name:          "generate limit exceeded",
+			client:        &mockIAMClient{Errors: []error{notPresent, notPresent}, GenerateErrors: []error{limitExceeded}},
+			wantFetched:   3,
+			wantGenerated: 2,
+		},
+		{
+			name:          "generate failure",
+			client:        &mockIAMClient{Errors: []error{notPresent}, GenerateErrors: []error{serviceFailure}},
+			wantErr:       true,
+			wantFetched:   1,
+			wantGenerated: 1,
+		},
+		{
+			name:        "get failure",
+			client:      &mockIAMClient{Errors: []error{serviceFailure}},
+			wantErr:     true,
+			wantFetched: 1,
+		},
+		{
+			name:        "non aws error",
+			client:      &mockIAMClient{Errors: []error{errors.New("connection reset")}},
+			wantErr:     true,
+			wantFetched: 1,
+		},
+	}
+
+	backoff := Backoff{Interval: time.Millisecond, MaxInterval: 4 * time.Millisecond}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			_, err := GetCredentialReport(ctx, tt.client, backoff)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCredentialReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.client.Fetched != tt.wantFetched || tt.client.Generated != tt.wantGenerated {
+				t.Errorf("fetched %d and generated %d times, want %d and %d",
+					tt.client.Fetched, tt.client.Generated, tt.wantFetched, tt.wantGenerated)
+			}
+		})
+	}
+}
+
+func TestGetCredentialReportContext(t *testing.T) {
+	notReady := awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil)
+	client := &mockIAMClient{Errors: []error{notReady, notReady, notReady}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := GetCredentialReport(ctx, client, Backoff{Interval: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetCredentialReport() error = %v, want context.Canceled", err)
+	}
+	if client.Fetched != 1 {
+		t.Errorf("fetched %d times after cancel, want 1", client.Fetched)
+	}
+}
+
+func TestBackoffNext(t *testing.T) {
+	b := Backoff{Interval: time.Second, MaxInterval: 5 * time.Second}
+	var have []time.Duration
+	for d := b.Interval; len(have) < 4; d = b.next(d) {
+		have = append(have, d)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("backoff = %v, want %v", have, want)
+	}
+}
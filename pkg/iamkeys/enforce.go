@@ -0,0 +1,354 @@
+package iamkeys
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"go.uber.org/zap"
+)
+
+// Actions the enforcer takes on an access key.
+const (
+	ActionWarn       = "warn"
+	ActionDeactivate = "deactivate"
+	ActionDelete     = "delete"
+	ActionExempt     = "exempt"
+)
+
+// DeactivatedTagPrefix starts the key of the user tag that records when one
+// of the user's access keys was deactivated. The access key ID follows it,
+// and the tag value is the time in RFC 3339 format.
+const DeactivatedTagPrefix = "iam-keys-check:deactivated:"
+
+// deactivatedTagKey returns the key of the tag recording when the access key
+// was deactivated.
+func deactivatedTagKey(accessKeyID string) string {
+	return DeactivatedTagPrefix + accessKeyID
+}
+
+// Action is a single thing the enforcer did, or would have done in a dry
+// run, to an access key.
+type Action struct {
+	Action      string
+	User        string
+	AccessKeyID string
+	AgeDays     int
+	DryRun      bool
+}
+
+// IAMKeysEnforce warns about, deactivates and finally deletes old access
+// keys. Key age is measured from the key's creation date. When a key is
+// deactivated, the time is recorded in a user tag, and the key is deleted
+// DeleteGraceDays after that. Keys found inactive without the tag, such as
+// keys deactivated by hand, are tagged with the current time first.
+type IAMKeysEnforce struct {
+	DryRun    bool
+	IAMClient iamiface.IAMAPI
+	Logger    *zap.Logger
+	// WarnDays is the age at which a key's owner is warned.
+	WarnDays uint
+	// DeactivateDays is the age at which an active key is made inactive.
+	DeactivateDays uint
+	// DeleteGraceDays is how long an inactive key is kept after it was
+	// deactivated before it is deleted.
+	DeleteGraceDays uint
+	// ExemptUsers are never warned or changed.
+	ExemptUsers []string
+	// ExemptTagKey and ExemptTagValue exempt users tagged with them. An
+	// empty ExemptTagValue matches any value.
+	ExemptTagKey   string
+	ExemptTagValue string
+}
+
+// Validate returns an error if the thresholds are out of order.
+func (e *IAMKeysEnforce) Validate() error {
+	if e.WarnDays == 0 || e.DeactivateDays == 0 {
+		return errors.New("warn and deactivate days must be greater than 0")
+	}
+	if e.WarnDays > e.DeactivateDays {
+		return fmt.Errorf("warn days (%d) must not be greater than deactivate days (%d)", e.WarnDays, e.DeactivateDays)
+	}
+	return nil
+}
+
+// Enforce walks every IAM user's access keys and returns the actions taken.
+func (e *IAMKeysEnforce) Enforce(now time.Time) ([]Action, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	var users []*iam.User
+	err := e.IAMClient.ListUsersPages(&iam.ListUsersInput{},
+		func(page *iam.ListUsersOutput, lastPage bool) bool {
+			users = append(users, page.Users...)
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	var actions []Action
+	for _, u := range users {
+		userActions, err := e.enforceUser(aws.StringValue(u.UserName), now)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, userActions...)
+	}
+	return actions, nil
+}
+
+func (e *IAMKeysEnforce) enforceUser(userName string, now time.Time) ([]Action, error) {
+	output, err := e.IAMClient.ListAccessKeys(&iam.ListAccessKeysInput{
+		UserName: aws.String(userName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access keys for %s: %w", userName, err)
+	}
+
+	// The user's tags are only listed once a key needs them.
+	var tags map[string]string
+	loadTags := func() error {
+		if tags != nil {
+			return nil
+		}
+		var err error
+		tags, err = e.listUserTags(userName)
+		return err
+	}
+
+	var actions []Action
+	for _, k := range output.AccessKeyMetadata {
+		action := Action{
+			User:        userName,
+			AccessKeyID: aws.StringValue(k.AccessKeyId),
+			AgeDays:     int(ageInDays(now, aws.TimeValue(k.CreateDate))),
+			DryRun:      e.DryRun,
+		}
+		status := aws.StringValue(k.Status)
+
+		var deactivatedAt time.Time
+		if status == iam.StatusTypeInactive {
+			if err := loadTags(); err != nil {
+				return actions, err
+			}
+			deactivatedAt = e.deactivatedAt(action, tags)
+		}
+
+		action.Action = e.keyAction(status, action.AgeDays, deactivatedAt, now)
+		if action.Action == "" {
+			continue
+		}
+
+		if e.ExemptTagKey != "" {
+			if err := loadTags(); err != nil {
+				return actions, err
+			}
+		}
+		if e.isExempt(userName, tags) {
+			if action.Action == actionStartGrace {
+				continue
+			}
+			action.Action = ActionExempt
+		}
+
+		if action.Action == actionStartGrace {
+			if err := e.startGrace(action, now); err != nil {
+				return actions, err
+			}
+			continue
+		}
+		if err := e.apply(action, now); err != nil {
+			return actions, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// deactivatedAt returns when an inactive key was deactivated, from its tag.
+// It is zero when the key has no tag, or a tag that doesn't parse, so the
+// key's grace period starts now and the tag is rewritten.
+func (e *IAMKeysEnforce) deactivatedAt(a Action, tags map[string]string) time.Time {
+	value, ok := tags[deactivatedTagKey(a.AccessKeyID)]
+	if !ok {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		e.Logger.Warn("restarting the delete grace period of a key with a malformed deactivation tag",
+			zap.String("user", a.User),
+			zap.String("access-key-id", a.AccessKeyID),
+			zap.String("tag-value", value),
+			zap.Error(err),
+		)
+		return time.Time{}
+	}
+	return t
+}
+
+// actionStartGrace records the deactivation time of an inactive key that
+// has none, so its delete grace period starts now. It is not reported.
+const actionStartGrace = "start-grace"
+
+// keyAction picks what to do with a key of the given status and age, or
+// returns "" to leave it alone. deactivatedAt is when an inactive key was
+// deactivated, or zero when that wasn't recorded.
+func (e *IAMKeysEnforce) keyAction(status string, ageDays int, deactivatedAt, now time.Time) string {
+	switch {
+	case status == iam.StatusTypeInactive && deactivatedAt.IsZero():
+		return actionStartGrace
+	case status == iam.StatusTypeInactive && now.After(deactivatedAt.AddDate(0, 0, int(e.DeleteGraceDays))):
+		return ActionDelete
+	case status == iam.StatusTypeActive && ageDays > int(e.DeactivateDays):
+		return ActionDeactivate
+	case status == iam.StatusTypeActive && ageDays > int(e.WarnDays):
+		return ActionWarn
+	}
+	return ""
+}
+
+// listUserTags returns the user's tags as a map.
+func (e *IAMKeysEnforce) listUserTags(userName string) (map[string]string, error) {
+	output, err := e.IAMClient.ListUserTags(&iam.ListUserTagsInput{
+		UserName: aws.String(userName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %w", userName, err)
+	}
+	tags := map[string]string{}
+	for _, t := range output.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags, nil
+}
+
+// isExempt reports whether the user is on the allowlist or carries the
+// exemption tag.
+func (e *IAMKeysEnforce) isExempt(userName string, tags map[string]string) bool {
+	for _, u := range e.ExemptUsers {
+		if u == userName {
+			return true
+		}
+	}
+	if e.ExemptTagKey == "" {
+		return false
+	}
+	value, ok := tags[e.ExemptTagKey]
+	return ok && (e.ExemptTagValue == "" || value == e.ExemptTagValue)
+}
+
+// tagDeactivated records on the user that the access key was deactivated at
+// now.
+func (e *IAMKeysEnforce) tagDeactivated(a Action, now time.Time) error {
+	_, err := e.IAMClient.TagUser(&iam.TagUserInput{
+		UserName: aws.String(a.User),
+		Tags: []*iam.Tag{{
+			Key:   aws.String(deactivatedTagKey(a.AccessKeyID)),
+			Value: aws.String(now.UTC().Format(time.RFC3339)),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record deactivation of access key %s: %w", a.AccessKeyID, err)
+	}
+	return nil
+}
+
+// startGrace records an inactive key without a deactivation time as
+// deactivated now.
+func (e *IAMKeysEnforce) startGrace(a Action, now time.Time) error {
+	fields := []zap.Field{
+		zap.String("user", a.User),
+		zap.String("access-key-id", a.AccessKeyID),
+		zap.Int("age-days", a.AgeDays),
+	}
+	if e.DryRun {
+		e.Logger.Info("would start delete grace period of inactive access key", fields...)
+		return nil
+	}
+	if err := e.tagDeactivated(a, now); err != nil {
+		return err
+	}
+	e.Logger.Info("started delete grace period of inactive access key", fields...)
+	return nil
+}
+
+func (e *IAMKeysEnforce) apply(a Action, now time.Time) error {
+	fields := []zap.Field{
+		zap.String("user", a.User),
+		zap.String("access-key-id", a.AccessKeyID),
+		zap.Int("age-days", a.AgeDays),
+	}
+
+	switch a.Action {
+	case ActionWarn:
+		e.Logger.Info("access key past warning age", fields...)
+	case ActionExempt:
+		e.Logger.Info("skipping exempt user access key", fields...)
+	case ActionDeactivate:
+		if e.DryRun {
+			e.Logger.Info("would deactivate access key", fields...)
+			return nil
+		}
+		_, err := e.IAMClient.UpdateAccessKey(&iam.UpdateAccessKeyInput{
+			AccessKeyId: aws.String(a.AccessKeyID),
+			Status:      aws.String(iam.StatusTypeInactive),
+			UserName:    aws.String(a.User),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to deactivate access key %s: %w", a.AccessKeyID, err)
+		}
+		if err := e.tagDeactivated(a, now); err != nil {
+			return err
+		}
+		e.Logger.Info("deactivated access key", fields...)
+	case ActionDelete:
+		if e.DryRun {
+			e.Logger.Info("would delete access key", fields...)
+			return nil
+		}
+		_, err := e.IAMClient.DeleteAccessKey(&iam.DeleteAccessKeyInput{
+			AccessKeyId: aws.String(a.AccessKeyID),
+			UserName:    aws.String(a.User),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete access key %s: %w", a.AccessKeyID, err)
+		}
+		_, err = e.IAMClient.UntagUser(&iam.UntagUserInput{
+			UserName: aws.String(a.User),
+			TagKeys:  []*string{aws.String(deactivatedTagKey(a.AccessKeyID))},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove deactivation tag of access key %s: %w", a.AccessKeyID, err)
+		}
+		e.Logger.Info("deleted access key", fields...)
+	}
+	return nil
+}
+
+// FilterActions returns the actions of the given kind.
+func FilterActions(actions []Action, kind string) []Action {
+	var filtered []Action
+	for _, a := range actions {
+		if a.Action == kind {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+// WriteActionReport writes a table of actions to w.
+func WriteActionReport(w io.Writer, actions []Action) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tUSER\tACCESS KEY ID\tAGE (DAYS)\tDRY RUN")
+	for _, a := range actions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%t\n", a.Action, a.User, a.AccessKeyID, a.AgeDays, a.DryRun)
+	}
+	return tw.Flush()
+}
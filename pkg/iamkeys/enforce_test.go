@@ -0,0 +1,324 @@
+package iamkeys
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+var enforceNow = time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC)
+
+type mockEnforceIAMClient struct {
+	iamiface.IAMAPI
+	Keys        map[string][]*iam.AccessKeyMetadata
+	Tags        map[string][]*iam.Tag
+	Deactivated []string
+	Deleted     []string
+}
+
+func (m *mockEnforceIAMClient) ListUsersPages(input *iam.ListUsersInput, fn func(*iam.ListUsersOutput, bool) bool) error {
+	var users []*iam.User
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		users = append(users, &iam.User{UserName: aws.String(name)})
+	}
+	fn(&iam.ListUsersOutput{Users: users}, true)
+	return nil
+}
+
+func (m *mockEnforceIAMClient) ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.ListAccessKeysOutput, error) {
+	return &iam.ListAccessKeysOutput{AccessKeyMetadata: m.Keys[aws.StringValue(input.UserName)]}, nil
+}
+
+func (m *mockEnforceIAMClient) ListUserTags(input *iam.ListUserTagsInput) (*iam.ListUserTagsOutput, error) {
+	return &iam.ListUserTagsOutput{Tags: m.Tags[aws.StringValue(input.UserName)]}, nil
+}
+
+func (m *mockEnforceIAMClient) TagUser(input *iam.TagUserInput) (*iam.TagUserOutput, error) {
+	user := aws.StringValue(input.UserName)
+	for _, tag := range input.Tags {
+		m.untag(user, aws.StringValue(tag.Key))
+		m.Tags[user] = append(m.Tags[user], tag)
+	}
+	return &iam.TagUserOutput{}, nil
+}
+
+func (m *mockEnforceIAMClient) UntagUser(input *iam.UntagUserInput) (*iam.UntagUserOutput, error) {
+	for _, key := range input.TagKeys {
+		m.untag(aws.StringValue(input.UserName), aws.StringValue(key))
+	}
+	return &iam.UntagUserOutput{}, nil
+}
+
+func (m *mockEnforceIAMClient) untag(user, key string) {
+	var kept []*iam.Tag
+	for _, t := range m.Tags[user] {
+		if aws.StringValue(t.Key) != key {
+			kept = append(kept, t)
+		}
+	}
+	m.Tags[user] = kept
+}
+
+// tag returns the value of a user's tag.
+func (m *mockEnforceIAMClient) tag(user, key string) string {
+	for _, t := range m.Tags[user] {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
+
+// setKeyStatus changes the status of a user's access key.
+func (m *mockEnforceIAMClient) setKeyStatus(user, id, status string) {
+	for _, k := range m.Keys[user] {
+		if aws.StringValue(k.AccessKeyId) == id {
+			k.Status = aws.String(status)
+		}
+	}
+}
+
+func (m *mockEnforceIAMClient) UpdateAccessKey(input *iam.UpdateAccessKeyInput) (*iam.UpdateAccessKeyOutput, error) {
+	m.Deactivated = append(m.Deactivated, aws.StringValue(input.AccessKeyId))
+	m.setKeyStatus(aws.StringValue(input.UserName), aws.StringValue(input.AccessKeyId), aws.StringValue(input.Status))
+	return &iam.UpdateAccessKeyOutput{}, nil
+}
+
+func (m *mockEnforceIAMClient) DeleteAccessKey(input *iam.DeleteAccessKeyInput) (*iam.DeleteAccessKeyOutput, error) {
+	m.Deleted = append(m.Deleted, aws.StringValue(input.AccessKeyId))
+	return &iam.DeleteAccessKeyOutput{}, nil
+}
+
+func accessKey(id, status string, ageDays int) *iam.AccessKeyMetadata {
+	return &iam.AccessKeyMetadata{
+		AccessKeyId: aws.String(id),
+		CreateDate:  aws.Time(enforceNow.AddDate(0, 0, -ageDays)),
+		Status:      aws.String(status),
+	}
+}
+
+func newMockEnforceIAMClient() *mockEnforceIAMClient {
+	return &mockEnforceIAMClient{
+		Keys: map[string][]*iam.AccessKeyMetadata{
+			"alice": {
+				accessKey("AKIAALICE1", iam.StatusTypeActive, 10),
+				accessKey("AKIAALICE2", iam.StatusTypeActive, 80),
+			},
+			"bob": {
+				accessKey("AKIABOB1", iam.StatusTypeActive, 100),
+				accessKey("AKIABOB2", iam.StatusTypeInactive, 100),
+			},
+			"carol": {
+				accessKey("AKIACAROL1", iam.StatusTypeInactive, 200),
+			},
+			"dave": {
+				accessKey("AKIADAVE1", iam.StatusTypeActive, 300),
+			},
+		},
+		Tags: map[string][]*iam.Tag{
+			"carol": {{
+				Key:   aws.String(deactivatedTagKey("AKIACAROL1")),
+				Value: aws.String(enforceNow.AddDate(0, 0, -31).Format(time.RFC3339)),
+			}},
+			"dave": {{Key: aws.String("KeyEnforcement"), Value: aws.String("exempt")}},
+		},
+	}
+}
+
+func actionKeys(actions []Action) []string {
+	var keys []string
+	for _, a := range actions {
+		keys = append(keys, a.Action+":"+a.AccessKeyID)
+	}
+	return keys
+}
+
+func TestEnforce(t *testing.T) {
+	m := newMockEnforceIAMClient()
+	e := IAMKeysEnforce{
+		IAMClient:       m,
+		Logger:          logger,
+		WarnDays:        75,
+		DeactivateDays:  90,
+		DeleteGraceDays: 30,
+		ExemptTagKey:    "KeyEnforcement",
+	}
+
+	actions, err := e.Enforce(enforceNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"warn:AKIAALICE2",
+		"deactivate:AKIABOB1",
+		"delete:AKIACAROL1",
+		"exempt:AKIADAVE1",
+	}
+	if have := actionKeys(actions); !reflect.DeepEqual(have, want) {
+		t.Fatalf("Enforce() = %v, want %v", have, want)
+	}
+	if !reflect.DeepEqual(m.Deactivated, []string{"AKIABOB1"}) {
+		t.Errorf("deactivated %v, want [AKIABOB1]", m.Deactivated)
+	}
+	if !reflect.DeepEqual(m.Deleted, []string{"AKIACAROL1"}) {
+		t.Errorf("deleted %v, want [AKIACAROL1]", m.Deleted)
+	}
+	if have := m.tag("bob", deactivatedTagKey("AKIABOB1")); have != "2019-07-01T00:00:00Z" {
+		t.Errorf("AKIABOB1 deactivation tag = %q, want the enforcement time", have)
+	}
+	if have := m.tag("bob", deactivatedTagKey("AKIABOB2")); have != "2019-07-01T00:00:00Z" {
+		t.Errorf("AKIABOB2 deactivation tag = %q, want its grace period started", have)
+	}
+	if have := m.tag("carol", deactivatedTagKey("AKIACAROL1")); have != "" {
+		t.Errorf("AKIACAROL1 deactivation tag = %q after delete, want it removed", have)
+	}
+}
+
+func TestEnforceDeleteGrace(t *testing.T) {
+	m := &mockEnforceIAMClient{
+		Keys: map[string][]*iam.AccessKeyMetadata{
+			"alice": {accessKey("AKIAALICE1", iam.StatusTypeActive, 400)},
+		},
+		Tags: map[string][]*iam.Tag{},
+	}
+	e := IAMKeysEnforce{
+		IAMClient:       m,
+		Logger:          logger,
+		WarnDays:        75,
+		DeactivateDays:  90,
+		DeleteGraceDays: 30,
+	}
+
+	runs := []struct {
+		days int
+		want []string
+	}{
+		{0, []string{"deactivate:AKIAALICE1"}},
+		{1, nil},
+		{30, nil},
+		{31, []string{"delete:AKIAALICE1"}},
+	}
+	for _, run := range runs {
+		actions, err := e.Enforce(enforceNow.AddDate(0, 0, run.days))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := actionKeys(actions); !reflect.DeepEqual(have, run.want) {
+			t.Errorf("Enforce() %d days after deactivating = %v, want %v", run.days, have, run.want)
+		}
+	}
+}
+
+func TestEnforceMalformedDeactivatedTag(t *testing.T) {
+	m := &mockEnforceIAMClient{
+		Keys: map[string][]*iam.AccessKeyMetadata{
+			"alice": {accessKey("AKIAALICE1", iam.StatusTypeInactive, 400)},
+		},
+		Tags: map[string][]*iam.Tag{
+			"alice": {{Key: aws.String(deactivatedTagKey("AKIAALICE1")), Value: aws.String("last tuesday")}},
+		},
+	}
+	e := IAMKeysEnforce{
+		IAMClient:       m,
+		Logger:          logger,
+		WarnDays:        75,
+		DeactivateDays:  90,
+		DeleteGraceDays: 30,
+	}
+
+	for _, days := range []int{0, 1} {
+		actions, err := e.Enforce(enforceNow.AddDate(0, 0, days))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actions) != 0 {
+			t.Errorf("Enforce() %d days after the malformed tag = %v, want nothing", days, actionKeys(actions))
+		}
+	}
+	if have := m.tag("alice", deactivatedTagKey("AKIAALICE1")); have != "2019-07-01T00:00:00Z" {
+		t.Errorf("deactivation tag = %q, want it rewritten once, on the first run", have)
+	}
+}
+
+func TestEnforceDryRunAndAllowlist(t *testing.T) {
+	m := newMockEnforceIAMClient()
+	e := IAMKeysEnforce{
+		DryRun:          true,
+		IAMClient:       m,
+		Logger:          logger,
+		WarnDays:        75,
+		DeactivateDays:  90,
+		DeleteGraceDays: 30,
+		ExemptUsers:     []string{"bob"},
+	}
+
+	actions, err := e.Enforce(enforceNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"warn:AKIAALICE2",
+		"exempt:AKIABOB1",
+		"delete:AKIACAROL1",
+		"deactivate:AKIADAVE1",
+	}
+	if have := actionKeys(actions); !reflect.DeepEqual(have, want) {
+		t.Fatalf("Enforce() = %v, want %v", have, want)
+	}
+	if len(m.Deactivated) != 0 || len(m.Deleted) != 0 {
+		t.Errorf("dry run changed keys: deactivated %v, deleted %v", m.Deactivated, m.Deleted)
+	}
+
+	var report bytes.Buffer
+	if err := WriteActionReport(&report, actions); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "ACTION") || !strings.Contains(lines[3], "AKIACAROL1") {
+		t.Errorf("WriteActionReport() =\n%s", report.String())
+	}
+}
+
+func TestEnforceValidate(t *testing.T) {
+	e := IAMKeysEnforce{WarnDays: 100, DeactivateDays: 90}
+	if err := e.Validate(); err == nil {
+		t.Error("Validate() with warn days past deactivate days did not return an error")
+	}
+	e = IAMKeysEnforce{WarnDays: 75}
+	if err := e.Validate(); err == nil {
+		t.Error("Validate() with zero deactivate days did not return an error")
+	}
+}
+
+func TestSlackAlertEnforcementMessage(t *testing.T) {
+	s := SlackAlert{Channel: "#ops"}
+	actions := []Action{
+		{Action: ActionWarn, User: "alice", AccessKeyID: "AKIAALICE2", AgeDays: 80, DryRun: true},
+		{Action: ActionDeactivate, User: "bob", AccessKeyID: "AKIABOB1", AgeDays: 100, DryRun: true},
+		{Action: ActionExempt, User: "dave", AccessKeyID: "AKIADAVE1", AgeDays: 300, DryRun: true},
+	}
+
+	fields := s.EnforcementMessage(actions).Attachments[0].Fields
+	want := []string{
+		"Access Keys Past Warning Age=alice (AKIAALICE2, 80 days)",
+		"Deactivated Access Keys (dry run)=bob (AKIABOB1, 100 days)",
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("EnforcementMessage() has %d fields, want %d", len(fields), len(want))
+	}
+	for i, f := range fields {
+		if have := f.Title + "=" + f.Value; have != want[i] {
+			t.Errorf("EnforcementMessage() field %d = %q, want %q", i, have, want[i])
+		}
+	}
+	n := s.EnforcementNotifyMessage(actions)
+	if len(n.Fields) != len(want) || n.Fields[1].Title != "Deactivated Access Keys (dry run)" {
+		t.Errorf("EnforcementNotifyMessage() fields = %v, want the enforcement fields", n.Fields)
+	}
+}
@@ -0,0 +1,104 @@
+package iamkeys
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"go.uber.org/zap"
+)
+
+// Finding is a single credential report problem found by a check.
+type Finding struct {
+	// Account is only set by MultiAccountCheck.
+	Account  *Account `json:"account,omitempty"`
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	User     string   `json:"user"`
+	// AccessKey is the key slot (1 or 2) the finding is about, or 0 for
+	// findings about the user's password or MFA.
+	AccessKey int `json:"access-key,omitempty"`
+	// Since is when the flagged credential was last rotated, changed or
+	// used, depending on the check.
+	Since   time.Time `json:"since,omitempty"`
+	AgeDays int       `json:"age-days,omitempty"`
+}
+
+// IAMKeysCheck defines parameters for checking IAM credential hygiene
+// against the account's credential report.
+type IAMKeysCheck struct {
+	Checks    Checks
+	IAMClient iamiface.IAMAPI
+	Logger    *zap.Logger
+	// PollInterval and MaxPollInterval bound the backoff while waiting for
+	// the credential report to be generated.
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	// Timeout limits how long to wait for the credential report. Zero
+	// waits as long as the context allows.
+	Timeout time.Duration
+}
+
+// Check is CheckWithContext with a background context.
+func (c *IAMKeysCheck) Check() ([]Finding, error) {
+	return c.CheckWithContext(context.Background())
+}
+
+// CheckWithContext downloads the credential report and returns the findings
+// of every enabled check.
+func (c *IAMKeysCheck) CheckWithContext(ctx context.Context) ([]Finding, error) {
+	if err := c.Checks.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	backoff := Backoff{Interval: c.PollInterval, MaxInterval: c.MaxPollInterval}
+	output, err := GetCredentialReport(ctx, c.IAMClient, backoff)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := ParseCredentialReport(output.Content, aws.TimeValue(output.GeneratedTime))
+	if err != nil {
+		return nil, err
+	}
+
+	findings := c.Checks.Evaluate(report)
+	c.Logger.Info("checked credential report",
+		zap.Int("users", len(report.Users)),
+		zap.Int("findings", len(findings)),
+	)
+	return findings, nil
+}
+
+// FilterFindings returns the findings produced by the named check.
+func FilterFindings(findings []Finding, check string) []Finding {
+	var filtered []Finding
+	for _, f := range findings {
+		if f.Check == check {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+// Users returns the sorted, de-duplicated user names in findings.
+func Users(findings []Finding) []string {
+	seen := map[string]bool{}
+	var users []string
+	for _, f := range findings {
+		if !seen[f.User] {
+			seen[f.User] = true
+			users = append(users, f.User)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
@@ -0,0 +1,136 @@
+package iamkeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"go.uber.org/zap"
+)
+
+var logger, _ = zap.NewProduction()
+
+// mockIAMClient returns the GetCredentialReport errors in Errors one at a
+// time before returning Content. GenerateCredentialReport likewise returns
+// GenerateErrors first, then GenerateState.
+type mockIAMClient struct {
+	iamiface.IAMAPI
+	Content        []byte
+	Errors         []error
+	GenerateErrors []error
+	GenerateState  string
+	Generated      int
+	Fetched        int
+}
+
+func (m *mockIAMClient) GetCredentialReportWithContext(ctx aws.Context, input *iam.GetCredentialReportInput, opts ...request.Option) (*iam.GetCredentialReportOutput, error) {
+	m.Fetched++
+	if len(m.Errors) > 0 {
+		err := m.Errors[0]
+		m.Errors = m.Errors[1:]
+		return nil, err
+	}
+	return &iam.GetCredentialReportOutput{
+		Content:       m.Content,
+		GeneratedTime: aws.Time(generatedTime),
+		ReportFormat:  aws.String(iam.ReportFormatTypeTextCsv),
+	}, nil
+}
+
+func (m *mockIAMClient) GenerateCredentialReportWithContext(ctx aws.Context, input *iam.GenerateCredentialReportInput, opts ...request.Option) (*iam.GenerateCredentialReportOutput, error) {
+	m.Generated++
+	if len(m.GenerateErrors) > 0 {
+		err := m.GenerateErrors[0]
+		m.GenerateErrors = m.GenerateErrors[1:]
+		return nil, err
+	}
+	state := m.GenerateState
+	if state == "" {
+		state = iam.ReportStateTypeStarted
+	}
+	return &iam.GenerateCredentialReportOutput{State: aws.String(state)}, nil
+}
+
+func TestCheck(t *testing.T) {
+	m := &mockIAMClient{
+		Content: readFixture(t, "credential_report.csv"),
+		Errors: []error{
+			awserr.New(iam.ErrCodeCredentialReportNotPresentException, "not present", nil),
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+		},
+	}
+	c := IAMKeysCheck{
+		Checks:       Checks{AccessKeyAge: CheckConfig{Enabled: true, MaxDays: 90, Severity: SeverityWarning}},
+		IAMClient:    m,
+		Logger:       logger,
+		PollInterval: time.Millisecond,
+		Timeout:      time.Second,
+	}
+
+	findings, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Generated != 1 {
+		t.Errorf("GenerateCredentialReport called %d times, want 1", m.Generated)
+	}
+
+	want := []string{"bob:1", "carol:2"}
+	var have []string
+	for _, f := range findings {
+		have = append(have, fmt.Sprintf("%s:%d", f.User, f.AccessKey))
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("Check() findings = %v, want %v", have, want)
+	}
+	if !reflect.DeepEqual(Users(findings), []string{"bob", "carol"}) {
+		t.Fatalf("Users(findings) = %v, want [bob carol]", Users(findings))
+	}
+}
+
+func TestFindingJSONOmitsAccount(t *testing.T) {
+	b, err := json.Marshal(Finding{Check: CheckAccessKeyAge, User: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "account") {
+		t.Errorf("single account finding JSON = %s, want no account", b)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	c := IAMKeysCheck{
+		IAMClient: &mockIAMClient{},
+		Logger:    logger,
+		Checks:    Checks{AccessKeyAge: CheckConfig{Enabled: true}},
+	}
+	if _, err := c.Check(); err == nil {
+		t.Error("Check() with MaxDays 0 did not return an error")
+	}
+
+	c.Checks.AccessKeyAge.MaxDays = 90
+	c.Timeout = 20 * time.Millisecond
+	c.PollInterval = time.Millisecond
+	c.IAMClient = &mockIAMClient{
+		Errors: []error{
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+			awserr.New(iam.ErrCodeCredentialReportNotReadyException, "not ready", nil),
+		},
+	}
+	c.MaxPollInterval = 10 * time.Millisecond
+	if _, err := c.Check(); err == nil {
+		t.Error("Check() that timed out did not return an error")
+	}
+}
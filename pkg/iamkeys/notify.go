@@ -0,0 +1,107 @@
+package iamkeys
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+)
+
+// notifySeverity converts a check severity to a notification severity.
+func notifySeverity(s Severity) notify.Severity {
+	switch s {
+	case SeverityCritical:
+		return notify.SeverityCritical
+	case SeverityWarning:
+		return notify.SeverityWarning
+	}
+	return notify.SeverityInfo
+}
+
+// NotifyMessage builds the neutral notification for findings, with the same
+// content as the Slack alert.
+func (s *SlackAlert) NotifyMessage(findings []Finding, checks Checks) *notify.Message {
+	data := s.NewMessageData(findings, checks)
+	m := &notify.Message{
+		Source:   "iam-keys-check",
+		Title:    data.Title,
+		Text:     data.Text,
+		Link:     data.ConsoleURL,
+		Color:    data.Color,
+		Footer:   "IAM Keys Check",
+		DedupKey: "iam-keys-check",
+	}
+
+	worst := SeverityInfo
+	for _, g := range data.Groups {
+		m.Fields = append(m.Fields, notify.Field{Title: g.Title, Value: strings.Join(g.Users, ", ")})
+		if g.Severity.rank() > worst.rank() {
+			worst = g.Severity
+		}
+	}
+	m.Severity = notifySeverity(worst)
+	if s.DocumentationURL != "" {
+		m.Fields = append(m.Fields, notify.Field{Title: "Access Key Rotation Instructions", Value: s.DocumentationURL})
+	}
+	return m
+}
+
+// AccountsNotifyMessage builds the neutral notification for the reports of
+// MultiAccountCheck, with one field per account that has findings or could
+// not be checked. It returns nil when there is nothing to report.
+func (s *SlackAlert) AccountsNotifyMessage(reports []AccountReport, checks Checks) *notify.Message {
+	m := &notify.Message{
+		Source:   "iam-keys-check",
+		Title:    "IAM Credential Report Findings",
+		Text:     "IAM users failing credential hygiene checks, by account",
+		Link:     s.consoleURL(),
+		Footer:   "IAM Keys Check",
+		DedupKey: "iam-keys-check",
+	}
+
+	worst := SeverityInfo
+	for _, r := range reports {
+		accountTitle := fmt.Sprintf("%s (%s)", r.Account.Name(), r.Account.ID)
+		if r.Err != nil {
+			m.Fields = append(m.Fields, notify.Field{Title: accountTitle + ": check failed", Value: r.Err.Error()})
+			worst = SeverityCritical
+			continue
+		}
+		var lines []string
+		for _, g := range s.NewMessageData(r.Findings, checks).Groups {
+			lines = append(lines, fmt.Sprintf("%s: %s", g.Title, strings.Join(g.Users, ", ")))
+			if g.Severity.rank() > worst.rank() {
+				worst = g.Severity
+			}
+		}
+		if len(lines) > 0 {
+			m.Fields = append(m.Fields, notify.Field{Title: accountTitle, Value: strings.Join(lines, "\n")})
+		}
+	}
+	if len(m.Fields) == 0 {
+		return nil
+	}
+
+	m.Severity = notifySeverity(worst)
+	if s.DocumentationURL != "" {
+		m.Fields = append(m.Fields, notify.Field{Title: "Access Key Rotation Instructions", Value: s.DocumentationURL})
+	}
+	return m
+}
+
+// EnforcementNotifyMessage builds the neutral notification for enforcement
+// actions, with the same fields as the Slack enforcement alert.
+func (s *SlackAlert) EnforcementNotifyMessage(actions []Action) *notify.Message {
+	m := &notify.Message{
+		Source:   "iam-keys-check",
+		Title:    "IAM Access Key Enforcement",
+		Link:     s.consoleURL(),
+		Severity: notify.SeverityWarning,
+		Footer:   "IAM Keys Check",
+		DedupKey: "iam-keys-check-enforcement",
+	}
+	for _, f := range s.EnforcementMessage(actions).Attachments[0].Fields {
+		m.Fields = append(m.Fields, notify.Field{Title: f.Title, Value: f.Value})
+	}
+	return m
+}
@@ -0,0 +1,177 @@
+package iamkeys
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/trussworks/truss-aws-tools/pkg/slackweb"
+	"go.uber.org/zap"
+)
+
+// Default IAM user tag keys naming who owns a user.
+const (
+	DefaultEmailTagKey = "email"
+	DefaultSlackTagKey = "slack"
+)
+
+// Owner is who should hear about a user's findings, read from its tags.
+type Owner struct {
+	Email string
+	Slack string
+}
+
+// OwnerNotify sends each IAM user's owner the findings for that user,
+// privately: by email and as a Slack direct message. The owner is read from
+// the user's email and slack tags; users without either tag are skipped.
+type OwnerNotify struct {
+	// DocumentationURL is linked from the messages when set.
+	DocumentationURL string
+	EmailTagKey      string
+	IAMClient        iamiface.IAMAPI
+	Logger           *zap.Logger
+	// SESClient and Sender are used to email owners. Email is skipped when
+	// Sender is empty.
+	SESClient sesiface.SESAPI
+	Sender    string
+	// SlackClient sends owners direct messages from a bot. Slack is
+	// skipped when it is nil.
+	SlackClient *slackweb.Client
+	SlackEmoji  string
+	SlackTagKey string
+}
+
+// GetOwner reads the owner tags of an IAM user.
+func (n *OwnerNotify) GetOwner(userName string) (Owner, error) {
+	var owner Owner
+	output, err := n.IAMClient.ListUserTags(&iam.ListUserTagsInput{
+		UserName: aws.String(userName),
+	})
+	if err != nil {
+		return owner, fmt.Errorf("failed to list tags for %s: %w", userName, err)
+	}
+	for _, t := range output.Tags {
+		switch aws.StringValue(t.Key) {
+		case n.EmailTagKey:
+			owner.Email = aws.StringValue(t.Value)
+		case n.SlackTagKey:
+			owner.Slack = aws.StringValue(t.Value)
+		}
+	}
+	return owner, nil
+}
+
+// Notify sends every tagged owner one message covering all of their user's
+// findings. The root user has no tags and is never notified.
+func (n *OwnerNotify) Notify(ctx context.Context, findings []Finding) error {
+	for _, user := range Users(findings) {
+		if user == RootAccountUser {
+			continue
+		}
+		var userFindings []Finding
+		for _, f := range findings {
+			if f.User == user {
+				userFindings = append(userFindings, f)
+			}
+		}
+
+		owner, err := n.GetOwner(user)
+		if err != nil {
+			return err
+		}
+		if owner.Slack == "" && owner.Email == "" {
+			n.Logger.Info("no owner tags on user, skipping notification", zap.String("user", user))
+			continue
+		}
+
+		if owner.Slack != "" && n.SlackClient != nil {
+			_, err = n.SlackClient.PostMessage(ctx, n.SlackMessage(user, owner.Slack, userFindings))
+			if err != nil {
+				return fmt.Errorf("failed to message owner of %s in slack: %w", user, err)
+			}
+			n.Logger.Info("messaged owner in slack", zap.String("user", user), zap.String("slack", owner.Slack))
+		}
+		if owner.Email != "" && n.Sender != "" {
+			err = n.sendEmail(user, owner.Email, userFindings)
+			if err != nil {
+				return fmt.Errorf("failed to email owner of %s: %w", user, err)
+			}
+			n.Logger.Info("emailed owner", zap.String("user", user), zap.String("email", owner.Email))
+		}
+	}
+	return nil
+}
+
+// slackUserID returns the member ID in a slack tag value. The tag may hold
+// a member ID such as U024BE7LH, @U024BE7LH or a mention like <@U024BE7LH>.
+func slackUserID(slack string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(slack, "<"), ">"), "@")
+}
+
+// SlackMessage builds the direct message to the owner of an IAM user
+// listing that user's findings. Posting to a member ID delivers the message
+// in the owner's direct messages with the bot.
+func (n *OwnerNotify) SlackMessage(user, slack string, findings []Finding) *slackweb.Message {
+	text := fmt.Sprintf("The IAM user %s has credentials that need attention:\n%s",
+		user, strings.Join(DescribeFindings(findings), "\n"))
+	if n.DocumentationURL != "" {
+		text += fmt.Sprintf("\nAccess key rotation instructions: %s", n.DocumentationURL)
+	}
+	return &slackweb.Message{
+		Channel:   slackUserID(slack),
+		Text:      text,
+		IconEmoji: n.SlackEmoji,
+	}
+}
+
+func (n *OwnerNotify) sendEmail(user, email string, findings []Finding) error {
+	body := fmt.Sprintf("The IAM user %s has credentials that need attention:\n\n%s\n",
+		user, strings.Join(DescribeFindings(findings), "\n"))
+	if n.DocumentationURL != "" {
+		body += fmt.Sprintf("\nAccess key rotation instructions: %s\n", n.DocumentationURL)
+	}
+
+	_, err := n.SESClient.SendEmail(&ses.SendEmailInput{
+		Destination: &ses.Destination{
+			ToAddresses: []*string{aws.String(email)},
+		},
+		Message: &ses.Message{
+			Subject: &ses.Content{Data: aws.String(fmt.Sprintf("IAM credentials for %s need attention", user))},
+			Body: &ses.Body{
+				Text: &ses.Content{Data: aws.String(body)},
+			},
+		},
+		Source: aws.String(n.Sender),
+	})
+	return err
+}
+
+// DescribeFindings returns a one line, human readable description of each
+// finding.
+func DescribeFindings(findings []Finding) []string {
+	var lines []string
+	for _, f := range findings {
+		var line string
+		switch f.Check {
+		case CheckAccessKeyAge:
+			line = fmt.Sprintf("access key %d was last rotated %s (%d days ago)", f.AccessKey, f.Since.Format("2006-01-02"), f.AgeDays)
+		case CheckAccessKeyUnused:
+			line = fmt.Sprintf("access key %d has not been used since %s (%d days ago)", f.AccessKey, f.Since.Format("2006-01-02"), f.AgeDays)
+		case CheckPasswordAge:
+			line = fmt.Sprintf("console password was last changed %s (%d days ago)", f.Since.Format("2006-01-02"), f.AgeDays)
+		case CheckConsoleWithoutMFA:
+			line = "console access is enabled without MFA"
+		case CheckRootAccessKey:
+			line = fmt.Sprintf("root access key %d is active", f.AccessKey)
+		default:
+			line = f.Check
+		}
+		lines = append(lines, fmt.Sprintf("- %s [%s]", line, f.Severity))
+	}
+	return lines
+}
@@ -0,0 +1,109 @@
+package iamkeys
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/trussworks/truss-aws-tools/pkg/slackweb"
+)
+
+type mockTagsIAMClient struct {
+	iamiface.IAMAPI
+	Tags map[string][]*iam.Tag
+}
+
+func (m *mockTagsIAMClient) ListUserTags(input *iam.ListUserTagsInput) (*iam.ListUserTagsOutput, error) {
+	return &iam.ListUserTagsOutput{Tags: m.Tags[aws.StringValue(input.UserName)]}, nil
+}
+
+type mockSESClient struct {
+	sesiface.SESAPI
+	Sent []*ses.SendEmailInput
+}
+
+func (m *mockSESClient) SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
+	m.Sent = append(m.Sent, input)
+	return &ses.SendEmailOutput{MessageId: aws.String("1")}, nil
+}
+
+func TestOwnerNotify(t *testing.T) {
+	var posted []slackweb.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("called %s, want chat.postMessage", r.URL.Path)
+		}
+		var m slackweb.Message
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		posted = append(posted, m)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	sesClient := &mockSESClient{}
+	n := OwnerNotify{
+		EmailTagKey: DefaultEmailTagKey,
+		IAMClient: &mockTagsIAMClient{Tags: map[string][]*iam.Tag{
+			"bob":   {{Key: aws.String("slack"), Value: aws.String("<@U024BE7LH>")}},
+			"carol": {{Key: aws.String("email"), Value: aws.String("carol@example.com")}},
+		}},
+		Logger:      logger,
+		SESClient:   sesClient,
+		Sender:      "security@example.com",
+		SlackClient: &slackweb.Client{BaseURL: server.URL + "/", Token: "xoxb-test"},
+		SlackTagKey: DefaultSlackTagKey,
+	}
+
+	since := time.Date(2017, 2, 3, 0, 0, 0, 0, time.UTC)
+	findings := []Finding{
+		{Check: CheckRootAccessKey, Severity: SeverityCritical, User: RootAccountUser, AccessKey: 1},
+		{Check: CheckConsoleWithoutMFA, Severity: SeverityWarning, User: "bob"},
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "bob", AccessKey: 1, Since: since, AgeDays: 877},
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "carol", AccessKey: 2, Since: since, AgeDays: 877},
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "dave", AccessKey: 1, Since: since, AgeDays: 877},
+	}
+	if err := n.Notify(context.Background(), findings); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(posted) != 1 {
+		t.Fatalf("posted %d slack messages, want 1", len(posted))
+	}
+	wantText := "The IAM user bob has credentials that need attention:\n" +
+		"- console access is enabled without MFA [warning]\n" +
+		"- access key 1 was last rotated 2017-02-03 (877 days ago) [warning]"
+	if posted[0].Text != wantText || posted[0].Channel != "U024BE7LH" {
+		t.Errorf("slack message = %q to %q, want %q to U024BE7LH", posted[0].Text, posted[0].Channel, wantText)
+	}
+
+	if len(sesClient.Sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sesClient.Sent))
+	}
+	email := sesClient.Sent[0]
+	if to := aws.StringValueSlice(email.Destination.ToAddresses); !reflect.DeepEqual(to, []string{"carol@example.com"}) {
+		t.Errorf("email sent to %v, want [carol@example.com]", to)
+	}
+	if !strings.Contains(aws.StringValue(email.Message.Body.Text.Data), "access key 2 was last rotated 2017-02-03") {
+		t.Errorf("email body = %q", aws.StringValue(email.Message.Body.Text.Data))
+	}
+}
+
+func TestSlackUserID(t *testing.T) {
+	for _, in := range []string{"U024BE7LH", "@U024BE7LH", "<@U024BE7LH>"} {
+		if have := slackUserID(in); have != "U024BE7LH" {
+			t.Errorf("slackUserID(%q) = %q, want U024BE7LH", in, have)
+		}
+	}
+}
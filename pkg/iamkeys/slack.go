@@ -0,0 +1,271 @@
+package iamkeys
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/lytics/slackhook"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
+)
+
+// checkOrder is the order check results are listed in alerts.
+var checkOrder = []string{
+	CheckAccessKeyAge,
+	CheckRootAccessKey,
+	CheckConsoleWithoutMFA,
+	CheckAccessKeyUnused,
+	CheckPasswordAge,
+}
+
+// DefaultTemplate is the alert attachment template used when SlackAlert has
+// no Template. It renders the message iam-keys-check has always sent.
+const DefaultTemplate = `{
+  "title": {{ json .Title }},
+  "title_link": {{ json .ConsoleURL }},
+  "text": {{ json .Text }},
+  "color": {{ json .Color }},
+  "footer": "IAM Keys Check",
+  "fields": [
+    {{- range $i, $g := .Groups }}{{ if $i }},{{ end }}
+    {"title": {{ json $g.Title }}, "value": {{ json (join $g.Users ", ") }}}
+    {{- end }}
+  ]
+}`
+
+var defaultTemplate = slacktemplate.Must("iam-keys-check", DefaultTemplate)
+
+// FindingGroup is the findings of one check, as passed to templates.
+type FindingGroup struct {
+	Check    string
+	Severity Severity
+	// Title is the check title, with the severity appended for every
+	// check but access key age.
+	Title string
+	Users []string
+}
+
+// MessageData is what alert templates are executed with.
+type MessageData struct {
+	Checks     Checks
+	Color      string
+	ConsoleURL string
+	Findings   []Finding
+	Groups     []FindingGroup
+	Text       string
+	Title      string
+}
+
+// SlackAlert defines where and how to post credential findings to Slack.
+type SlackAlert struct {
+	Channel          string
+	DocumentationURL string
+	Emoji            string
+	// Region is the region console links open in.
+	Region string
+	// Template renders the findings attachment. DefaultTemplate is used
+	// when it is nil.
+	Template   *template.Template
+	WebhookURL string
+}
+
+// consoleURL returns the IAM users console page in the alert's region.
+func (s *SlackAlert) consoleURL() string {
+	return slacktemplate.ConsoleURL("iam", s.Region) + "#/users"
+}
+
+// Message builds the Slack message listing the users in findings, with one
+// field per check that found something. A run with only expired access
+// keys produces the same message iam-keys-check always has.
+func (s *SlackAlert) Message(findings []Finding, checks Checks) (*slackhook.Message, error) {
+	attachment, err := s.findingsAttachment(findings, checks)
+	if err != nil {
+		return nil, err
+	}
+	if s.DocumentationURL != "" {
+		attachment.Fields = append(attachment.Fields, slackhook.Field{
+			Title: "Access Key Rotation Instructions",
+			Value: s.DocumentationURL,
+		})
+	}
+
+	message := &slackhook.Message{
+		Channel:   s.Channel,
+		IconEmoji: s.Emoji,
+	}
+	message.AddAttachment(attachment)
+	return message, nil
+}
+
+// AccountsMessage builds a Slack message with one attachment per account
+// that has findings or could not be checked, titled with the account alias.
+func (s *SlackAlert) AccountsMessage(reports []AccountReport, checks Checks) (*slackhook.Message, error) {
+	message := &slackhook.Message{
+		Channel:   s.Channel,
+		IconEmoji: s.Emoji,
+	}
+	for _, r := range reports {
+		accountTitle := fmt.Sprintf("%s (%s)", r.Account.Name(), r.Account.ID)
+		if r.Err != nil {
+			message.AddAttachment(&slackhook.Attachment{
+				Title:  accountTitle + ": check failed",
+				Text:   r.Err.Error(),
+				Color:  "danger",
+				Footer: "IAM Keys Check",
+			})
+			continue
+		}
+		if len(r.Findings) == 0 {
+			continue
+		}
+		attachment, err := s.findingsAttachment(r.Findings, checks)
+		if err != nil {
+			return nil, err
+		}
+		attachment.Title = fmt.Sprintf("%s: %s", accountTitle, attachment.Title)
+		message.AddAttachment(attachment)
+	}
+
+	if s.DocumentationURL != "" && len(message.Attachments) > 0 {
+		message.AddAttachment(&slackhook.Attachment{
+			Fields: []slackhook.Field{{
+				Title: "Access Key Rotation Instructions",
+				Value: s.DocumentationURL,
+			}},
+		})
+	}
+	return message, nil
+}
+
+// NewMessageData summarizes findings by check for templates.
+func (s *SlackAlert) NewMessageData(findings []Finding, checks Checks) MessageData {
+	data := MessageData{
+		Checks:     checks,
+		Color:      "warn",
+		ConsoleURL: s.consoleURL(),
+		Findings:   findings,
+		Text:       fmt.Sprintf("IAM users with access keys older than %d days", checks.AccessKeyAge.MaxDays),
+		Title:      "Expired IAM Access Keys",
+	}
+
+	worst := SeverityInfo
+	for _, check := range checkOrder {
+		checkFindings := FilterFindings(findings, check)
+		if len(checkFindings) == 0 {
+			continue
+		}
+		group := FindingGroup{
+			Check:    check,
+			Severity: checkFindings[0].Severity,
+			Title:    CheckTitle(check),
+			Users:    Users(checkFindings),
+		}
+		if check != CheckAccessKeyAge {
+			group.Title = fmt.Sprintf("%s (%s)", group.Title, group.Severity)
+		}
+		data.Groups = append(data.Groups, group)
+		for _, f := range checkFindings {
+			if f.Severity.rank() > worst.rank() {
+				worst = f.Severity
+			}
+		}
+	}
+
+	if len(FilterFindings(findings, CheckAccessKeyAge)) != len(findings) {
+		data.Title = "IAM Credential Report Findings"
+		data.Text = "IAM users failing credential hygiene checks"
+	}
+	if worst == SeverityCritical {
+		data.Color = "danger"
+	}
+	return data
+}
+
+// findingsAttachment renders the attachment summarizing findings by check.
+func (s *SlackAlert) findingsAttachment(findings []Finding, checks Checks) (*slackhook.Attachment, error) {
+	tmpl := s.Template
+	if tmpl == nil {
+		tmpl = defaultTemplate
+	}
+	return slacktemplate.Execute(tmpl, s.NewMessageData(findings, checks))
+}
+
+// SendAccounts posts the per-account reports to the Slack webhook.
+func (s *SlackAlert) SendAccounts(reports []AccountReport, checks Checks) error {
+	message, err := s.AccountsMessage(reports, checks)
+	if err != nil {
+		return err
+	}
+	slack := slackhook.New(s.WebhookURL)
+	return slack.Send(message)
+}
+
+// Send posts the findings to the Slack webhook.
+func (s *SlackAlert) Send(findings []Finding, checks Checks) error {
+	message, err := s.Message(findings, checks)
+	if err != nil {
+		return err
+	}
+	slack := slackhook.New(s.WebhookURL)
+	return slack.Send(message)
+}
+
+// enforcementFields are the enforcement actions listed in alerts, in order,
+// with their field titles.
+var enforcementFields = []struct {
+	action string
+	title  string
+}{
+	{ActionWarn, "Access Keys Past Warning Age"},
+	{ActionDeactivate, "Deactivated Access Keys"},
+	{ActionDelete, "Deleted Access Keys"},
+}
+
+// EnforcementMessage builds the Slack message naming each access key that
+// was warned about, deactivated or deleted. Exempt keys are left out.
+func (s *SlackAlert) EnforcementMessage(actions []Action) *slackhook.Message {
+	attachment := slackhook.Attachment{
+		Title:     "IAM Access Key Enforcement",
+		TitleLink: s.consoleURL(),
+		Color:     "warn",
+		Footer:    "IAM Keys Check",
+	}
+
+	for _, f := range enforcementFields {
+		var keys []string
+		for _, a := range FilterActions(actions, f.action) {
+			keys = append(keys, fmt.Sprintf("%s (%s, %d days)", a.User, a.AccessKeyID, a.AgeDays))
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		title := f.title
+		if actions[0].DryRun && f.action != ActionWarn {
+			title += " (dry run)"
+		}
+		attachment.Fields = append(attachment.Fields, slackhook.Field{
+			Title: title,
+			Value: strings.Join(keys, "\n"),
+		})
+	}
+
+	if s.DocumentationURL != "" {
+		attachment.Fields = append(attachment.Fields, slackhook.Field{
+			Title: "Access Key Rotation Instructions",
+			Value: s.DocumentationURL,
+		})
+	}
+
+	message := &slackhook.Message{
+		Channel:   s.Channel,
+		IconEmoji: s.Emoji,
+	}
+	message.AddAttachment(&attachment)
+	return message
+}
+
+// SendEnforcement posts the enforcement actions to the Slack webhook.
+func (s *SlackAlert) SendEnforcement(actions []Action) error {
+	slack := slackhook.New(s.WebhookURL)
+	return slack.Send(s.EnforcementMessage(actions))
+}
@@ -0,0 +1,160 @@
+package iamkeys
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lytics/slackhook"
+	"github.com/trussworks/truss-aws-tools/pkg/notify"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
+)
+
+func TestSlackAlertMessage(t *testing.T) {
+	s := SlackAlert{
+		Channel:          "#ops",
+		DocumentationURL: "https://example.com/rotate",
+		Emoji:            ":key:",
+	}
+	findings := []Finding{
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "carol", AccessKey: 2},
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "bob", AccessKey: 1},
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "bob", AccessKey: 2},
+	}
+	checks := Checks{AccessKeyAge: CheckConfig{Enabled: true, MaxDays: 90}}
+
+	message, err := s.Message(findings, checks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Channel != "#ops" || message.IconEmoji != ":key:" {
+		t.Fatalf("Message() channel/emoji = %q/%q, want #ops/:key:", message.Channel, message.IconEmoji)
+	}
+	if len(message.Attachments) != 1 {
+		t.Fatalf("Message() has %d attachments, want 1", len(message.Attachments))
+	}
+	attachment := message.Attachments[0]
+	if attachment.Title != "Expired IAM Access Keys" || attachment.Color != "warn" {
+		t.Errorf("Message() title/color = %q/%q", attachment.Title, attachment.Color)
+	}
+	if attachment.Text != "IAM users with access keys older than 90 days" {
+		t.Errorf("Message() text = %q", attachment.Text)
+	}
+	if len(attachment.Fields) != 2 {
+		t.Fatalf("Message() has %d fields, want 2", len(attachment.Fields))
+	}
+	if attachment.Fields[0].Value != "bob, carol" {
+		t.Errorf("Message() users = %q, want %q", attachment.Fields[0].Value, "bob, carol")
+	}
+	if attachment.Fields[1].Value != "https://example.com/rotate" {
+		t.Errorf("Message() documentation = %q", attachment.Fields[1].Value)
+	}
+}
+
+func TestSlackAlertMessageChecks(t *testing.T) {
+	s := SlackAlert{Channel: "#ops"}
+	findings := []Finding{
+		{Check: CheckConsoleWithoutMFA, Severity: SeverityWarning, User: "bob"},
+		{Check: CheckRootAccessKey, Severity: SeverityCritical, User: RootAccountUser, AccessKey: 1},
+	}
+
+	message, err := s.Message(findings, Checks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachment := message.Attachments[0]
+	if attachment.Title != "IAM Credential Report Findings" {
+		t.Errorf("Message() title = %q", attachment.Title)
+	}
+	if attachment.Color != "danger" {
+		t.Errorf("Message() color = %q, want danger", attachment.Color)
+	}
+	want := []string{
+		"Root Account Access Keys (critical)=<root_account>",
+		"Console Users Without MFA (warning)=bob",
+	}
+	if len(attachment.Fields) != len(want) {
+		t.Fatalf("Message() has %d fields, want %d", len(attachment.Fields), len(want))
+	}
+	for i, f := range attachment.Fields {
+		if have := f.Title + "=" + f.Value; have != want[i] {
+			t.Errorf("Message() field %d = %q, want %q", i, have, want[i])
+		}
+	}
+}
+
+func TestSlackAlertDefaultTemplate(t *testing.T) {
+	s := SlackAlert{}
+	findings := []Finding{
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "carol", AccessKey: 2},
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "bob", AccessKey: 1},
+	}
+	message, err := s.Message(findings, Checks{AccessKeyAge: CheckConfig{Enabled: true, MaxDays: 90}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &slackhook.Attachment{
+		Title:     "Expired IAM Access Keys",
+		Text:      "IAM users with access keys older than 90 days",
+		TitleLink: "https://console.aws.amazon.com/iam/home?region=us-west-2#/users",
+		Color:     "warn",
+		Footer:    "IAM Keys Check",
+		Fields:    []slackhook.Field{{Title: "IAM Users", Value: "bob, carol"}},
+	}
+	if !reflect.DeepEqual(message.Attachments[0], want) {
+		t.Errorf("Message() = %+v, want %+v", message.Attachments[0], want)
+	}
+}
+
+func TestSlackAlertTemplate(t *testing.T) {
+	tmpl, err := slacktemplate.Parse("custom", `{
+  "title": "{{ len .Findings }} findings",
+  "title_link": {{ json .ConsoleURL }},
+  "fields": [{"title": "Who", "value": {{ json (join (index .Groups 0).Users " & ") }}}]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := SlackAlert{Region: "eu-west-1", Template: tmpl}
+	findings := []Finding{
+		{Check: CheckAccessKeyAge, User: "bob"},
+		{Check: CheckAccessKeyAge, User: "carol"},
+	}
+
+	message, err := s.Message(findings, Checks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachment := message.Attachments[0]
+	if attachment.Title != "2 findings" || attachment.Fields[0].Value != "bob & carol" {
+		t.Errorf("Message() = %+v", attachment)
+	}
+	if !strings.Contains(attachment.TitleLink, "region=eu-west-1") {
+		t.Errorf("Message() link = %q, want region eu-west-1", attachment.TitleLink)
+	}
+}
+
+func TestSlackAlertNotifyMessage(t *testing.T) {
+	s := SlackAlert{DocumentationURL: "https://example.com/rotate"}
+	findings := []Finding{
+		{Check: CheckAccessKeyAge, Severity: SeverityWarning, User: "bob", AccessKey: 1},
+		{Check: CheckRootAccessKey, Severity: SeverityCritical, User: RootAccountUser, AccessKey: 1},
+	}
+
+	m := s.NotifyMessage(findings, Checks{AccessKeyAge: CheckConfig{Enabled: true, MaxDays: 90}})
+	if m.Severity != notify.SeverityCritical || m.Color != "danger" {
+		t.Errorf("NotifyMessage() severity/color = %s/%s, want critical/danger", m.Severity, m.Color)
+	}
+	if m.Title != "IAM Credential Report Findings" || m.Source != "iam-keys-check" {
+		t.Errorf("NotifyMessage() title/source = %q/%q", m.Title, m.Source)
+	}
+	want := []notify.Field{
+		{Title: "IAM Users", Value: "bob"},
+		{Title: "Root Account Access Keys (critical)", Value: RootAccountUser},
+		{Title: "Access Key Rotation Instructions", Value: "https://example.com/rotate"},
+	}
+	if !reflect.DeepEqual(m.Fields, want) {
+		t.Errorf("NotifyMessage() fields = %v, want %v", m.Fields, want)
+	}
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// snsSubjectLimit is the longest subject SNS accepts.
+const snsSubjectLimit = 100
+
+// SNS publishes messages as plain text to an SNS topic.
+type SNS struct {
+	SNSClient snsiface.SNSAPI
+	TopicArn  string
+}
+
+// Notify implements Notifier.
+func (s *SNS) Notify(ctx context.Context, m *Message) error {
+	subject := m.Title
+	if len(subject) > snsSubjectLimit {
+		subject = subject[:snsSubjectLimit]
+	}
+	_, err := s.SNSClient.PublishWithContext(ctx, &sns.PublishInput{
+		Message:  aws.String(m.PlainText()),
+		Subject:  aws.String(subject),
+		TopicArn: aws.String(s.TopicArn),
+	})
+	return err
+}
+
+// SES emails messages as plain text.
+type SES struct {
+	Recipients []string
+	Sender     string
+	SESClient  sesiface.SESAPI
+}
+
+// Notify implements Notifier.
+func (s *SES) Notify(ctx context.Context, m *Message) error {
+	_, err := s.SESClient.SendEmailWithContext(ctx, &ses.SendEmailInput{
+		Destination: &ses.Destination{
+			ToAddresses: aws.StringSlice(s.Recipients),
+		},
+		Message: &ses.Message{
+			Subject: &ses.Content{Data: aws.String(m.Title)},
+			Body: &ses.Body{
+				Text: &ses.Content{Data: aws.String(m.PlainText())},
+			},
+		},
+		Source: aws.String(s.Sender),
+	})
+	return err
+}
@@ -0,0 +1,237 @@
+// Package notify sends a tool-neutral notification message to one or more
+// sinks: Slack and Microsoft Teams webhooks, PagerDuty Events v2, generic
+// JSON webhooks, SNS topics and SES email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/trussworks/truss-aws-tools/internal/aws/ssm"
+)
+
+// Severity is how urgent a message is.
+type Severity string
+
+// Message severities.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Field is a titled value shown with a message.
+type Field struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
+}
+
+// Message is the neutral notification every sink renders.
+type Message struct {
+	// Source names the tool sending the message.
+	Source   string   `json:"source"`
+	Title    string   `json:"title"`
+	Text     string   `json:"text,omitempty"`
+	Link     string   `json:"link,omitempty"`
+	Severity Severity `json:"severity"`
+	// Color overrides the severity color in sinks that have colors.
+	Color  string  `json:"color,omitempty"`
+	Fields []Field `json:"fields,omitempty"`
+	Footer string  `json:"footer,omitempty"`
+	// Mentions are prepended to chat messages.
+	Mentions []string `json:"mentions,omitempty"`
+	// DedupKey groups messages about the same thing, such as an event ARN.
+	DedupKey string `json:"dedup_key,omitempty"`
+}
+
+// PlainText renders the message as plain text for email and SNS.
+func (m *Message) PlainText() string {
+	var b strings.Builder
+	b.WriteString(m.Title + "\n")
+	if m.Text != "" {
+		b.WriteString("\n" + m.Text + "\n")
+	}
+	if len(m.Fields) > 0 {
+		b.WriteString("\n")
+		for _, f := range m.Fields {
+			fmt.Fprintf(&b, "%s: %s\n", f.Title, f.Value)
+		}
+	}
+	if m.Link != "" {
+		b.WriteString("\n" + m.Link + "\n")
+	}
+	return b.String()
+}
+
+// Notifier sends a message to one sink.
+type Notifier interface {
+	Notify(ctx context.Context, m *Message) error
+}
+
+// Multi sends each message to every notifier. All notifiers are tried even
+// when one fails.
+type Multi []Notifier
+
+// Notify implements Notifier.
+func (n Multi) Notify(ctx context.Context, m *Message) error {
+	var failures []string
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, m); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d notifiers failed: %s", len(failures), len(n), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Sink types for Config.
+const (
+	TypeSlack     = "slack"
+	TypeTeams     = "teams"
+	TypePagerDuty = "pagerduty"
+	TypeWebhook   = "webhook"
+	TypeSNS       = "sns"
+	TypeSES       = "ses"
+)
+
+// Config configures a single sink. Only the fields its Type uses are read.
+type Config struct {
+	Type string `json:"type"`
+	// URL is the webhook URL of slack, teams and webhook sinks, and
+	// overrides the PagerDuty events endpoint.
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers"`
+	Channel    string            `json:"channel"`
+	Emoji      string            `json:"emoji"`
+	RoutingKey string            `json:"routing_key"`
+	TopicArn   string            `json:"topic_arn"`
+	Sender     string            `json:"sender"`
+	Recipients []string          `json:"recipients"`
+}
+
+// ParseConfig parses a JSON list of sink configs.
+func ParseConfig(content []byte) ([]Config, error) {
+	var configs []Config
+	if err := json.Unmarshal(content, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse notifier config: %w", err)
+	}
+	return configs, nil
+}
+
+// SSMPrefix marks a config source read from an SSM parameter.
+const SSMPrefix = "ssm:"
+
+// LoadConfig reads and parses the sink configs at source. A source starting
+// with "ssm:" is read from that SSM parameter; anything else is a file path.
+func LoadConfig(sess *session.Session, source string) ([]Config, error) {
+	var content []byte
+	if strings.HasPrefix(source, SSMPrefix) {
+		value, err := ssm.DecryptValue(sess, strings.TrimPrefix(source, SSMPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notifier config %s: %w", source, err)
+		}
+		content = []byte(value)
+	} else {
+		var err error
+		content, err = os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notifier config %s: %w", source, err)
+		}
+	}
+	return ParseConfig(content)
+}
+
+// New builds the notifier for a config. sess is used by the SNS and SES
+// sinks.
+func New(sess *session.Session, c Config) (Notifier, error) {
+	switch c.Type {
+	case TypeSlack:
+		if c.URL == "" {
+			return nil, errors.New("slack notifier needs a url")
+		}
+		return &SlackWebhook{Channel: c.Channel, Emoji: c.Emoji, URL: c.URL}, nil
+	case TypeTeams:
+		if c.URL == "" {
+			return nil, errors.New("teams notifier needs a url")
+		}
+		return &Teams{URL: c.URL}, nil
+	case TypePagerDuty:
+		if c.RoutingKey == "" {
+			return nil, errors.New("pagerduty notifier needs a routing_key")
+		}
+		return &PagerDuty{RoutingKey: c.RoutingKey, URL: c.URL}, nil
+	case TypeWebhook:
+		if c.URL == "" {
+			return nil, errors.New("webhook notifier needs a url")
+		}
+		return &Webhook{Headers: c.Headers, URL: c.URL}, nil
+	case TypeSNS:
+		if c.TopicArn == "" {
+			return nil, errors.New("sns notifier needs a topic_arn")
+		}
+		return &SNS{SNSClient: sns.New(sess), TopicArn: c.TopicArn}, nil
+	case TypeSES:
+		if c.Sender == "" || len(c.Recipients) == 0 {
+			return nil, errors.New("ses notifier needs a sender and recipients")
+		}
+		return &SES{Recipients: c.Recipients, Sender: c.Sender, SESClient: ses.New(sess)}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", c.Type)
+}
+
+// NewMulti builds a Multi from configs.
+func NewMulti(sess *session.Session, configs []Config) (Multi, error) {
+	var multi Multi
+	for i, c := range configs {
+		n, err := New(sess, c)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %d: %w", i, err)
+		}
+		multi = append(multi, n)
+	}
+	return multi, nil
+}
+
+// postJSON posts body as JSON and fails on any non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned http status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
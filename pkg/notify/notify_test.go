@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+var testMessage = &Message{
+	Source:   "aws-health-notifier",
+	Title:    "AWS Health Notification",
+	Text:     "EC2 is degraded",
+	Link:     "https://phd.aws.amazon.com/phd/home",
+	Severity: SeverityCritical,
+	Fields: []Field{
+		{Title: "Service", Value: "EC2"},
+		{Title: "Region", Value: "us-west-2", Short: true},
+	},
+	Footer:   "tests",
+	Mentions: []string{"<!subteam^S0123>"},
+	DedupKey: "arn:aws:health:us-west-2::event/EC2/1",
+}
+
+// recorder is an httptest server that keeps the last request body.
+type recorder struct {
+	*httptest.Server
+	Body    map[string]interface{}
+	Headers http.Header
+	Status  int
+}
+
+func newRecorder(t *testing.T) *recorder {
+	r := &recorder{Status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Headers = req.Header
+		r.Body = nil
+		if err := json.NewDecoder(req.Body).Decode(&r.Body); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(r.Status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// get returns the value at a path of map keys and slice indexes.
+func (r *recorder) get(path ...interface{}) interface{} {
+	var v interface{} = r.Body
+	for _, p := range path {
+		switch k := p.(type) {
+		case string:
+			v = v.(map[string]interface{})[k]
+		case int:
+			v = v.([]interface{})[k]
+		}
+	}
+	return v
+}
+
+func TestSlackWebhook(t *testing.T) {
+	r := newRecorder(t)
+	s := SlackWebhook{Channel: "#ops", Emoji: ":boom:", URL: r.URL}
+	if err := s.Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string][2]interface{}{
+		"channel":    {r.get("channel"), "#ops"},
+		"text":       {r.get("text"), "<!subteam^S0123>"},
+		"title":      {r.get("attachments", 0, "title"), "AWS Health Notification"},
+		"title_link": {r.get("attachments", 0, "title_link"), testMessage.Link},
+		"color":      {r.get("attachments", 0, "color"), "danger"},
+		"field":      {r.get("attachments", 0, "fields", 1, "value"), "us-west-2"},
+	}
+	for name, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("slack %s = %v, want %v", name, c[0], c[1])
+		}
+	}
+
+	m := *testMessage
+	m.Color = "#123456"
+	if color := s.Payload(&m).Attachments[0].Color; color != "#123456" {
+		t.Errorf("slack color override = %q", color)
+	}
+}
+
+func TestTeams(t *testing.T) {
+	r := newRecorder(t)
+	n := Teams{URL: r.URL}
+	if err := n.Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string][2]interface{}{
+		"@type":      {r.get("@type"), "MessageCard"},
+		"themeColor": {r.get("themeColor"), "A30200"},
+		"title":      {r.get("title"), "AWS Health Notification"},
+		"text":       {r.get("text"), "<!subteam^S0123> EC2 is degraded"},
+		"fact":       {r.get("sections", 0, "facts", 0, "name"), "Service"},
+		"uri":        {r.get("potentialAction", 0, "targets", 0, "uri"), testMessage.Link},
+	}
+	for name, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("teams %s = %v, want %v", name, c[0], c[1])
+		}
+	}
+}
+
+func TestPagerDuty(t *testing.T) {
+	r := newRecorder(t)
+	r.Status = http.StatusAccepted
+	p := PagerDuty{RoutingKey: "R0UT1NGK3Y", URL: r.URL}
+	if err := p.Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string][2]interface{}{
+		"routing_key":  {r.get("routing_key"), "R0UT1NGK3Y"},
+		"event_action": {r.get("event_action"), "trigger"},
+		"dedup_key":    {r.get("dedup_key"), testMessage.DedupKey},
+		"summary":      {r.get("payload", "summary"), "AWS Health Notification"},
+		"source":       {r.get("payload", "source"), "aws-health-notifier"},
+		"severity":     {r.get("payload", "severity"), "critical"},
+		"detail":       {r.get("payload", "custom_details", "Service"), "EC2"},
+		"link":         {r.get("links", 0, "href"), testMessage.Link},
+	}
+	for name, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("pagerduty %s = %v, want %v", name, c[0], c[1])
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	r := newRecorder(t)
+	w := Webhook{URL: r.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	if err := w.Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if r.Headers.Get("Authorization") != "Bearer secret" {
+		t.Errorf("webhook authorization = %q", r.Headers.Get("Authorization"))
+	}
+	if r.get("dedup_key") != testMessage.DedupKey || r.get("fields", 0, "title") != "Service" {
+		t.Errorf("webhook body = %v", r.Body)
+	}
+
+	r.Status = http.StatusInternalServerError
+	if err := w.Notify(context.Background(), testMessage); err == nil {
+		t.Error("Notify() to a failing webhook did not return an error")
+	}
+}
+
+type mockSNSClient struct {
+	snsiface.SNSAPI
+	Published []*sns.PublishInput
+}
+
+func (m *mockSNSClient) PublishWithContext(ctx aws.Context, input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+	m.Published = append(m.Published, input)
+	return &sns.PublishOutput{}, nil
+}
+
+type mockSESClient struct {
+	sesiface.SESAPI
+	Sent []*ses.SendEmailInput
+}
+
+func (m *mockSESClient) SendEmailWithContext(ctx aws.Context, input *ses.SendEmailInput, opts ...request.Option) (*ses.SendEmailOutput, error) {
+	m.Sent = append(m.Sent, input)
+	return &ses.SendEmailOutput{}, nil
+}
+
+func TestSNSAndSES(t *testing.T) {
+	snsClient := &mockSNSClient{}
+	sesClient := &mockSESClient{}
+	n := Multi{
+		&SNS{SNSClient: snsClient, TopicArn: "arn:aws:sns:us-west-2:123456789012:alerts"},
+		&SES{Recipients: []string{"ops@example.com"}, Sender: "alerts@example.com", SESClient: sesClient},
+	}
+	if err := n.Notify(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snsClient.Published) != 1 || aws.StringValue(snsClient.Published[0].Subject) != "AWS Health Notification" {
+		t.Fatalf("sns published %v", snsClient.Published)
+	}
+	body := aws.StringValue(snsClient.Published[0].Message)
+	for _, want := range []string{"EC2 is degraded", "Service: EC2", testMessage.Link} {
+		if !strings.Contains(body, want) {
+			t.Errorf("sns message %q does not contain %q", body, want)
+		}
+	}
+	if len(sesClient.Sent) != 1 || !reflect.DeepEqual(aws.StringValueSlice(sesClient.Sent[0].Destination.ToAddresses), []string{"ops@example.com"}) {
+		t.Fatalf("ses sent %v", sesClient.Sent)
+	}
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(ctx context.Context, m *Message) error {
+	return errors.New("boom")
+}
+
+func TestMulti(t *testing.T) {
+	snsClient := &mockSNSClient{}
+	n := Multi{failingNotifier{}, &SNS{SNSClient: snsClient}}
+	err := n.Notify(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 notifiers failed: boom") {
+		t.Errorf("Notify() error = %v", err)
+	}
+	if len(snsClient.Published) != 1 {
+		t.Error("Multi stopped at the first failing notifier")
+	}
+}
+
+func TestNewMulti(t *testing.T) {
+	configs, err := ParseConfig([]byte(`[
+  {"type": "slack", "url": "https://hooks.slack.com/services/x", "channel": "#ops"},
+  {"type": "teams", "url": "https://example.webhook.office.com/x"},
+  {"type": "pagerduty", "routing_key": "R0UT1NGK3Y"},
+  {"type": "webhook", "url": "https://example.com/hook"}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	multi, err := NewMulti(nil, configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(multi) != 4 {
+		t.Errorf("NewMulti() made %d notifiers, want 4", len(multi))
+	}
+
+	for _, c := range []Config{{Type: "carrier-pigeon"}, {Type: TypeSlack}, {Type: TypeSES, Sender: "a@example.com"}} {
+		if _, err := New(nil, c); err == nil {
+			t.Errorf("New(%+v) did not return an error", c)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifiers.json")
+	err := os.WriteFile(path, []byte(`[{"type": "teams", "url": "https://example.webhook.office.com/x"}]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := LoadConfig(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Config{{Type: TypeTeams, URL: "https://example.webhook.office.com/x"}}; !reflect.DeepEqual(configs, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", configs, want)
+	}
+
+	if _, err := LoadConfig(nil, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadConfig() of a missing file did not return an error")
+	}
+}
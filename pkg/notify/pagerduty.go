@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers PagerDuty alerts through the Events API v2.
+type PagerDuty struct {
+	HTTPClient *http.Client
+	RoutingKey string
+	// URL defaults to PagerDutyEventsURL.
+	URL string
+}
+
+// PagerDutyPayload is the alert details of an event.
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// PagerDutyLink is a link attached to an alert.
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// PagerDutyEvent is an Events API v2 trigger event.
+type PagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key,omitempty"`
+	Payload     PagerDutyPayload `json:"payload"`
+	Links       []PagerDutyLink  `json:"links,omitempty"`
+}
+
+// Payload returns the trigger event for a message.
+func (p *PagerDuty) Payload(m *Message) *PagerDutyEvent {
+	event := &PagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    m.DedupKey,
+		Payload: PagerDutyPayload{
+			Summary:  m.Title,
+			Source:   m.Source,
+			Severity: string(m.Severity),
+		},
+	}
+	if event.Payload.Severity == "" {
+		event.Payload.Severity = string(SeverityInfo)
+	}
+	if m.Text != "" || len(m.Fields) > 0 {
+		event.Payload.CustomDetails = map[string]string{}
+		if m.Text != "" {
+			event.Payload.CustomDetails["text"] = m.Text
+		}
+		for _, f := range m.Fields {
+			event.Payload.CustomDetails[f.Title] = f.Value
+		}
+	}
+	if m.Link != "" {
+		event.Links = []PagerDutyLink{{Href: m.Link, Text: m.Title}}
+	}
+	return event
+}
+
+// Notify implements Notifier.
+func (p *PagerDuty) Notify(ctx context.Context, m *Message) error {
+	url := p.URL
+	if url == "" {
+		url = PagerDutyEventsURL
+	}
+	return postJSON(ctx, p.HTTPClient, url, nil, p.Payload(m))
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/lytics/slackhook"
+)
+
+// slackColors are the attachment colors for each severity.
+var slackColors = map[Severity]string{
+	SeverityInfo:     "good",
+	SeverityWarning:  "warning",
+	SeverityCritical: "danger",
+}
+
+// SlackWebhook posts messages to a Slack incoming webhook as an attachment.
+type SlackWebhook struct {
+	Channel    string
+	Emoji      string
+	HTTPClient *http.Client
+	URL        string
+}
+
+// Payload returns the webhook payload for a message.
+func (s *SlackWebhook) Payload(m *Message) *slackhook.Message {
+	color := m.Color
+	if color == "" {
+		color = slackColors[m.Severity]
+	}
+	attachment := &slackhook.Attachment{
+		Title:     m.Title,
+		TitleLink: m.Link,
+		Text:      m.Text,
+		Color:     color,
+		Footer:    m.Footer,
+	}
+	for _, f := range m.Fields {
+		attachment.Fields = append(attachment.Fields, slackhook.Field{Title: f.Title, Value: f.Value, Short: f.Short})
+	}
+
+	payload := &slackhook.Message{
+		Channel:   s.Channel,
+		IconEmoji: s.Emoji,
+		Text:      strings.Join(m.Mentions, " "),
+	}
+	payload.AddAttachment(attachment)
+	return payload
+}
+
+// Notify implements Notifier.
+func (s *SlackWebhook) Notify(ctx context.Context, m *Message) error {
+	return postJSON(ctx, s.HTTPClient, s.URL, nil, s.Payload(m))
+}
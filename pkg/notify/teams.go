@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// teamsColors are the MessageCard theme colors for each severity.
+var teamsColors = map[Severity]string{
+	SeverityInfo:     "439FE0",
+	SeverityWarning:  "DAA038",
+	SeverityCritical: "A30200",
+}
+
+// Teams posts messages to a Microsoft Teams incoming webhook as a
+// MessageCard.
+type Teams struct {
+	HTTPClient *http.Client
+	URL        string
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+// TeamsCard is a legacy actionable MessageCard.
+type TeamsCard struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	Summary         string         `json:"summary"`
+	ThemeColor      string         `json:"themeColor,omitempty"`
+	Title           string         `json:"title"`
+	Text            string         `json:"text,omitempty"`
+	Sections        []teamsSection `json:"sections,omitempty"`
+	PotentialAction []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+// Payload returns the MessageCard for a message.
+func (t *Teams) Payload(m *Message) *TeamsCard {
+	color := strings.TrimPrefix(m.Color, "#")
+	if color == "" || slackColorNames[color] {
+		color = teamsColors[m.Severity]
+	}
+	text := m.Text
+	if len(m.Mentions) > 0 {
+		text = strings.TrimSpace(strings.Join(m.Mentions, " ") + " " + text)
+	}
+
+	card := &TeamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    m.Title,
+		ThemeColor: color,
+		Title:      m.Title,
+		Text:       text,
+	}
+	if len(m.Fields) > 0 {
+		var section teamsSection
+		for _, f := range m.Fields {
+			section.Facts = append(section.Facts, teamsFact{Name: f.Title, Value: f.Value})
+		}
+		card.Sections = []teamsSection{section}
+	}
+	if m.Link != "" {
+		card.PotentialAction = []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "Open",
+			Targets: []teamsTarget{{OS: "default", URI: m.Link}},
+		}}
+	}
+	return card
+}
+
+// slackColorNames are Slack color names that aren't valid Teams colors.
+var slackColorNames = map[string]bool{"good": true, "warning": true, "danger": true, "warn": true}
+
+// Notify implements Notifier.
+func (t *Teams) Notify(ctx context.Context, m *Message) error {
+	return postJSON(ctx, t.HTTPClient, t.URL, nil, t.Payload(m))
+}
@@ -0,0 +1,19 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// Webhook posts the Message itself as JSON to any URL.
+type Webhook struct {
+	// Headers are added to every request, for example for authorization.
+	Headers    map[string]string
+	HTTPClient *http.Client
+	URL        string
+}
+
+// Notify implements Notifier.
+func (w *Webhook) Notify(ctx context.Context, m *Message) error {
+	return postJSON(ctx, w.HTTPClient, w.URL, w.Headers, m)
+}
@@ -0,0 +1,360 @@
+// Package quotamonitor alerts on AWS quotas close to their limits, reading
+// usage from the Trusted Advisor service limits checks and from Service
+// Quotas with CloudWatch usage metrics.
+package quotamonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
+	"github.com/trussworks/truss-aws-tools/pkg/tarefresh"
+	"go.uber.org/zap"
+)
+
+// Sources of quota usage.
+const (
+	SourceTrustedAdvisor = "trusted-advisor"
+	SourceServiceQuotas  = "service-quotas"
+)
+
+// DefaultThreshold is the percentage of a quota in use that alerts.
+const DefaultThreshold = 80
+
+// TrustedAdvisorFloor is the lowest percentage of a limit in use that the
+// Trusted Advisor service limits checks flag. Quotas only Trusted Advisor
+// covers are not seen below it, whatever the threshold.
+const TrustedAdvisorFloor = 80
+
+// usageLookback is how far back CloudWatch usage metrics are read.
+const usageLookback = time.Hour
+
+// usagePeriod is the period of AWS/Usage metrics, which are published every
+// minute.
+const usagePeriod = time.Minute
+
+// errCodeSubscriptionRequired is returned by the Support API to accounts
+// without a Business or Enterprise support plan. The SDK has no constant
+// for it.
+const errCodeSubscriptionRequired = "SubscriptionRequiredException"
+
+// Usage is how much of a quota is in use.
+type Usage struct {
+	Source    string  `json:"source"`
+	Service   string  `json:"service"`
+	QuotaName string  `json:"quota_name"`
+	QuotaCode string  `json:"quota_code,omitempty"`
+	Region    string  `json:"region"`
+	Limit     float64 `json:"limit"`
+	Used      float64 `json:"used"`
+}
+
+// Percent returns the percentage of the quota in use.
+func (u Usage) Percent() float64 {
+	if u.Limit == 0 {
+		return 0
+	}
+	return u.Used / u.Limit * 100
+}
+
+// key identifies a quota within a source. Trusted Advisor and Service
+// Quotas name services and quotas differently, so a quota both report is
+// listed once for each.
+func (u Usage) key() string {
+	return strings.ToLower(strings.Join([]string{u.Source, u.Service, u.QuotaName, u.Region}, "|"))
+}
+
+// QuotaMonitor reads quota usage from the Trusted Advisor service limits
+// checks and from Service Quotas with CloudWatch usage metrics.
+type QuotaMonitor struct {
+	CloudWatchClient    cloudwatchiface.CloudWatchAPI
+	Logger              *zap.Logger
+	ServiceQuotasClient servicequotasiface.ServiceQuotasAPI
+	// ServiceCodes are the Service Quotas services checked, such as ec2.
+	ServiceCodes []string
+	// SupportClient reads the Trusted Advisor service limits checks. They
+	// are skipped when it is nil or the account has no support plan.
+	SupportClient supportiface.SupportAPI
+	// Threshold is the percentage of a quota in use that alerts, or
+	// DefaultThreshold when it is 0.
+	Threshold float64
+	// Region is the region the Service Quotas client reads.
+	Region string
+}
+
+// Check returns the quotas at or over the threshold, most used first. A
+// quota a source reports more than once under the same service, name and
+// region is listed once, with the higher usage.
+func (m *QuotaMonitor) Check(ctx context.Context, now time.Time) ([]Usage, error) {
+	var usages []Usage
+	if m.SupportClient != nil {
+		if m.threshold() < TrustedAdvisorFloor {
+			m.Logger.Warn("trusted advisor only flags service limits at the floor or above, quotas only it covers are missed below it",
+				zap.Float64("threshold", m.threshold()),
+				zap.Float64("floor", TrustedAdvisorFloor),
+			)
+		}
+		taUsages, err := m.TrustedAdvisorUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, taUsages...)
+	}
+	sqUsages, err := m.ServiceQuotasUsage(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	usages = append(usages, sqUsages...)
+	return OverThreshold(usages, m.threshold()), nil
+}
+
+func (m *QuotaMonitor) threshold() float64 {
+	if m.Threshold == 0 {
+		return DefaultThreshold
+	}
+	return m.Threshold
+}
+
+// OverThreshold returns the usages at or over threshold percent, one per
+// quota and source, most used first.
+func OverThreshold(usages []Usage, threshold float64) []Usage {
+	byKey := map[string]Usage{}
+	var keys []string
+	for _, u := range usages {
+		if u.Percent() < threshold {
+			continue
+		}
+		existing, ok := byKey[u.key()]
+		if !ok {
+			keys = append(keys, u.key())
+		}
+		if !ok || u.Percent() > existing.Percent() {
+			byKey[u.key()] = u
+		}
+	}
+
+	var over []Usage
+	for _, k := range keys {
+		over = append(over, byKey[k])
+	}
+	sort.SliceStable(over, func(i, j int) bool {
+		return over[i].Percent() > over[j].Percent()
+	})
+	return over
+}
+
+// TrustedAdvisorUsage reads the usage of every quota in the Trusted Advisor
+// service limits checks.
+func (m *QuotaMonitor) TrustedAdvisorUsage(ctx context.Context) ([]Usage, error) {
+	output, err := m.SupportClient.DescribeTrustedAdvisorChecksWithContext(ctx, &support.DescribeTrustedAdvisorChecksInput{
+		Language: aws.String(tarefresh.DefaultLanguage),
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == errCodeSubscriptionRequired {
+		m.Logger.Warn("skipping trusted advisor service limits, the account has no support plan")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trusted advisor checks: %w", err)
+	}
+
+	filter := tarefresh.Filter{IncludeCategories: []string{tarefresh.CategoryServiceLimits}}
+	var usages []Usage
+	for _, check := range filter.Checks(output.Checks) {
+		result, err := m.SupportClient.DescribeTrustedAdvisorCheckResultWithContext(ctx, &support.DescribeTrustedAdvisorCheckResultInput{
+			CheckId:  check.Id,
+			Language: aws.String(tarefresh.DefaultLanguage),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get result of %s: %w", aws.StringValue(check.Name), err)
+		}
+		if result.Result == nil {
+			continue
+		}
+		usages = append(usages, serviceLimitUsages(check, result.Result)...)
+	}
+	return usages, nil
+}
+
+// serviceLimitUsages reads the flagged resources of a service limits check.
+// Each resource's metadata is laid out as the check's metadata headings,
+// such as Region, Service, Limit Name, Limit Amount and Current Usage.
+func serviceLimitUsages(check *support.TrustedAdvisorCheckDescription, result *support.TrustedAdvisorCheckResult) []Usage {
+	columns := map[string]int{}
+	for i, heading := range check.Metadata {
+		columns[aws.StringValue(heading)] = i
+	}
+	column := func(metadata []*string, heading string) string {
+		i, ok := columns[heading]
+		if !ok || i >= len(metadata) {
+			return ""
+		}
+		return aws.StringValue(metadata[i])
+	}
+
+	var usages []Usage
+	for _, r := range result.FlaggedResources {
+		limit, err := parseAmount(column(r.Metadata, "Limit Amount"))
+		if err != nil {
+			continue
+		}
+		used, err := parseAmount(column(r.Metadata, "Current Usage"))
+		if err != nil {
+			continue
+		}
+		usages = append(usages, Usage{
+			Source:    SourceTrustedAdvisor,
+			Service:   column(r.Metadata, "Service"),
+			QuotaName: column(r.Metadata, "Limit Name"),
+			Region:    column(r.Metadata, "Region"),
+			Limit:     limit,
+			Used:      used,
+		})
+	}
+	return usages
+}
+
+// parseAmount parses a Trusted Advisor amount such as "1,280".
+func parseAmount(amount string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(amount), ",", ""), 64)
+}
+
+// ServiceQuotasUsage reads the usage of every quota of ServiceCodes that has
+// a CloudWatch usage metric, at its peak in the hour before now.
+func (m *QuotaMonitor) ServiceQuotasUsage(ctx context.Context, now time.Time) ([]Usage, error) {
+	var usages []Usage
+	for _, code := range m.ServiceCodes {
+		quotas, err := m.serviceQuotas(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range quotas {
+			used, ok, err := m.metricUsage(ctx, q.UsageMetric, now)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get usage of %s: %w", aws.StringValue(q.QuotaName), err)
+			}
+			if !ok {
+				continue
+			}
+			usages = append(usages, Usage{
+				Source:    SourceServiceQuotas,
+				Service:   aws.StringValue(q.ServiceName),
+				QuotaName: aws.StringValue(q.QuotaName),
+				QuotaCode: aws.StringValue(q.QuotaCode),
+				Region:    m.Region,
+				Limit:     aws.Float64Value(q.Value),
+				Used:      used,
+			})
+		}
+	}
+	return usages, nil
+}
+
+// serviceQuotas returns the quotas of a service that have usage metrics. The
+// applied value is used where there is one, and the default otherwise.
+func (m *QuotaMonitor) serviceQuotas(ctx context.Context, code string) ([]*servicequotas.ServiceQuota, error) {
+	byCode := map[string]*servicequotas.ServiceQuota{}
+	var codes []string
+	add := func(quotas []*servicequotas.ServiceQuota) {
+		for _, q := range quotas {
+			if q.UsageMetric == nil || q.UsageMetric.MetricName == nil || q.Value == nil {
+				continue
+			}
+			if _, ok := byCode[aws.StringValue(q.QuotaCode)]; !ok {
+				codes = append(codes, aws.StringValue(q.QuotaCode))
+			}
+			byCode[aws.StringValue(q.QuotaCode)] = q
+		}
+	}
+
+	err := m.ServiceQuotasClient.ListAWSDefaultServiceQuotasPagesWithContext(ctx, &servicequotas.ListAWSDefaultServiceQuotasInput{
+		ServiceCode: aws.String(code),
+	}, func(page *servicequotas.ListAWSDefaultServiceQuotasOutput, lastPage bool) bool {
+		add(page.Quotas)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list default %s quotas: %w", code, err)
+	}
+	err = m.ServiceQuotasClient.ListServiceQuotasPagesWithContext(ctx, &servicequotas.ListServiceQuotasInput{
+		ServiceCode: aws.String(code),
+	}, func(page *servicequotas.ListServiceQuotasOutput, lastPage bool) bool {
+		add(page.Quotas)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s quotas: %w", code, err)
+	}
+
+	var quotas []*servicequotas.ServiceQuota
+	for _, c := range codes {
+		quotas = append(quotas, byCode[c])
+	}
+	return quotas, nil
+}
+
+// metricUsage returns the highest one minute value of a usage metric in the
+// hour before now, or ok false when the metric has no data.
+func (m *QuotaMonitor) metricUsage(ctx context.Context, metric *servicequotas.MetricInfo, now time.Time) (float64, bool, error) {
+	statistic := aws.StringValue(metric.MetricStatisticRecommendation)
+	if statistic == "" {
+		statistic = cloudwatch.StatisticMaximum
+	}
+	var dimensions []*cloudwatch.Dimension
+	for name, value := range metric.MetricDimensions {
+		dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String(name), Value: value})
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		return aws.StringValue(dimensions[i].Name) < aws.StringValue(dimensions[j].Name)
+	})
+
+	output, err := m.CloudWatchClient.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  metric.MetricNamespace,
+		MetricName: metric.MetricName,
+		Dimensions: dimensions,
+		StartTime:  aws.Time(now.Add(-usageLookback)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int64(int64(usagePeriod / time.Second)),
+		Statistics: []*string{aws.String(statistic)},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	var used float64
+	var ok bool
+	for _, d := range output.Datapoints {
+		value := datapointValue(d, statistic)
+		if !ok || value > used {
+			used = value
+			ok = true
+		}
+	}
+	return used, ok, nil
+}
+
+func datapointValue(d *cloudwatch.Datapoint, statistic string) float64 {
+	switch statistic {
+	case cloudwatch.StatisticAverage:
+		return aws.Float64Value(d.Average)
+	case cloudwatch.StatisticSum:
+		return aws.Float64Value(d.Sum)
+	case cloudwatch.StatisticMinimum:
+		return aws.Float64Value(d.Minimum)
+	case cloudwatch.StatisticSampleCount:
+		return aws.Float64Value(d.SampleCount)
+	}
+	return aws.Float64Value(d.Maximum)
+}
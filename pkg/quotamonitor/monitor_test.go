@@ -0,0 +1,205 @@
+package quotamonitor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
+	"go.uber.org/zap"
+)
+
+var logger, _ = zap.NewProduction()
+
+type mockSupportClient struct {
+	supportiface.SupportAPI
+	Err     error
+	Checks  []*support.TrustedAdvisorCheckDescription
+	Results map[string]*support.TrustedAdvisorCheckResult
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorChecksWithContext(ctx aws.Context, input *support.DescribeTrustedAdvisorChecksInput, opts ...request.Option) (*support.DescribeTrustedAdvisorChecksOutput, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return &support.DescribeTrustedAdvisorChecksOutput{Checks: m.Checks}, nil
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorCheckResultWithContext(ctx aws.Context, input *support.DescribeTrustedAdvisorCheckResultInput, opts ...request.Option) (*support.DescribeTrustedAdvisorCheckResultOutput, error) {
+	return &support.DescribeTrustedAdvisorCheckResultOutput{Result: m.Results[aws.StringValue(input.CheckId)]}, nil
+}
+
+type mockServiceQuotasClient struct {
+	servicequotasiface.ServiceQuotasAPI
+	Defaults []*servicequotas.ServiceQuota
+	Applied  []*servicequotas.ServiceQuota
+}
+
+func (m *mockServiceQuotasClient) ListAWSDefaultServiceQuotasPagesWithContext(ctx aws.Context, input *servicequotas.ListAWSDefaultServiceQuotasInput, fn func(*servicequotas.ListAWSDefaultServiceQuotasOutput, bool) bool, opts ...request.Option) error {
+	fn(&servicequotas.ListAWSDefaultServiceQuotasOutput{Quotas: m.Defaults}, true)
+	return nil
+}
+
+func (m *mockServiceQuotasClient) ListServiceQuotasPagesWithContext(ctx aws.Context, input *servicequotas.ListServiceQuotasInput, fn func(*servicequotas.ListServiceQuotasOutput, bool) bool, opts ...request.Option) error {
+	fn(&servicequotas.ListServiceQuotasOutput{Quotas: m.Applied}, true)
+	return nil
+}
+
+type mockCloudWatchClient struct {
+	cloudwatchiface.CloudWatchAPI
+	// Maximums are the datapoints returned for each metric name.
+	Maximums map[string][]float64
+	Inputs   []*cloudwatch.GetMetricStatisticsInput
+}
+
+func (m *mockCloudWatchClient) GetMetricStatisticsWithContext(ctx aws.Context, input *cloudwatch.GetMetricStatisticsInput, opts ...request.Option) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	m.Inputs = append(m.Inputs, input)
+	output := &cloudwatch.GetMetricStatisticsOutput{}
+	for _, v := range m.Maximums[aws.StringValue(input.MetricName)] {
+		output.Datapoints = append(output.Datapoints, &cloudwatch.Datapoint{Maximum: aws.Float64(v)})
+	}
+	return output, nil
+}
+
+func usageQuota(code, name string, value float64, metric string) *servicequotas.ServiceQuota {
+	return &servicequotas.ServiceQuota{
+		ServiceCode: aws.String("ec2"),
+		ServiceName: aws.String("Amazon Elastic Compute Cloud (Amazon EC2)"),
+		QuotaCode:   aws.String(code),
+		QuotaName:   aws.String(name),
+		Value:       aws.Float64(value),
+		UsageMetric: &servicequotas.MetricInfo{
+			MetricNamespace:               aws.String("AWS/Usage"),
+			MetricName:                    aws.String(metric),
+			MetricDimensions:              map[string]*string{"Service": aws.String("EC2"), "Type": aws.String("Resource")},
+			MetricStatisticRecommendation: aws.String(cloudwatch.StatisticMaximum),
+		},
+	}
+}
+
+func testMonitor() (*QuotaMonitor, *mockCloudWatchClient) {
+	cw := &mockCloudWatchClient{Maximums: map[string][]float64{
+		"ResourceCount": {3, 9},
+		"VCPUs":         {100},
+	}}
+	m := &QuotaMonitor{
+		CloudWatchClient: cw,
+		Logger:           logger,
+		Region:           "us-west-2",
+		ServiceCodes:     []string{"ec2"},
+		ServiceQuotasClient: &mockServiceQuotasClient{
+			Defaults: []*servicequotas.ServiceQuota{
+				usageQuota("L-0263D0A3", "EC2-VPC Elastic IPs", 5, "ResourceCount"),
+				usageQuota("L-1216C47A", "Running On-Demand Standard instances", 64, "VCPUs"),
+				{QuotaCode: aws.String("L-NOMETRIC"), QuotaName: aws.String("No metric"), Value: aws.Float64(1)},
+			},
+			Applied: []*servicequotas.ServiceQuota{
+				usageQuota("L-1216C47A", "Running On-Demand Standard instances", 1152, "VCPUs"),
+			},
+		},
+		SupportClient: &mockSupportClient{
+			Checks: []*support.TrustedAdvisorCheckDescription{
+				{
+					Id:       aws.String("jL7PP0l7J9"),
+					Name:     aws.String("VPC"),
+					Category: aws.String("service_limits"),
+					Metadata: aws.StringSlice([]string{"Region", "Service", "Limit Name", "Limit Amount", "Current Usage", "Status"}),
+				},
+				{Id: aws.String("Qch7DwouX1"), Name: aws.String("Low Utilization Amazon EC2 Instances"), Category: aws.String("cost_optimizing")},
+			},
+			Results: map[string]*support.TrustedAdvisorCheckResult{
+				"jL7PP0l7J9": {FlaggedResources: []*support.TrustedAdvisorResourceDetail{
+					{Metadata: aws.StringSlice([]string{"us-east-1", "VPC", "VPCs", "5", "5", "Red"})},
+					{Metadata: aws.StringSlice([]string{"us-west-2", "VPC", "Internet gateways", "1,000", "10", "Green"})},
+				}},
+			},
+		},
+	}
+	return m, cw
+}
+
+func TestCheck(t *testing.T) {
+	m, cw := testMonitor()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	usages, err := m.Check(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Usage{
+		{Source: SourceServiceQuotas, Service: "Amazon Elastic Compute Cloud (Amazon EC2)", QuotaName: "EC2-VPC Elastic IPs", QuotaCode: "L-0263D0A3", Region: "us-west-2", Limit: 5, Used: 9},
+		{Source: SourceTrustedAdvisor, Service: "VPC", QuotaName: "VPCs", Region: "us-east-1", Limit: 5, Used: 5},
+	}
+	if !reflect.DeepEqual(usages, want) {
+		t.Errorf("Check() = %+v, want %+v", usages, want)
+	}
+
+	if len(cw.Inputs) != 2 {
+		t.Fatalf("metric requests = %d, want 2", len(cw.Inputs))
+	}
+	input := cw.Inputs[0]
+	if !aws.TimeValue(input.StartTime).Equal(now.Add(-time.Hour)) || aws.Int64Value(input.Period) != 60 || aws.StringValue(input.Dimensions[0].Name) != "Service" {
+		t.Errorf("metric request = %+v", input)
+	}
+}
+
+func TestCheckWithoutSupportPlan(t *testing.T) {
+	m, _ := testMonitor()
+	m.SupportClient.(*mockSupportClient).Err = awserr.New(errCodeSubscriptionRequired, "no support plan", nil)
+	usages, err := m.Check(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usages) != 1 || usages[0].Source != SourceServiceQuotas {
+		t.Errorf("Check() = %+v, want only the service quotas usage", usages)
+	}
+}
+
+func TestOverThreshold(t *testing.T) {
+	usages := []Usage{
+		{Source: SourceTrustedAdvisor, Service: "EC2", QuotaName: "Elastic IPs", Region: "us-west-2", Limit: 5, Used: 4},
+		{Source: SourceTrustedAdvisor, Service: "ec2", QuotaName: "elastic ips", Region: "us-west-2", Limit: 5, Used: 5},
+		{Source: SourceServiceQuotas, Service: "Amazon Elastic Compute Cloud (Amazon EC2)", QuotaName: "EC2-VPC Elastic IPs", Region: "us-west-2", Limit: 5, Used: 5},
+		{Source: SourceServiceQuotas, Service: "EC2", QuotaName: "VPCs", Region: "us-west-2", Limit: 5, Used: 3},
+		{Source: SourceServiceQuotas, Service: "EC2", QuotaName: "Unlimited", Region: "us-west-2"},
+		{Source: SourceServiceQuotas, Service: "EC2", QuotaName: "Snapshots", Region: "us-west-2", Limit: 100, Used: 90},
+	}
+	var have []string
+	for _, u := range OverThreshold(usages, 80) {
+		have = append(have, u.Source+":"+u.QuotaName)
+	}
+	want := []string{"trusted-advisor:elastic ips", "service-quotas:EC2-VPC Elastic IPs", "service-quotas:Snapshots"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("OverThreshold() = %v, want %v", have, want)
+	}
+}
+
+func TestSlackMessage(t *testing.T) {
+	s := SlackAlert{Channel: "#aws", Emoji: ":chart_with_upwards_trend:", Region: "us-west-2"}
+	m := s.Message([]Usage{
+		{Service: "EC2", QuotaName: "Elastic IPs", Region: "us-west-2", Limit: 5, Used: 5},
+		{Service: "EC2", QuotaName: "Snapshots", Region: "us-west-2", Limit: 100000, Used: 85000.5},
+	}, 80)
+	if len(m.Attachments) != 1 {
+		t.Fatalf("attachments = %d, want 1", len(m.Attachments))
+	}
+	a := m.Attachments[0]
+	if a.Title != "2 quotas are over 80% used" || a.Color != "danger" || len(a.Fields) != 2 {
+		t.Errorf("attachment = %+v", a)
+	}
+	if a.TitleLink != "https://console.aws.amazon.com/servicequotas/home?region=us-west-2" {
+		t.Errorf("title link = %s", a.TitleLink)
+	}
+	if a.Fields[1].Value != "85000.5 of 100000 (85%)" {
+		t.Errorf("field value = %s", a.Fields[1].Value)
+	}
+}
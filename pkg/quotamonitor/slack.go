@@ -0,0 +1,53 @@
+package quotamonitor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lytics/slackhook"
+	"github.com/trussworks/truss-aws-tools/pkg/slacktemplate"
+)
+
+// SlackAlert defines where to post quotas over the threshold.
+type SlackAlert struct {
+	Channel    string
+	Emoji      string
+	Region     string
+	WebhookURL string
+}
+
+// formatAmount formats a quota amount without trailing zeros.
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// Message builds the Slack message with one field per quota.
+func (s *SlackAlert) Message(usages []Usage, threshold float64) *slackhook.Message {
+	message := &slackhook.Message{
+		Channel:   s.Channel,
+		IconEmoji: s.Emoji,
+	}
+	attachment := &slackhook.Attachment{
+		Title:     fmt.Sprintf("%d quotas are over %s%% used", len(usages), formatAmount(threshold)),
+		TitleLink: slacktemplate.ConsoleURL("servicequotas", s.Region),
+		Color:     "warning",
+		Footer:    "Quota Monitor",
+	}
+	for _, u := range usages {
+		if u.Percent() >= 100 {
+			attachment.Color = "danger"
+		}
+		attachment.Fields = append(attachment.Fields, slackhook.Field{
+			Title: fmt.Sprintf("%s: %s (%s)", u.Service, u.QuotaName, u.Region),
+			Value: fmt.Sprintf("%s of %s (%.0f%%)", formatAmount(u.Used), formatAmount(u.Limit), u.Percent()),
+		})
+	}
+	message.AddAttachment(attachment)
+	return message
+}
+
+// Send posts the quotas over the threshold to the Slack webhook.
+func (s *SlackAlert) Send(usages []Usage, threshold float64) error {
+	slack := slackhook.New(s.WebhookURL)
+	return slack.Send(s.Message(usages, threshold))
+}
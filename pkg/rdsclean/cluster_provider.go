@@ -0,0 +1,167 @@
+package rdsclean
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// clusterClient makes the cluster snapshot calls of the DocumentDB or
+// Neptune API in terms of the RDS types. The three APIs share the RDS
+// control plane and their requests and responses have the same fields, but
+// each SDK package has its own copies of the types.
+type clusterClient struct {
+	describeSnapshotsPages     func(*rds.DescribeDBClusterSnapshotsInput, func(*rds.DescribeDBClusterSnapshotsOutput, bool) bool) error
+	listTags                   func(*rds.ListTagsForResourceInput) (*rds.ListTagsForResourceOutput, error)
+	describeSnapshotAttributes func(*rds.DescribeDBClusterSnapshotAttributesInput) (*rds.DescribeDBClusterSnapshotAttributesOutput, error)
+	deleteSnapshot             func(*rds.DeleteDBClusterSnapshotInput) (*rds.DeleteDBClusterSnapshotOutput, error)
+}
+
+// newClusterClient adapts a DocumentDB or Neptune client's cluster snapshot
+// calls to clusterClient.
+func newClusterClient[DI, DO, TI, TO, AI, AO, XI, XO any](
+	describeSnapshotsPages func(*DI, func(*DO, bool) bool) error,
+	listTags func(*TI) (*TO, error),
+	describeSnapshotAttributes func(*AI) (*AO, error),
+	deleteSnapshot func(*XI) (*XO, error),
+) clusterClient {
+	return clusterClient{
+		describeSnapshotsPages: func(input *rds.DescribeDBClusterSnapshotsInput, fn func(*rds.DescribeDBClusterSnapshotsOutput, bool) bool) error {
+			in := new(DI)
+			if err := convertShape(in, input); err != nil {
+				return err
+			}
+			var convertErr error
+			err := describeSnapshotsPages(in, func(page *DO, lastPage bool) bool {
+				out := &rds.DescribeDBClusterSnapshotsOutput{}
+				if convertErr = convertShape(out, page); convertErr != nil {
+					return false
+				}
+				return fn(out, lastPage)
+			})
+			if err != nil {
+				return err
+			}
+			return convertErr
+		},
+		listTags:                   clusterCall[rds.ListTagsForResourceInput, rds.ListTagsForResourceOutput](listTags),
+		describeSnapshotAttributes: clusterCall[rds.DescribeDBClusterSnapshotAttributesInput, rds.DescribeDBClusterSnapshotAttributesOutput](describeSnapshotAttributes),
+		deleteSnapshot:             clusterCall[rds.DeleteDBClusterSnapshotInput, rds.DeleteDBClusterSnapshotOutput](deleteSnapshot),
+	}
+}
+
+// clusterCall adapts an engine API call to take and return the matching RDS
+// types.
+func clusterCall[RI, RO, In, Out any](call func(*In) (*Out, error)) func(*RI) (*RO, error) {
+	return func(input *RI) (*RO, error) {
+		in := new(In)
+		if err := convertShape(in, input); err != nil {
+			return nil, err
+		}
+		out, err := call(in)
+		if err != nil {
+			return nil, err
+		}
+		res := new(RO)
+		return res, convertShape(res, out)
+	}
+}
+
+// convertShape copies an API request or response into another service's
+// type with the same field names. Fields dst doesn't have are dropped.
+func convertShape(dst, src interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+// clusterProvider implements the snapshot handling DocumentDB and Neptune
+// share.
+type clusterProvider struct {
+	client clusterClient
+	engine string
+}
+
+// FindManualSnapshots returns the available manual snapshots of a cluster
+// that run the provider's engine. The DocumentDB and Neptune APIs don't
+// return tags with snapshots, so they are looked up one snapshot at a time.
+func (p *clusterProvider) FindManualSnapshots(dbIdentifier string) ([]*Snapshot, error) {
+	var snapshots []*Snapshot
+
+	input := &rds.DescribeDBClusterSnapshotsInput{
+		DBClusterIdentifier: aws.String(dbIdentifier),
+		IncludePublic:       aws.Bool(false),
+		IncludeShared:       aws.Bool(false),
+		SnapshotType:        aws.String("manual"),
+	}
+
+	err := p.client.describeSnapshotsPages(input,
+		func(page *rds.DescribeDBClusterSnapshotsOutput, lastPage bool) bool {
+			for _, s := range page.DBClusterSnapshots {
+				if aws.StringValue(s.Status) != "available" || s.SnapshotCreateTime == nil ||
+					aws.StringValue(s.Engine) != p.engine {
+					continue
+				}
+				snapshots = append(snapshots, &Snapshot{
+					Arn:          aws.StringValue(s.DBClusterSnapshotArn),
+					CreateTime:   *s.SnapshotCreateTime,
+					DBIdentifier: aws.StringValue(s.DBClusterIdentifier),
+					Identifier:   aws.StringValue(s.DBClusterSnapshotIdentifier),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range snapshots {
+		res, err := p.client.listTags(&rds.ListTagsForResourceInput{
+			ResourceName: aws.String(s.Arn),
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.Tags = tagsToMap(res.TagList)
+	}
+	return snapshots, nil
+}
+
+// FindExportingSnapshotArns returns nothing because DocumentDB and Neptune
+// snapshots can't be exported to S3.
+func (p *clusterProvider) FindExportingSnapshotArns() (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+
+// IsShared reports whether the snapshot's restore attribute grants access to
+// any other account.
+func (p *clusterProvider) IsShared(s *Snapshot) (bool, error) {
+	res, err := p.client.describeSnapshotAttributes(&rds.DescribeDBClusterSnapshotAttributesInput{
+		DBClusterSnapshotIdentifier: aws.String(s.Identifier),
+	})
+	if err != nil {
+		return false, err
+	}
+	if res.DBClusterSnapshotAttributesResult == nil {
+		return false, nil
+	}
+	for _, a := range res.DBClusterSnapshotAttributesResult.DBClusterSnapshotAttributes {
+		if hasRestoreAccounts(aws.StringValue(a.AttributeName), a.AttributeValues) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteSnapshot deletes the cluster snapshot. DocumentDB and Neptune have
+// no waiter for deleted snapshots, so this returns as soon as the delete is
+// accepted.
+func (p *clusterProvider) DeleteSnapshot(s *Snapshot) error {
+	_, err := p.client.deleteSnapshot(&rds.DeleteDBClusterSnapshotInput{
+		DBClusterSnapshotIdentifier: aws.String(s.Identifier),
+	})
+	return err
+}
@@ -0,0 +1,45 @@
+package rdsclean
+
+import "github.com/aws/aws-sdk-go/service/docdb/docdbiface"
+
+// DocDBProvider manages manual snapshots of Amazon DocumentDB clusters.
+type DocDBProvider struct {
+	DocDBClient docdbiface.DocDBAPI
+}
+
+// Name returns the provider name.
+func (p *DocDBProvider) Name() string {
+	return ProviderDocDB
+}
+
+func (p *DocDBProvider) cluster() *clusterProvider {
+	c := p.DocDBClient
+	return &clusterProvider{
+		client: newClusterClient(c.DescribeDBClusterSnapshotsPages, c.ListTagsForResource,
+			c.DescribeDBClusterSnapshotAttributes, c.DeleteDBClusterSnapshot),
+		engine: engineDocDB,
+	}
+}
+
+// FindManualSnapshots returns the available manual snapshots of a cluster.
+func (p *DocDBProvider) FindManualSnapshots(dbIdentifier string) ([]*Snapshot, error) {
+	return p.cluster().FindManualSnapshots(dbIdentifier)
+}
+
+// FindExportingSnapshotArns returns nothing because Amazon DocumentDB snapshots
+// can't be exported to S3.
+func (p *DocDBProvider) FindExportingSnapshotArns() (map[string]bool, error) {
+	return p.cluster().FindExportingSnapshotArns()
+}
+
+// IsShared reports whether the snapshot's restore attribute grants access to
+// any other account.
+func (p *DocDBProvider) IsShared(s *Snapshot) (bool, error) {
+	return p.cluster().IsShared(s)
+}
+
+// DeleteSnapshot deletes the cluster snapshot without waiting for it to be
+// gone.
+func (p *DocDBProvider) DeleteSnapshot(s *Snapshot) error {
+	return p.cluster().DeleteSnapshot(s)
+}
@@ -0,0 +1,45 @@
+package rdsclean
+
+import "github.com/aws/aws-sdk-go/service/neptune/neptuneiface"
+
+// NeptuneProvider manages manual snapshots of Amazon Neptune clusters.
+type NeptuneProvider struct {
+	NeptuneClient neptuneiface.NeptuneAPI
+}
+
+// Name returns the provider name.
+func (p *NeptuneProvider) Name() string {
+	return ProviderNeptune
+}
+
+func (p *NeptuneProvider) cluster() *clusterProvider {
+	c := p.NeptuneClient
+	return &clusterProvider{
+		client: newClusterClient(c.DescribeDBClusterSnapshotsPages, c.ListTagsForResource,
+			c.DescribeDBClusterSnapshotAttributes, c.DeleteDBClusterSnapshot),
+		engine: engineNeptune,
+	}
+}
+
+// FindManualSnapshots returns the available manual snapshots of a cluster.
+func (p *NeptuneProvider) FindManualSnapshots(dbIdentifier string) ([]*Snapshot, error) {
+	return p.cluster().FindManualSnapshots(dbIdentifier)
+}
+
+// FindExportingSnapshotArns returns nothing because Amazon Neptune snapshots
+// can't be exported to S3.
+func (p *NeptuneProvider) FindExportingSnapshotArns() (map[string]bool, error) {
+	return p.cluster().FindExportingSnapshotArns()
+}
+
+// IsShared reports whether the snapshot's restore attribute grants access to
+// any other account.
+func (p *NeptuneProvider) IsShared(s *Snapshot) (bool, error) {
+	return p.cluster().IsShared(s)
+}
+
+// DeleteSnapshot deletes the cluster snapshot without waiting for it to be
+// gone.
+func (p *NeptuneProvider) DeleteSnapshot(s *Snapshot) error {
+	return p.cluster().DeleteSnapshot(s)
+}
@@ -0,0 +1,94 @@
+package rdsclean
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+)
+
+// Snapshot is the engine-neutral view of a manual snapshot that the
+// retention rules operate on.
+type Snapshot struct {
+	Arn          string
+	CreateTime   time.Time
+	DBIdentifier string
+	Identifier   string
+	Tags         map[string]string
+}
+
+// SnapshotProvider lists, inspects and deletes the manual snapshots of one
+// kind of database. Providers only return snapshots that are available, so
+// the retention rules never see snapshots that are still being created.
+type SnapshotProvider interface {
+	// Name identifies the provider in logs and on the command line.
+	Name() string
+	// FindManualSnapshots returns the available manual snapshots of the
+	// given instance or cluster, including their tags.
+	FindManualSnapshots(dbIdentifier string) ([]*Snapshot, error)
+	// FindExportingSnapshotArns returns the ARNs of snapshots that are the
+	// source of a running S3 export task.
+	FindExportingSnapshotArns() (map[string]bool, error)
+	// IsShared reports whether the snapshot is shared with other accounts.
+	IsShared(s *Snapshot) (bool, error)
+	// DeleteSnapshot deletes the snapshot and waits for it to be gone when
+	// the API supports waiting.
+	DeleteSnapshot(s *Snapshot) error
+}
+
+// Provider names accepted by NewSnapshotProvider's callers.
+const (
+	ProviderRDSInstance = "rds-instance"
+	ProviderRDSCluster  = "rds-cluster"
+	ProviderDocDB       = "docdb"
+	ProviderNeptune     = "neptune"
+)
+
+// Engines reported for DocumentDB and Neptune clusters. They share the RDS
+// control plane, so the RDS cluster APIs return their snapshots too.
+const (
+	engineDocDB   = "docdb"
+	engineNeptune = "neptune"
+)
+
+// tagsToMap converts an RDS-style tag list into a map.
+func tagsToMap(tags []*rds.Tag) map[string]string {
+	m := map[string]string{}
+	for _, t := range tags {
+		m[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return m
+}
+
+// hasRestoreAccounts reports whether a restore attribute lists any account.
+func hasRestoreAccounts(name string, values []*string) bool {
+	return name == restoreAttributeName && len(values) > 0
+}
+
+// findExportingSnapshotArns returns the source ARNs of all RDS S3 export
+// tasks that have not finished yet. Both instance and cluster snapshots can
+// be exported.
+func findExportingSnapshotArns(client rdsiface.RDSAPI) (map[string]bool, error) {
+	exporting := map[string]bool{}
+	err := client.DescribeExportTasksPages(&rds.DescribeExportTasksInput{},
+		func(page *rds.DescribeExportTasksOutput, lastPage bool) bool {
+			for _, t := range page.ExportTasks {
+				if isExportTaskActive(aws.StringValue(t.Status)) {
+					exporting[aws.StringValue(t.SourceArn)] = true
+				}
+			}
+			return true
+		})
+	return exporting, err
+}
+
+// isExportTaskActive reports whether an export task with the given status is
+// still reading from its source snapshot.
+func isExportTaskActive(status string) bool {
+	switch status {
+	case "STARTING", "IN_PROGRESS":
+		return true
+	}
+	return false
+}
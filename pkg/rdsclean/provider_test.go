@@ -0,0 +1,265 @@
+package rdsclean
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/docdb"
+	"github.com/aws/aws-sdk-go/service/docdb/docdbiface"
+	"github.com/aws/aws-sdk-go/service/neptune"
+	"github.com/aws/aws-sdk-go/service/neptune/neptuneiface"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/trussworks/truss-aws-tools/internal/aws/fakerds"
+	"go.uber.org/zap"
+)
+
+// Every provider must satisfy the interface the retention engine uses.
+var _ = []SnapshotProvider{
+	&DBInstanceProvider{},
+	&DBClusterProvider{},
+	&DocDBProvider{},
+	&NeptuneProvider{},
+}
+
+func snapshotIdentifiers(snapshots []*Snapshot) []string {
+	var ids []string
+	for _, s := range snapshots {
+		ids = append(ids, s.Identifier)
+	}
+	return ids
+}
+
+func TestDBClusterProviderClean(t *testing.T) {
+	logger, _ := zap.NewProduction()
+
+	b := fakerds.New()
+	for _, s := range []*rds.DBClusterSnapshot{
+		{
+			DBClusterIdentifier:         aws.String("foo-cluster"),
+			DBClusterSnapshotIdentifier: aws.String("old-aurora"),
+			Engine:                      aws.String("aurora-postgresql"),
+			SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+		},
+		{
+			DBClusterIdentifier:         aws.String("foo-cluster"),
+			DBClusterSnapshotIdentifier: aws.String("old-shared"),
+			Engine:                      aws.String("aurora-postgresql"),
+			SnapshotCreateTime:          aws.Time(getTime("2017-03-01T21:00:00+00:00")),
+		},
+		{
+			DBClusterIdentifier:         aws.String("foo-cluster"),
+			DBClusterSnapshotIdentifier: aws.String("new-aurora"),
+			Engine:                      aws.String("aurora-postgresql"),
+			SnapshotCreateTime:          aws.Time(getTime("2017-03-03T22:00:00+00:00")),
+			TagList:                     []*rds.Tag{{Key: aws.String("Name"), Value: aws.String("foo-cluster")}},
+		},
+		{
+			DBClusterIdentifier:         aws.String("foo-cluster"),
+			DBClusterSnapshotIdentifier: aws.String("old-docdb"),
+			Engine:                      aws.String(engineDocDB),
+			SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+		},
+	} {
+		b.AddDBClusterSnapshot(s)
+	}
+	b.ShareDBClusterSnapshot("old-shared", "all")
+
+	p := &DBClusterProvider{RDSClient: b}
+	snapshots, err := p.FindManualSnapshots("foo-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFound := []string{"new-aurora", "old-aurora", "old-shared"}
+	if have := snapshotIdentifiers(snapshots); !reflect.DeepEqual(have, wantFound) {
+		t.Fatalf("FindManualSnapshots() = %v, want %v", have, wantFound)
+	}
+	if snapshots[0].Tags["Name"] != "foo-cluster" {
+		t.Fatalf("FindManualSnapshots() tags = %v, want Name=foo-cluster", snapshots[0].Tags)
+	}
+
+	r := RDSManualSnapshotClean{
+		DBIdentifier:   "foo-cluster",
+		ExpirationDate: getTime("2017-03-02T22:00:00+00:00"),
+		Logger:         logger,
+		Provider:       p,
+	}
+	if err := r.Clean(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantLeft := []string{"new-aurora", "old-docdb", "old-shared"}
+	if have := b.DBClusterSnapshotIdentifiers(); !reflect.DeepEqual(have, wantLeft) {
+		t.Fatalf("remaining cluster snapshots = %v, want %v", have, wantLeft)
+	}
+}
+
+// mockDocDBClient returns a fixed page of cluster snapshots and records
+// deletes.
+type mockDocDBClient struct {
+	docdbiface.DocDBAPI
+	Snapshots []*docdb.DBClusterSnapshot
+	Tags      map[string][]*docdb.Tag
+	Deleted   []string
+}
+
+func (m *mockDocDBClient) DescribeDBClusterSnapshotsPages(input *docdb.DescribeDBClusterSnapshotsInput, fn func(*docdb.DescribeDBClusterSnapshotsOutput, bool) bool) error {
+	fn(&docdb.DescribeDBClusterSnapshotsOutput{DBClusterSnapshots: m.Snapshots}, true)
+	return nil
+}
+
+func (m *mockDocDBClient) ListTagsForResource(input *docdb.ListTagsForResourceInput) (*docdb.ListTagsForResourceOutput, error) {
+	return &docdb.ListTagsForResourceOutput{TagList: m.Tags[*input.ResourceName]}, nil
+}
+
+func (m *mockDocDBClient) DescribeDBClusterSnapshotAttributes(input *docdb.DescribeDBClusterSnapshotAttributesInput) (*docdb.DescribeDBClusterSnapshotAttributesOutput, error) {
+	return &docdb.DescribeDBClusterSnapshotAttributesOutput{}, nil
+}
+
+func (m *mockDocDBClient) DeleteDBClusterSnapshot(input *docdb.DeleteDBClusterSnapshotInput) (*docdb.DeleteDBClusterSnapshotOutput, error) {
+	m.Deleted = append(m.Deleted, *input.DBClusterSnapshotIdentifier)
+	return &docdb.DeleteDBClusterSnapshotOutput{}, nil
+}
+
+func TestDocDBProviderClean(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	m := &mockDocDBClient{
+		Snapshots: []*docdb.DBClusterSnapshot{
+			{
+				DBClusterIdentifier:         aws.String("foo-docdb"),
+				DBClusterSnapshotArn:        aws.String("arn:old"),
+				DBClusterSnapshotIdentifier: aws.String("old-snapshot"),
+				Engine:                      aws.String(engineDocDB),
+				SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+				Status:                      aws.String("available"),
+			},
+			{
+				DBClusterIdentifier:         aws.String("foo-docdb"),
+				DBClusterSnapshotArn:        aws.String("arn:kept"),
+				DBClusterSnapshotIdentifier: aws.String("kept-snapshot"),
+				Engine:                      aws.String(engineDocDB),
+				SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+				Status:                      aws.String("available"),
+			},
+			{
+				DBClusterIdentifier:         aws.String("foo-docdb"),
+				DBClusterSnapshotArn:        aws.String("arn:creating"),
+				DBClusterSnapshotIdentifier: aws.String("creating-snapshot"),
+				Engine:                      aws.String(engineDocDB),
+				SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+				Status:                      aws.String("creating"),
+			},
+		},
+		Tags: map[string][]*docdb.Tag{
+			"arn:kept": {{Key: aws.String("Retain"), Value: aws.String("true")}},
+		},
+	}
+
+	r := RDSManualSnapshotClean{
+		DBIdentifier:   "foo-docdb",
+		ExpirationDate: getTime("2017-03-02T22:00:00+00:00"),
+		Logger:         logger,
+		Provider:       &DocDBProvider{DocDBClient: m},
+		RetainTagKey:   "Retain",
+	}
+	if err := r.Clean(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"old-snapshot"}
+	if !reflect.DeepEqual(m.Deleted, want) {
+		t.Fatalf("deleted snapshots = %v, want %v", m.Deleted, want)
+	}
+}
+
+// mockNeptuneClient returns a fixed page of cluster snapshots and records
+// deletes.
+type mockNeptuneClient struct {
+	neptuneiface.NeptuneAPI
+	Snapshots []*neptune.DBClusterSnapshot
+	Deleted   []string
+}
+
+func (m *mockNeptuneClient) DescribeDBClusterSnapshotsPages(input *neptune.DescribeDBClusterSnapshotsInput, fn func(*neptune.DescribeDBClusterSnapshotsOutput, bool) bool) error {
+	fn(&neptune.DescribeDBClusterSnapshotsOutput{DBClusterSnapshots: m.Snapshots}, true)
+	return nil
+}
+
+func (m *mockNeptuneClient) ListTagsForResource(input *neptune.ListTagsForResourceInput) (*neptune.ListTagsForResourceOutput, error) {
+	return &neptune.ListTagsForResourceOutput{}, nil
+}
+
+func (m *mockNeptuneClient) DescribeDBClusterSnapshotAttributes(input *neptune.DescribeDBClusterSnapshotAttributesInput) (*neptune.DescribeDBClusterSnapshotAttributesOutput, error) {
+	var values []*string
+	if *input.DBClusterSnapshotIdentifier == "shared-snapshot" {
+		values = aws.StringSlice([]string{"210987654321"})
+	}
+	return &neptune.DescribeDBClusterSnapshotAttributesOutput{
+		DBClusterSnapshotAttributesResult: &neptune.DBClusterSnapshotAttributesResult{
+			DBClusterSnapshotAttributes: []*neptune.DBClusterSnapshotAttribute{
+				{AttributeName: aws.String("restore"), AttributeValues: values},
+			},
+		},
+	}, nil
+}
+
+func (m *mockNeptuneClient) DeleteDBClusterSnapshot(input *neptune.DeleteDBClusterSnapshotInput) (*neptune.DeleteDBClusterSnapshotOutput, error) {
+	m.Deleted = append(m.Deleted, *input.DBClusterSnapshotIdentifier)
+	return &neptune.DeleteDBClusterSnapshotOutput{}, nil
+}
+
+func TestNeptuneProviderClean(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	m := &mockNeptuneClient{
+		Snapshots: []*neptune.DBClusterSnapshot{
+			{
+				DBClusterIdentifier:         aws.String("foo-neptune"),
+				DBClusterSnapshotArn:        aws.String("arn:old"),
+				DBClusterSnapshotIdentifier: aws.String("old-snapshot"),
+				Engine:                      aws.String(engineNeptune),
+				SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+				Status:                      aws.String("available"),
+			},
+			{
+				DBClusterIdentifier:         aws.String("foo-neptune"),
+				DBClusterSnapshotArn:        aws.String("arn:shared"),
+				DBClusterSnapshotIdentifier: aws.String("shared-snapshot"),
+				Engine:                      aws.String(engineNeptune),
+				SnapshotCreateTime:          aws.Time(getTime("2017-03-01T22:00:00+00:00")),
+				Status:                      aws.String("available"),
+			},
+			{
+				DBClusterIdentifier:         aws.String("foo-neptune"),
+				DBClusterSnapshotArn:        aws.String("arn:new"),
+				DBClusterSnapshotIdentifier: aws.String("new-snapshot"),
+				Engine:                      aws.String(engineNeptune),
+				SnapshotCreateTime:          aws.Time(getTime("2017-03-03T22:00:00+00:00")),
+				Status:                      aws.String("available"),
+			},
+		},
+	}
+
+	r := RDSManualSnapshotClean{
+		DBIdentifier:   "foo-neptune",
+		ExpirationDate: getTime("2017-03-02T22:00:00+00:00"),
+		Logger:         logger,
+		Provider:       &NeptuneProvider{NeptuneClient: m},
+	}
+
+	r.DryRun = true
+	if err := r.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Deleted) != 0 {
+		t.Fatalf("dry run deleted %v, want nothing", m.Deleted)
+	}
+
+	r.DryRun = false
+	if err := r.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"old-snapshot"}
+	if !reflect.DeepEqual(m.Deleted, want) {
+		t.Fatalf("deleted snapshots = %v, want %v", m.Deleted, want)
+	}
+}
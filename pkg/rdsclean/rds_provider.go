@@ -0,0 +1,178 @@
+package rdsclean
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+)
+
+// DBInstanceProvider manages manual snapshots of RDS DB instances.
+type DBInstanceProvider struct {
+	RDSClient rdsiface.RDSAPI
+}
+
+// Name returns the provider name.
+func (p *DBInstanceProvider) Name() string {
+	return ProviderRDSInstance
+}
+
+// FindManualSnapshots returns the available manual snapshots of an instance.
+func (p *DBInstanceProvider) FindManualSnapshots(dbIdentifier string) ([]*Snapshot, error) {
+	var snapshots []*Snapshot
+
+	input := &rds.DescribeDBSnapshotsInput{
+		DBInstanceIdentifier: aws.String(dbIdentifier),
+		IncludePublic:        aws.Bool(false),
+		IncludeShared:        aws.Bool(false),
+		SnapshotType:         aws.String("manual"),
+	}
+
+	err := p.RDSClient.DescribeDBSnapshotsPages(input,
+		func(page *rds.DescribeDBSnapshotsOutput, lastPage bool) bool {
+			for _, s := range page.DBSnapshots {
+				if aws.StringValue(s.Status) != "available" || s.SnapshotCreateTime == nil {
+					continue
+				}
+				snapshots = append(snapshots, &Snapshot{
+					Arn:          aws.StringValue(s.DBSnapshotArn),
+					CreateTime:   *s.SnapshotCreateTime,
+					DBIdentifier: aws.StringValue(s.DBInstanceIdentifier),
+					Identifier:   aws.StringValue(s.DBSnapshotIdentifier),
+					Tags:         tagsToMap(s.TagList),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// FindExportingSnapshotArns returns the ARNs of snapshots being exported.
+func (p *DBInstanceProvider) FindExportingSnapshotArns() (map[string]bool, error) {
+	return findExportingSnapshotArns(p.RDSClient)
+}
+
+// IsShared reports whether the snapshot's restore attribute grants access to
+// any other account.
+func (p *DBInstanceProvider) IsShared(s *Snapshot) (bool, error) {
+	res, err := p.RDSClient.DescribeDBSnapshotAttributes(&rds.DescribeDBSnapshotAttributesInput{
+		DBSnapshotIdentifier: aws.String(s.Identifier),
+	})
+	if err != nil {
+		return false, err
+	}
+	if res.DBSnapshotAttributesResult == nil {
+		return false, nil
+	}
+	for _, a := range res.DBSnapshotAttributesResult.DBSnapshotAttributes {
+		if hasRestoreAccounts(aws.StringValue(a.AttributeName), a.AttributeValues) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteSnapshot deletes DB snapshot and waits for it to complete
+func (p *DBInstanceProvider) DeleteSnapshot(s *Snapshot) error {
+	_, err := p.RDSClient.DeleteDBSnapshot(&rds.DeleteDBSnapshotInput{
+		DBSnapshotIdentifier: aws.String(s.Identifier),
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.RDSClient.WaitUntilDBSnapshotDeleted(&rds.DescribeDBSnapshotsInput{
+		DBSnapshotIdentifier: aws.String(s.Identifier),
+	})
+}
+
+// DBClusterProvider manages manual snapshots of Aurora and other RDS DB
+// clusters. DocumentDB and Neptune snapshots are left to their own providers.
+type DBClusterProvider struct {
+	RDSClient rdsiface.RDSAPI
+}
+
+// Name returns the provider name.
+func (p *DBClusterProvider) Name() string {
+	return ProviderRDSCluster
+}
+
+// FindManualSnapshots returns the available manual snapshots of a cluster.
+func (p *DBClusterProvider) FindManualSnapshots(dbIdentifier string) ([]*Snapshot, error) {
+	var snapshots []*Snapshot
+
+	input := &rds.DescribeDBClusterSnapshotsInput{
+		DBClusterIdentifier: aws.String(dbIdentifier),
+		IncludePublic:       aws.Bool(false),
+		IncludeShared:       aws.Bool(false),
+		SnapshotType:        aws.String("manual"),
+	}
+
+	err := p.RDSClient.DescribeDBClusterSnapshotsPages(input,
+		func(page *rds.DescribeDBClusterSnapshotsOutput, lastPage bool) bool {
+			for _, s := range page.DBClusterSnapshots {
+				if aws.StringValue(s.Status) != "available" || s.SnapshotCreateTime == nil {
+					continue
+				}
+				switch aws.StringValue(s.Engine) {
+				case engineDocDB, engineNeptune:
+					continue
+				}
+				snapshots = append(snapshots, &Snapshot{
+					Arn:          aws.StringValue(s.DBClusterSnapshotArn),
+					CreateTime:   *s.SnapshotCreateTime,
+					DBIdentifier: aws.StringValue(s.DBClusterIdentifier),
+					Identifier:   aws.StringValue(s.DBClusterSnapshotIdentifier),
+					Tags:         tagsToMap(s.TagList),
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// FindExportingSnapshotArns returns the ARNs of snapshots being exported.
+func (p *DBClusterProvider) FindExportingSnapshotArns() (map[string]bool, error) {
+	return findExportingSnapshotArns(p.RDSClient)
+}
+
+// IsShared reports whether the snapshot's restore attribute grants access to
+// any other account.
+func (p *DBClusterProvider) IsShared(s *Snapshot) (bool, error) {
+	res, err := p.RDSClient.DescribeDBClusterSnapshotAttributes(&rds.DescribeDBClusterSnapshotAttributesInput{
+		DBClusterSnapshotIdentifier: aws.String(s.Identifier),
+	})
+	if err != nil {
+		return false, err
+	}
+	if res.DBClusterSnapshotAttributesResult == nil {
+		return false, nil
+	}
+	for _, a := range res.DBClusterSnapshotAttributesResult.DBClusterSnapshotAttributes {
+		if hasRestoreAccounts(aws.StringValue(a.AttributeName), a.AttributeValues) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteSnapshot deletes the cluster snapshot and waits for it to complete
+func (p *DBClusterProvider) DeleteSnapshot(s *Snapshot) error {
+	_, err := p.RDSClient.DeleteDBClusterSnapshot(&rds.DeleteDBClusterSnapshotInput{
+		DBClusterSnapshotIdentifier: aws.String(s.Identifier),
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.RDSClient.WaitUntilDBClusterSnapshotDeleted(&rds.DescribeDBClusterSnapshotsInput{
+		DBClusterSnapshotIdentifier: aws.String(s.Identifier),
+	})
+}
@@ -4,35 +4,70 @@ import (
 	"sort"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/rds"
 	"go.uber.org/zap"
 )
 
 const (
 	// RFC8601 is the date/time format used by AWS.
 	RFC8601 = "2006-01-02T15:04:05-07:00"
+	// restoreAttributeName is the snapshot attribute listing the
+	// accounts a manual snapshot is shared with.
+	restoreAttributeName = "restore"
 )
 
-// RDSManualSnapshotClean defines parameters for cleaning manual RDS snapshots
-// based on ExpirationDate and MaxDBSnapshotCount
+// Reasons a snapshot matching the retention rules is kept anyway.
+const (
+	SkipReasonRetainTag = "retain-tag"
+	SkipReasonShared    = "shared"
+	SkipReasonExporting = "export-in-progress"
+)
+
+// RDSManualSnapshotClean defines parameters for cleaning manual snapshots
+// based on ExpirationDate and MaxDBSnapshotCount. The Provider decides which
+// kind of database (RDS instance, Aurora cluster, DocumentDB or Neptune)
+// DBIdentifier refers to.
 type RDSManualSnapshotClean struct {
-	DBInstanceIdentifier string
-	DryRun               bool
-	ExpirationDate       time.Time
-	Logger               *zap.Logger
-	MaxDBSnapshotCount   uint
-	RDSClient            *rds.RDS
+	DBIdentifier       string
+	DryRun             bool
+	ExpirationDate     time.Time
+	Logger             *zap.Logger
+	MaxDBSnapshotCount uint
+	Provider           SnapshotProvider
+	// RetainTagKey and RetainTagValue mark snapshots that should never
+	// be deleted. An empty RetainTagValue matches any value.
+	RetainTagKey   string
+	RetainTagValue string
+}
+
+// Clean finds the manual snapshots past the retention rules, drops the ones
+// that must be kept, and deletes the rest.
+func (r *RDSManualSnapshotClean) Clean() error {
+	manualDBSnapshots, err := r.FindManualDBSnapshots()
+	if err != nil {
+		return err
+	}
+
+	dbSnapshotsToDelete, err := r.FindDBSnapshotsToDelete(manualDBSnapshots)
+	if err != nil {
+		return err
+	}
+
+	dbSnapshotsToDelete, err = r.SkipRetainedDBSnapshots(dbSnapshotsToDelete)
+	if err != nil {
+		return err
+	}
+
+	return r.DeleteDBSnapshots(dbSnapshotsToDelete)
 }
 
 // FindDBSnapshotsToDelete will return a slice of DB snapshots to delete
-func (r *RDSManualSnapshotClean) FindDBSnapshotsToDelete(dbSnapshots []*rds.DBSnapshot) ([]*rds.DBSnapshot, error) {
-	var dbSnapshotsToDelete []*rds.DBSnapshot
+func (r *RDSManualSnapshotClean) FindDBSnapshotsToDelete(dbSnapshots []*Snapshot) ([]*Snapshot, error) {
+	var dbSnapshotsToDelete []*Snapshot
 
 	sortDBSnapshots(dbSnapshots)
 	for i, s := range dbSnapshots {
 		// add snapshot to delete slice if past expiration
-		if s.SnapshotCreateTime.Before(r.ExpirationDate) {
+		if s.CreateTime.Before(r.ExpirationDate) {
 			dbSnapshotsToDelete = append(dbSnapshotsToDelete, s)
 			continue
 		}
@@ -48,54 +83,103 @@ func (r *RDSManualSnapshotClean) FindDBSnapshotsToDelete(dbSnapshots []*rds.DBSn
 }
 
 // FindManualDBSnapshots returns a slice of available manual snapshots
-func (r *RDSManualSnapshotClean) FindManualDBSnapshots() ([]*rds.DBSnapshot, error) {
-	var manualDBSnapshots []*rds.DBSnapshot
-
-	input := &rds.DescribeDBSnapshotsInput{
-		DBInstanceIdentifier: aws.String(r.DBInstanceIdentifier),
-		IncludePublic:        aws.Bool(false),
-		IncludeShared:        aws.Bool(false),
-		SnapshotType:         aws.String("manual"),
+func (r *RDSManualSnapshotClean) FindManualDBSnapshots() ([]*Snapshot, error) {
+	return r.Provider.FindManualSnapshots(r.DBIdentifier)
+}
+
+// SkipRetainedDBSnapshots removes snapshots that carry the retain tag, are
+// shared with other accounts, or are the source of a running S3 export task.
+// Every skipped snapshot is logged along with the reason it was kept.
+func (r *RDSManualSnapshotClean) SkipRetainedDBSnapshots(dbSnapshots []*Snapshot) ([]*Snapshot, error) {
+	var dbSnapshotsToDelete []*Snapshot
+
+	if len(dbSnapshots) == 0 {
+		return dbSnapshotsToDelete, nil
 	}
 
-	res, err := r.RDSClient.DescribeDBSnapshots(input)
+	exporting, err := r.Provider.FindExportingSnapshotArns()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, s := range res.DBSnapshots {
-		if s.Status == aws.String("available") || s.SnapshotCreateTime != nil {
-			manualDBSnapshots = append(manualDBSnapshots, s)
+	for _, s := range dbSnapshots {
+		reason, err := r.skipReason(s, exporting)
+		if err != nil {
+			return nil, err
 		}
+		if reason != "" {
+			r.Logger.Info("skipping db snapshot",
+				zap.String("provider", r.Provider.Name()),
+				zap.String("db-snapshot-identifier", s.Identifier),
+				zap.String("reason", reason),
+			)
+			continue
+		}
+		dbSnapshotsToDelete = append(dbSnapshotsToDelete, s)
 	}
 
-	return manualDBSnapshots, err
+	return dbSnapshotsToDelete, nil
 }
 
-// sortDBSnapshots sorts a slice of DB snapshots in chronological order(newest first) using SnapshotCreateTime
-func sortDBSnapshots(dbSnapshots []*rds.DBSnapshot) {
+// skipReason returns why a snapshot must be kept, or an empty string if it
+// can be deleted.
+func (r *RDSManualSnapshotClean) skipReason(s *Snapshot, exporting map[string]bool) (string, error) {
+	if hasRetainTag(s.Tags, r.RetainTagKey, r.RetainTagValue) {
+		return SkipReasonRetainTag, nil
+	}
+
+	if exporting[s.Arn] {
+		return SkipReasonExporting, nil
+	}
+
+	shared, err := r.Provider.IsShared(s)
+	if err != nil {
+		return "", err
+	}
+	if shared {
+		return SkipReasonShared, nil
+	}
+
+	return "", nil
+}
+
+// hasRetainTag reports whether tags contain the retain tag. An empty key
+// never matches; an empty value matches any value for the key.
+func hasRetainTag(tags map[string]string, key, value string) bool {
+	if key == "" {
+		return false
+	}
+	v, ok := tags[key]
+	return ok && (value == "" || v == value)
+}
+
+// sortDBSnapshots sorts a slice of DB snapshots in chronological order(newest first) using CreateTime
+func sortDBSnapshots(dbSnapshots []*Snapshot) {
 	// sort by snapshot creation time
 	sort.Slice(dbSnapshots, func(i, j int) bool {
-		return dbSnapshots[i].SnapshotCreateTime.After(*dbSnapshots[j].SnapshotCreateTime)
+		return dbSnapshots[i].CreateTime.After(dbSnapshots[j].CreateTime)
 	})
 }
 
-// DeleteDBSnapshots iterates through a list of snapshots and calls deleteDBSnapshot
-func (r *RDSManualSnapshotClean) DeleteDBSnapshots(dbSnapshotsToDelete []*rds.DBSnapshot) error {
-	r.Logger.Info("db snapshots to delete", zap.Int("snapshots", len(dbSnapshotsToDelete)))
+// DeleteDBSnapshots iterates through a list of snapshots and calls DeleteDBSnapshot
+func (r *RDSManualSnapshotClean) DeleteDBSnapshots(dbSnapshotsToDelete []*Snapshot) error {
+	r.Logger.Info("db snapshots to delete",
+		zap.String("provider", r.Provider.Name()),
+		zap.Int("snapshots", len(dbSnapshotsToDelete)),
+	)
 	for _, e := range dbSnapshotsToDelete {
 		if r.DryRun {
 			r.Logger.Info("would delete db snapshot",
-				zap.String("db-snapshot-identifier", *e.DBSnapshotIdentifier),
-				zap.String("db-snapshot-create-time", e.SnapshotCreateTime.Format(RFC8601)),
+				zap.String("db-snapshot-identifier", e.Identifier),
+				zap.String("db-snapshot-create-time", e.CreateTime.Format(RFC8601)),
 			)
 
 		} else {
 			r.Logger.Info("deleting snapshot",
-				zap.String("db-snapshot-identifier", *e.DBSnapshotIdentifier),
-				zap.String("db-snapshot-create-time", e.SnapshotCreateTime.Format(RFC8601)),
+				zap.String("db-snapshot-identifier", e.Identifier),
+				zap.String("db-snapshot-create-time", e.CreateTime.Format(RFC8601)),
 			)
-			err := r.DeleteDBSnapshot(*e.DBSnapshotIdentifier)
+			err := r.DeleteDBSnapshot(e)
 			if err != nil {
 				return err
 			}
@@ -105,19 +189,7 @@ func (r *RDSManualSnapshotClean) DeleteDBSnapshots(dbSnapshotsToDelete []*rds.DB
 	return nil
 }
 
-// DeleteDBSnapshot deletes DB snapshot and waits for it to complete
-func (r *RDSManualSnapshotClean) DeleteDBSnapshot(DBSnapshotIdentifier string) error {
-	deleteDBSnapshotInput := &rds.DeleteDBSnapshotInput{
-		DBSnapshotIdentifier: aws.String(DBSnapshotIdentifier),
-	}
-	_, err := r.RDSClient.DeleteDBSnapshot(deleteDBSnapshotInput)
-	if err != nil {
-		return err
-	}
-
-	WaitUntilDBSnapshotDeletedInput := &rds.DescribeDBSnapshotsInput{
-		DBSnapshotIdentifier: aws.String(DBSnapshotIdentifier),
-	}
-	err = r.RDSClient.WaitUntilDBSnapshotDeleted(WaitUntilDBSnapshotDeletedInput)
-	return err
+// DeleteDBSnapshot deletes a DB snapshot through the provider
+func (r *RDSManualSnapshotClean) DeleteDBSnapshot(s *Snapshot) error {
+	return r.Provider.DeleteSnapshot(s)
 }
@@ -7,21 +7,31 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/trussworks/truss-aws-tools/internal/aws/fakerds"
 	"go.uber.org/zap"
 )
 
-var oldDBSnapshot = &rds.DBSnapshot{
-	DBInstanceIdentifier: aws.String("foo-db"),
-	DBSnapshotIdentifier: aws.String("old-snapshot"),
-	SnapshotCreateTime:   aws.Time(getTime("2017-03-01T22:00:00+00:00")),
-	Status:               aws.String("available"),
+var oldDBSnapshot = &Snapshot{
+	DBIdentifier: "foo-db",
+	Identifier:   "old-snapshot",
+	CreateTime:   getTime("2017-03-01T22:00:00+00:00"),
 }
 
-var newDBSnapshot = &rds.DBSnapshot{
-	DBInstanceIdentifier: aws.String("foo-db"),
-	DBSnapshotIdentifier: aws.String("new-snapshot"),
-	SnapshotCreateTime:   aws.Time(getTime("2017-03-03T22:00:00+00:00")),
-	Status:               aws.String("available"),
+var newDBSnapshot = &Snapshot{
+	DBIdentifier: "foo-db",
+	Identifier:   "new-snapshot",
+	CreateTime:   getTime("2017-03-03T22:00:00+00:00"),
+}
+
+// rdsDBSnapshot converts a test snapshot into the RDS shape the fake
+// backend stores.
+func rdsDBSnapshot(s *Snapshot) *rds.DBSnapshot {
+	return &rds.DBSnapshot{
+		DBInstanceIdentifier: aws.String(s.DBIdentifier),
+		DBSnapshotIdentifier: aws.String(s.Identifier),
+		SnapshotCreateTime:   aws.Time(s.CreateTime),
+		Status:               aws.String("available"),
+	}
 }
 
 func getTime(original string) (parsed time.Time) {
@@ -33,10 +43,10 @@ func getTime(original string) (parsed time.Time) {
 }
 
 func TestSortDBSnapshots(t *testing.T) {
-	wantDBSnapshots := []*rds.DBSnapshot{
+	wantDBSnapshots := []*Snapshot{
 		newDBSnapshot,
 		oldDBSnapshot}
-	haveDBSnapshots := []*rds.DBSnapshot{
+	haveDBSnapshots := []*Snapshot{
 		oldDBSnapshot,
 		newDBSnapshot}
 
@@ -50,7 +60,7 @@ func TestSortDBSnapshots(t *testing.T) {
 }
 
 func TestFindDBSnapshotsToDelete(t *testing.T) {
-	dbSnapshots := []*rds.DBSnapshot{
+	dbSnapshots := []*Snapshot{
 		newDBSnapshot,
 		newDBSnapshot,
 		oldDBSnapshot,
@@ -58,17 +68,17 @@ func TestFindDBSnapshotsToDelete(t *testing.T) {
 
 	logger, _ := zap.NewProduction()
 	r := RDSManualSnapshotClean{
-		DBInstanceIdentifier: "cleanme",
-		DryRun:               true,
-		ExpirationDate:       getTime("2017-03-02T22:00:00+00:00"),
-		Logger:               logger,
-		MaxDBSnapshotCount:   0,
-		RDSClient:            nil,
+		DBIdentifier:       "cleanme",
+		DryRun:             true,
+		ExpirationDate:     getTime("2017-03-02T22:00:00+00:00"),
+		Logger:             logger,
+		MaxDBSnapshotCount: 0,
+		Provider:           nil,
 	}
 
 	//expirationTime := getTime("2017-03-02T22:00:00+00:00")
 	//maxDBSnapshotCount := 0
-	wantExpiredDBSnapshots := []*rds.DBSnapshot{oldDBSnapshot}
+	wantExpiredDBSnapshots := []*Snapshot{oldDBSnapshot}
 
 	haveExpiredDBSnapshots, err := r.FindDBSnapshotsToDelete(dbSnapshots)
 	if err != nil {
@@ -82,7 +92,7 @@ func TestFindDBSnapshotsToDelete(t *testing.T) {
 
 	r.ExpirationDate = getTime("2017-02-28T22:00:00+00:00")
 	r.MaxDBSnapshotCount = 2
-	wantMaxDBSnapshots := []*rds.DBSnapshot{oldDBSnapshot}
+	wantMaxDBSnapshots := []*Snapshot{oldDBSnapshot}
 	haveMaxDBSnapshots, err := r.FindDBSnapshotsToDelete(dbSnapshots)
 	if err != nil {
 		t.Fatal(err)
@@ -94,3 +104,173 @@ func TestFindDBSnapshotsToDelete(t *testing.T) {
 	}
 
 }
+
+func TestHasRetainTag(t *testing.T) {
+	tags := map[string]string{
+		"Name":   "foo-db",
+		"Retain": "true",
+	}
+
+	cases := []struct {
+		key   string
+		value string
+		want  bool
+	}{
+		{"", "", false},
+		{"Retain", "", true},
+		{"Retain", "true", true},
+		{"Retain", "false", false},
+		{"Keep", "", false},
+	}
+	for _, c := range cases {
+		if have := hasRetainTag(tags, c.key, c.value); have != c.want {
+			t.Errorf("hasRetainTag(tags, %q, %q) = %v, want %v", c.key, c.value, have, c.want)
+		}
+	}
+}
+
+func TestIsExportTaskActive(t *testing.T) {
+	cases := map[string]bool{
+		"STARTING":    true,
+		"IN_PROGRESS": true,
+		"COMPLETE":    false,
+		"FAILED":      false,
+		"CANCELED":    false,
+	}
+	for status, want := range cases {
+		if have := isExportTaskActive(status); have != want {
+			t.Errorf("isExportTaskActive(%q) = %v, want %v", status, have, want)
+		}
+	}
+}
+
+// newFakeBackend returns a fake RDS backend holding the old and new
+// snapshots for foo-db plus a snapshot belonging to another instance.
+func newFakeBackend() *fakerds.Backend {
+	b := fakerds.New()
+	b.AddDBInstance("foo-db")
+	b.AddDBSnapshot(rdsDBSnapshot(oldDBSnapshot))
+	b.AddDBSnapshot(rdsDBSnapshot(newDBSnapshot))
+	b.AddDBSnapshot(&rds.DBSnapshot{
+		DBInstanceIdentifier: aws.String("bar-db"),
+		DBSnapshotIdentifier: aws.String("other-snapshot"),
+		SnapshotCreateTime:   aws.Time(getTime("2017-02-01T22:00:00+00:00")),
+	})
+	b.AddDBSnapshot(&rds.DBSnapshot{
+		DBInstanceIdentifier: aws.String("foo-db"),
+		DBSnapshotIdentifier: aws.String("creating-snapshot"),
+		SnapshotCreateTime:   aws.Time(getTime("2017-02-01T22:00:00+00:00")),
+		Status:               aws.String(fakerds.StatusCreating),
+	})
+	return b
+}
+
+func TestCleanDBSnapshots(t *testing.T) {
+	logger, _ := zap.NewProduction()
+
+	cases := []struct {
+		name        string
+		dryRun      bool
+		setup       func(b *fakerds.Backend)
+		wantDeleted []string
+		wantLeft    []string
+	}{
+		{
+			name:        "deletes expired snapshots",
+			wantDeleted: []string{"old-snapshot"},
+			wantLeft:    []string{"creating-snapshot", "new-snapshot", "other-snapshot"},
+		},
+		{
+			name:        "dry run deletes nothing",
+			dryRun:      true,
+			wantDeleted: nil,
+			wantLeft:    []string{"creating-snapshot", "new-snapshot", "old-snapshot", "other-snapshot"},
+		},
+		{
+			name: "keeps snapshots with the retain tag",
+			setup: func(b *fakerds.Backend) {
+				b.AddDBSnapshot(&rds.DBSnapshot{
+					DBInstanceIdentifier: aws.String("foo-db"),
+					DBSnapshotIdentifier: aws.String("old-snapshot"),
+					SnapshotCreateTime:   aws.Time(oldDBSnapshot.CreateTime),
+					TagList:              []*rds.Tag{{Key: aws.String("Retain"), Value: aws.String("true")}},
+				})
+			},
+			wantDeleted: nil,
+			wantLeft:    []string{"creating-snapshot", "new-snapshot", "old-snapshot", "other-snapshot"},
+		},
+		{
+			name: "keeps shared snapshots",
+			setup: func(b *fakerds.Backend) {
+				b.ShareDBSnapshot("old-snapshot", "210987654321")
+			},
+			wantDeleted: nil,
+			wantLeft:    []string{"creating-snapshot", "new-snapshot", "old-snapshot", "other-snapshot"},
+		},
+		{
+			name: "keeps snapshots being exported",
+			setup: func(b *fakerds.Backend) {
+				b.AddExportTask("arn:aws:rds:us-west-2:123456789012:snapshot:old-snapshot", "IN_PROGRESS")
+			},
+			wantDeleted: nil,
+			wantLeft:    []string{"creating-snapshot", "new-snapshot", "old-snapshot", "other-snapshot"},
+		},
+		{
+			name: "deletes snapshots whose export finished",
+			setup: func(b *fakerds.Backend) {
+				b.AddExportTask("arn:aws:rds:us-west-2:123456789012:snapshot:old-snapshot", "COMPLETE")
+			},
+			wantDeleted: []string{"old-snapshot"},
+			wantLeft:    []string{"creating-snapshot", "new-snapshot", "other-snapshot"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := newFakeBackend()
+			if c.setup != nil {
+				c.setup(b)
+			}
+			r := RDSManualSnapshotClean{
+				DBIdentifier:   "foo-db",
+				DryRun:         c.dryRun,
+				ExpirationDate: getTime("2017-03-02T22:00:00+00:00"),
+				Logger:         logger,
+				Provider:       &DBInstanceProvider{RDSClient: b},
+				RetainTagKey:   "Retain",
+			}
+
+			if err := r.Clean(); err != nil {
+				t.Fatal(err)
+			}
+
+			if have := b.DeletedSnapshots(); !reflect.DeepEqual(have, c.wantDeleted) {
+				t.Errorf("deleted snapshots = %v, want %v", have, c.wantDeleted)
+			}
+			if have := b.DBSnapshotIdentifiers(); !reflect.DeepEqual(have, c.wantLeft) {
+				t.Errorf("remaining snapshots = %v, want %v", have, c.wantLeft)
+			}
+		})
+	}
+}
+
+func TestCleanDBSnapshotsMaxCount(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	b := newFakeBackend()
+	r := RDSManualSnapshotClean{
+		DBIdentifier:       "foo-db",
+		ExpirationDate:     getTime("2017-02-28T22:00:00+00:00"),
+		Logger:             logger,
+		MaxDBSnapshotCount: 1,
+		Provider:           &DBInstanceProvider{RDSClient: b},
+	}
+
+	if err := r.Clean(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantDeleted := []string{"old-snapshot"}
+	if have := b.DeletedSnapshots(); !reflect.DeepEqual(have, wantDeleted) {
+		t.Fatalf("deleted snapshots = %v, want %v", have, wantDeleted)
+	}
+}
@@ -0,0 +1,189 @@
+package rdssnapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"go.uber.org/zap"
+)
+
+const (
+	// SnapshotTimeFormat is the timestamp layout used in snapshot
+	// identifiers. RDS identifiers may only contain letters, digits and
+	// hyphens, so the usual RFC3339 layout can't be used.
+	SnapshotTimeFormat = "2006-01-02-15-04"
+)
+
+// RDSManualSnapshotCreate defines parameters for creating manual snapshots
+// of RDS instances and Aurora clusters.
+type RDSManualSnapshotCreate struct {
+	DBClusterIdentifiers  []string
+	DBInstanceIdentifiers []string
+	DryRun                bool
+	Logger                *zap.Logger
+	RDSClient             rdsiface.RDSAPI
+	SnapshotPrefix        string
+	// Timeout bounds how long we wait for each snapshot to become
+	// available. Zero means wait using the SDK's default waiter limits.
+	Timeout time.Duration
+}
+
+// SnapshotIdentifier returns the name to use for a snapshot of the given
+// instance or cluster taken at time t. Names look like
+// <prefix>-<identifier>-<yyyy-mm-dd-hh-mm>, or drop the prefix if it is empty.
+func SnapshotIdentifier(prefix, identifier string, t time.Time) string {
+	name := fmt.Sprintf("%s-%s", identifier, t.UTC().Format(SnapshotTimeFormat))
+	if prefix != "" {
+		name = fmt.Sprintf("%s-%s", prefix, name)
+	}
+	return name
+}
+
+// copyTags returns the tags that can be copied onto a snapshot. AWS owns
+// every key starting with aws: and refuses to let us set them.
+func copyTags(tags []*rds.Tag) []*rds.Tag {
+	var retval []*rds.Tag
+	for _, t := range tags {
+		if strings.HasPrefix(aws.StringValue(t.Key), "aws:") {
+			continue
+		}
+		retval = append(retval, &rds.Tag{Key: t.Key, Value: t.Value})
+	}
+	return retval
+}
+
+// CreateSnapshots creates a manual snapshot of every configured instance and
+// cluster and waits for each to become available. It returns the identifiers
+// of the snapshots that were created.
+func (r *RDSManualSnapshotCreate) CreateSnapshots(now time.Time) ([]string, error) {
+	var created []string
+
+	for _, id := range r.DBInstanceIdentifiers {
+		snapshotID, err := r.CreateDBSnapshot(id, now)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, snapshotID)
+	}
+
+	for _, id := range r.DBClusterIdentifiers {
+		snapshotID, err := r.CreateDBClusterSnapshot(id, now)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, snapshotID)
+	}
+
+	return created, nil
+}
+
+// CreateDBSnapshot snapshots a single DB instance, copying its tags, and
+// waits for the snapshot to become available.
+func (r *RDSManualSnapshotCreate) CreateDBSnapshot(DBInstanceIdentifier string, now time.Time) (string, error) {
+	snapshotID := SnapshotIdentifier(r.SnapshotPrefix, DBInstanceIdentifier, now)
+
+	res, err := r.RDSClient.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(DBInstanceIdentifier),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.DBInstances) != 1 {
+		return "", fmt.Errorf("no db instance found with identifier: %s", DBInstanceIdentifier)
+	}
+
+	if r.DryRun {
+		r.Logger.Info("would create db snapshot",
+			zap.String("db-instance-identifier", DBInstanceIdentifier),
+			zap.String("db-snapshot-identifier", snapshotID),
+		)
+		return snapshotID, nil
+	}
+
+	r.Logger.Info("creating db snapshot",
+		zap.String("db-instance-identifier", DBInstanceIdentifier),
+		zap.String("db-snapshot-identifier", snapshotID),
+	)
+	_, err = r.RDSClient.CreateDBSnapshot(&rds.CreateDBSnapshotInput{
+		DBInstanceIdentifier: aws.String(DBInstanceIdentifier),
+		DBSnapshotIdentifier: aws.String(snapshotID),
+		Tags:                 copyTags(res.DBInstances[0].TagList),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := r.waitContext()
+	defer cancel()
+	err = r.RDSClient.WaitUntilDBSnapshotAvailableWithContext(ctx, &rds.DescribeDBSnapshotsInput{
+		DBSnapshotIdentifier: aws.String(snapshotID),
+	}, r.waiterOptions()...)
+	return snapshotID, err
+}
+
+// CreateDBClusterSnapshot snapshots a single Aurora cluster, copying its
+// tags, and waits for the snapshot to become available.
+func (r *RDSManualSnapshotCreate) CreateDBClusterSnapshot(DBClusterIdentifier string, now time.Time) (string, error) {
+	snapshotID := SnapshotIdentifier(r.SnapshotPrefix, DBClusterIdentifier, now)
+
+	res, err := r.RDSClient.DescribeDBClusters(&rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(DBClusterIdentifier),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.DBClusters) != 1 {
+		return "", fmt.Errorf("no db cluster found with identifier: %s", DBClusterIdentifier)
+	}
+
+	if r.DryRun {
+		r.Logger.Info("would create db cluster snapshot",
+			zap.String("db-cluster-identifier", DBClusterIdentifier),
+			zap.String("db-cluster-snapshot-identifier", snapshotID),
+		)
+		return snapshotID, nil
+	}
+
+	r.Logger.Info("creating db cluster snapshot",
+		zap.String("db-cluster-identifier", DBClusterIdentifier),
+		zap.String("db-cluster-snapshot-identifier", snapshotID),
+	)
+	_, err = r.RDSClient.CreateDBClusterSnapshot(&rds.CreateDBClusterSnapshotInput{
+		DBClusterIdentifier:         aws.String(DBClusterIdentifier),
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+		Tags:                        copyTags(res.DBClusters[0].TagList),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := r.waitContext()
+	defer cancel()
+	err = r.RDSClient.WaitUntilDBClusterSnapshotAvailableWithContext(ctx, &rds.DescribeDBClusterSnapshotsInput{
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+	}, r.waiterOptions()...)
+	return snapshotID, err
+}
+
+// waitContext returns a context bounded by Timeout, if one is set.
+func (r *RDSManualSnapshotCreate) waitContext() (context.Context, context.CancelFunc) {
+	if r.Timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), r.Timeout)
+}
+
+// waiterOptions lifts the SDK's default attempt limit when a timeout is set
+// so that the context deadline, not the attempt count, ends the wait.
+func (r *RDSManualSnapshotCreate) waiterOptions() []request.WaiterOption {
+	if r.Timeout == 0 {
+		return nil
+	}
+	return []request.WaiterOption{request.WithWaiterMaxAttempts(0)}
+}
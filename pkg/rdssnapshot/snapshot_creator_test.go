@@ -0,0 +1,97 @@
+package rdssnapshot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/trussworks/truss-aws-tools/internal/aws/fakerds"
+	"go.uber.org/zap"
+)
+
+func TestSnapshotIdentifier(t *testing.T) {
+	now := time.Date(2019, 7, 1, 13, 5, 0, 0, time.UTC)
+
+	cases := []struct {
+		prefix     string
+		identifier string
+		want       string
+	}{
+		{"manual", "foo-db", "manual-foo-db-2019-07-01-13-05"},
+		{"", "foo-db", "foo-db-2019-07-01-13-05"},
+	}
+	for _, c := range cases {
+		if have := SnapshotIdentifier(c.prefix, c.identifier, now); have != c.want {
+			t.Errorf("SnapshotIdentifier(%q, %q) = %q, want %q", c.prefix, c.identifier, have, c.want)
+		}
+	}
+}
+
+func TestCopyTags(t *testing.T) {
+	tags := []*rds.Tag{
+		{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("TestStack")},
+		{Key: aws.String("Name"), Value: aws.String("foo-db")},
+	}
+	want := []*rds.Tag{
+		{Key: aws.String("Name"), Value: aws.String("foo-db")},
+	}
+
+	have := copyTags(tags)
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("copyTags(tags) = %v, want %v", have, want)
+	}
+}
+
+func TestCreateSnapshots(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	now := time.Date(2019, 7, 1, 13, 5, 0, 0, time.UTC)
+
+	b := fakerds.New()
+	b.Now = func() time.Time { return now }
+	b.AddDBInstance("foo-db", &rds.Tag{Key: aws.String("Name"), Value: aws.String("foo-db")})
+	b.AddDBCluster("foo-cluster")
+
+	c := RDSManualSnapshotCreate{
+		DBClusterIdentifiers:  []string{"foo-cluster"},
+		DBInstanceIdentifiers: []string{"foo-db"},
+		DryRun:                true,
+		Logger:                logger,
+		RDSClient:             b,
+		SnapshotPrefix:        "manual",
+		Timeout:               time.Minute,
+	}
+
+	want := []string{"manual-foo-db-2019-07-01-13-05", "manual-foo-cluster-2019-07-01-13-05"}
+	have, err := c.CreateSnapshots(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("CreateSnapshots() in dry run = %v, want %v", have, want)
+	}
+	if ids := b.DBSnapshotIdentifiers(); len(ids) != 0 {
+		t.Fatalf("CreateSnapshots() in dry run created %v", ids)
+	}
+
+	c.DryRun = false
+	have, err = c.CreateSnapshots(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("CreateSnapshots() = %v, want %v", have, want)
+	}
+	if status := b.DBSnapshotStatus(want[0]); status != fakerds.StatusAvailable {
+		t.Fatalf("snapshot %s status = %q, want %q", want[0], status, fakerds.StatusAvailable)
+	}
+	if ids := b.DBClusterSnapshotIdentifiers(); !reflect.DeepEqual(ids, want[1:]) {
+		t.Fatalf("cluster snapshots = %v, want %v", ids, want[1:])
+	}
+
+	// A second run in the same minute collides with the first snapshot.
+	if _, err = c.CreateSnapshots(now); err == nil {
+		t.Fatal("CreateSnapshots() with an existing snapshot identifier did not fail")
+	}
+}
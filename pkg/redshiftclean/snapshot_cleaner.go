@@ -0,0 +1,134 @@
+package redshiftclean
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshift"
+	"github.com/aws/aws-sdk-go/service/redshift/redshiftiface"
+	"go.uber.org/zap"
+)
+
+const (
+	// RFC8601 is the date/time format used by AWS.
+	RFC8601 = "2006-01-02T15:04:05-07:00"
+	// IndefiniteRetentionPeriod is the ManualSnapshotRetentionPeriod
+	// Redshift reports for manual snapshots that never expire on their own.
+	IndefiniteRetentionPeriod = -1
+)
+
+// RedshiftManualSnapshotClean defines parameters for cleaning manual Redshift
+// snapshots based on ExpirationDate and MaxSnapshotCount
+type RedshiftManualSnapshotClean struct {
+	ClusterIdentifier string
+	DryRun            bool
+	ExpirationDate    time.Time
+	Logger            *zap.Logger
+	MaxSnapshotCount  uint
+	RedshiftClient    redshiftiface.RedshiftAPI
+}
+
+// FindSnapshotsToDelete will return a slice of cluster snapshots to delete
+func (r *RedshiftManualSnapshotClean) FindSnapshotsToDelete(snapshots []*redshift.Snapshot) ([]*redshift.Snapshot, error) {
+	var snapshotsToDelete []*redshift.Snapshot
+
+	sortSnapshots(snapshots)
+	for i, s := range snapshots {
+		// add snapshot to delete slice if past expiration
+		if s.SnapshotCreateTime.Before(r.ExpirationDate) {
+			snapshotsToDelete = append(snapshotsToDelete, s)
+			continue
+		}
+		// if we are still over maxSnapshotCount add to the delete slice
+		// skip if maxSnapshotCount is 0
+		if i+1 > int(r.MaxSnapshotCount) && r.MaxSnapshotCount != 0 {
+			snapshotsToDelete = append(snapshotsToDelete, s)
+		}
+	}
+
+	return snapshotsToDelete, nil
+}
+
+// FindManualSnapshots returns a slice of available manual snapshots for the
+// cluster. Snapshots with their own retention period are left for Redshift
+// to expire and are not returned.
+func (r *RedshiftManualSnapshotClean) FindManualSnapshots() ([]*redshift.Snapshot, error) {
+	var manualSnapshots []*redshift.Snapshot
+
+	input := &redshift.DescribeClusterSnapshotsInput{
+		ClusterIdentifier: aws.String(r.ClusterIdentifier),
+		SnapshotType:      aws.String("manual"),
+	}
+
+	err := r.RedshiftClient.DescribeClusterSnapshotsPages(input,
+		func(page *redshift.DescribeClusterSnapshotsOutput, lastPage bool) bool {
+			for _, s := range page.Snapshots {
+				if aws.StringValue(s.Status) != "available" || s.SnapshotCreateTime == nil {
+					continue
+				}
+				if hasRetentionPeriod(s) {
+					r.Logger.Info("skipping snapshot with retention period",
+						zap.String("snapshot-identifier", aws.StringValue(s.SnapshotIdentifier)),
+						zap.Int64("manual-snapshot-retention-period", aws.Int64Value(s.ManualSnapshotRetentionPeriod)),
+						zap.Int64("manual-snapshot-remaining-days", aws.Int64Value(s.ManualSnapshotRemainingDays)),
+					)
+					continue
+				}
+				manualSnapshots = append(manualSnapshots, s)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return manualSnapshots, nil
+}
+
+// hasRetentionPeriod reports whether Redshift will delete the snapshot on
+// its own once its manual retention period runs out.
+func hasRetentionPeriod(s *redshift.Snapshot) bool {
+	return s.ManualSnapshotRetentionPeriod != nil &&
+		*s.ManualSnapshotRetentionPeriod != IndefiniteRetentionPeriod
+}
+
+// sortSnapshots sorts a slice of snapshots in chronological order(newest first) using SnapshotCreateTime
+func sortSnapshots(snapshots []*redshift.Snapshot) {
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].SnapshotCreateTime.After(*snapshots[j].SnapshotCreateTime)
+	})
+}
+
+// DeleteSnapshots iterates through a list of snapshots and calls DeleteSnapshot
+func (r *RedshiftManualSnapshotClean) DeleteSnapshots(snapshotsToDelete []*redshift.Snapshot) error {
+	r.Logger.Info("snapshots to delete", zap.Int("snapshots", len(snapshotsToDelete)))
+	for _, s := range snapshotsToDelete {
+		if r.DryRun {
+			r.Logger.Info("would delete snapshot",
+				zap.String("snapshot-identifier", *s.SnapshotIdentifier),
+				zap.String("snapshot-create-time", s.SnapshotCreateTime.Format(RFC8601)),
+			)
+		} else {
+			r.Logger.Info("deleting snapshot",
+				zap.String("snapshot-identifier", *s.SnapshotIdentifier),
+				zap.String("snapshot-create-time", s.SnapshotCreateTime.Format(RFC8601)),
+			)
+			err := r.DeleteSnapshot(*s.SnapshotIdentifier)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteSnapshot deletes a manual cluster snapshot
+func (r *RedshiftManualSnapshotClean) DeleteSnapshot(snapshotIdentifier string) error {
+	_, err := r.RedshiftClient.DeleteClusterSnapshot(&redshift.DeleteClusterSnapshotInput{
+		SnapshotClusterIdentifier: aws.String(r.ClusterIdentifier),
+		SnapshotIdentifier:        aws.String(snapshotIdentifier),
+	})
+	return err
+}
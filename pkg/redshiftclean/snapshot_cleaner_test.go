@@ -0,0 +1,173 @@
+package redshiftclean
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshift"
+	"github.com/aws/aws-sdk-go/service/redshift/redshiftiface"
+	"go.uber.org/zap"
+)
+
+// We set up a mock Redshift client so that we can mock API calls for our
+// code. Pages holds the DescribeClusterSnapshots pages to return in order.
+type mockRedshiftClient struct {
+	redshiftiface.RedshiftAPI
+	Pages   [][]*redshift.Snapshot
+	Deleted []string
+}
+
+var logger, _ = zap.NewProduction()
+
+var oldSnapshot = &redshift.Snapshot{
+	ClusterIdentifier:             aws.String("foo-cluster"),
+	ManualSnapshotRetentionPeriod: aws.Int64(IndefiniteRetentionPeriod),
+	SnapshotCreateTime:            aws.Time(time.Date(2017, 3, 1, 22, 0, 0, 0, time.UTC)),
+	SnapshotIdentifier:            aws.String("old-snapshot"),
+	Status:                        aws.String("available"),
+}
+
+var newSnapshot = &redshift.Snapshot{
+	ClusterIdentifier:             aws.String("foo-cluster"),
+	ManualSnapshotRetentionPeriod: aws.Int64(IndefiniteRetentionPeriod),
+	SnapshotCreateTime:            aws.Time(time.Date(2017, 3, 3, 22, 0, 0, 0, time.UTC)),
+	SnapshotIdentifier:            aws.String("new-snapshot"),
+	Status:                        aws.String("available"),
+}
+
+var newerSnapshot = &redshift.Snapshot{
+	ClusterIdentifier:             aws.String("foo-cluster"),
+	ManualSnapshotRetentionPeriod: aws.Int64(IndefiniteRetentionPeriod),
+	SnapshotCreateTime:            aws.Time(time.Date(2017, 3, 4, 22, 0, 0, 0, time.UTC)),
+	SnapshotIdentifier:            aws.String("newer-snapshot"),
+	Status:                        aws.String("available"),
+}
+
+// This snapshot expires on its own, so the cleaner should leave it alone.
+var retainedSnapshot = &redshift.Snapshot{
+	ClusterIdentifier:             aws.String("foo-cluster"),
+	ManualSnapshotRemainingDays:   aws.Int64(10),
+	ManualSnapshotRetentionPeriod: aws.Int64(30),
+	SnapshotCreateTime:            aws.Time(time.Date(2017, 2, 1, 22, 0, 0, 0, time.UTC)),
+	SnapshotIdentifier:            aws.String("retained-snapshot"),
+	Status:                        aws.String("available"),
+}
+
+// This snapshot is still being created.
+var creatingSnapshot = &redshift.Snapshot{
+	ClusterIdentifier:             aws.String("foo-cluster"),
+	ManualSnapshotRetentionPeriod: aws.Int64(IndefiniteRetentionPeriod),
+	SnapshotCreateTime:            aws.Time(time.Date(2017, 2, 1, 22, 0, 0, 0, time.UTC)),
+	SnapshotIdentifier:            aws.String("creating-snapshot"),
+	Status:                        aws.String("creating"),
+}
+
+func (m *mockRedshiftClient) DescribeClusterSnapshotsPages(input *redshift.DescribeClusterSnapshotsInput, fn func(*redshift.DescribeClusterSnapshotsOutput, bool) bool) error {
+	for i, p := range m.Pages {
+		if !fn(&redshift.DescribeClusterSnapshotsOutput{Snapshots: p}, i == len(m.Pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockRedshiftClient) DeleteClusterSnapshot(input *redshift.DeleteClusterSnapshotInput) (*redshift.DeleteClusterSnapshotOutput, error) {
+	m.Deleted = append(m.Deleted, *input.SnapshotIdentifier)
+	return &redshift.DeleteClusterSnapshotOutput{}, nil
+}
+
+func testRedshiftClean(client redshiftiface.RedshiftAPI) RedshiftManualSnapshotClean {
+	return RedshiftManualSnapshotClean{
+		ClusterIdentifier: "foo-cluster",
+		DryRun:            false,
+		ExpirationDate:    time.Date(2017, 3, 2, 22, 0, 0, 0, time.UTC),
+		Logger:            logger,
+		RedshiftClient:    client,
+	}
+}
+
+func TestSortSnapshots(t *testing.T) {
+	want := []*redshift.Snapshot{newerSnapshot, newSnapshot, oldSnapshot}
+	have := []*redshift.Snapshot{oldSnapshot, newerSnapshot, newSnapshot}
+
+	sortSnapshots(have)
+	if !reflect.DeepEqual(want, have) {
+		t.Fatalf("sortSnapshots(have) = %v, \nwant = %v", have, want)
+	}
+}
+
+func TestFindManualSnapshots(t *testing.T) {
+	m := &mockRedshiftClient{
+		Pages: [][]*redshift.Snapshot{
+			{oldSnapshot, retainedSnapshot},
+			{newSnapshot, creatingSnapshot},
+		},
+	}
+	r := testRedshiftClean(m)
+
+	want := []*redshift.Snapshot{oldSnapshot, newSnapshot}
+	have, err := r.FindManualSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Fatalf("FindManualSnapshots() = %v, \nwant = %v", have, want)
+	}
+}
+
+func TestFindSnapshotsToDelete(t *testing.T) {
+	r := testRedshiftClean(&mockRedshiftClient{})
+
+	cases := []struct {
+		expirationDate   time.Time
+		maxSnapshotCount uint
+		want             []*redshift.Snapshot
+	}{
+		{time.Date(2017, 3, 2, 22, 0, 0, 0, time.UTC), 0, []*redshift.Snapshot{oldSnapshot}},
+		{time.Date(2017, 2, 28, 22, 0, 0, 0, time.UTC), 2, []*redshift.Snapshot{oldSnapshot}},
+		{time.Date(2017, 2, 28, 22, 0, 0, 0, time.UTC), 1, []*redshift.Snapshot{newSnapshot, oldSnapshot}},
+		{time.Date(2017, 2, 28, 22, 0, 0, 0, time.UTC), 0, nil},
+	}
+	for _, c := range cases {
+		r.ExpirationDate = c.expirationDate
+		r.MaxSnapshotCount = c.maxSnapshotCount
+		snapshots := []*redshift.Snapshot{oldSnapshot, newSnapshot, newerSnapshot}
+
+		have, err := r.FindSnapshotsToDelete(snapshots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.want, have) {
+			t.Errorf("FindSnapshotsToDelete() with expiration %v and max %d = %v, \nwant = %v",
+				c.expirationDate, c.maxSnapshotCount, have, c.want)
+		}
+	}
+}
+
+func TestDeleteSnapshots(t *testing.T) {
+	m := &mockRedshiftClient{}
+	r := testRedshiftClean(m)
+
+	r.DryRun = true
+	err := r.DeleteSnapshots([]*redshift.Snapshot{oldSnapshot, newSnapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Deleted) != 0 {
+		t.Fatalf("DeleteSnapshots() in dry run deleted %v, want nothing", m.Deleted)
+	}
+
+	r.DryRun = false
+	err = r.DeleteSnapshots([]*redshift.Snapshot{oldSnapshot, newSnapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"new-snapshot", "old-snapshot"}
+	sort.Strings(m.Deleted)
+	if !reflect.DeepEqual(want, m.Deleted) {
+		t.Fatalf("DeleteSnapshots() deleted %v, want %v", m.Deleted, want)
+	}
+}
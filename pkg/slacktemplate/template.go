@@ -0,0 +1,128 @@
+// Package slacktemplate renders Slack attachments from text/template
+// templates, so notifiers can change their message layout without a
+// rebuild.
+//
+// A template renders a JSON attachment using Slack's field names:
+//
+//	{
+//	  "title": {{ json .Title }},
+//	  "title_link": {{ json .Link }},
+//	  "color": "danger",
+//	  "fields": [{"title": "Service", "value": {{ json .Service }}}]
+//	}
+//
+// The json function quotes a value for use in the document and join joins a
+// string slice.
+package slacktemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/lytics/slackhook"
+	"github.com/trussworks/truss-aws-tools/internal/aws/ssm"
+)
+
+const (
+	// SSMPrefix marks a template source as an SSM parameter name.
+	SSMPrefix = "ssm:"
+	// DefaultRegion is used in console links when the region is unknown.
+	DefaultRegion = "us-west-2"
+)
+
+// Field is a single attachment field.
+type Field struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Attachment is the document a template renders.
+type Attachment struct {
+	Color     string  `json:"color"`
+	Fields    []Field `json:"fields"`
+	Footer    string  `json:"footer"`
+	Text      string  `json:"text"`
+	Title     string  `json:"title"`
+	TitleLink string  `json:"title_link"`
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": strings.Join,
+}
+
+// Parse parses a template with the json and join functions available.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcs).Parse(text)
+}
+
+// Must is like template.Must for built-in templates.
+func Must(name, text string) *template.Template {
+	return template.Must(Parse(name, text))
+}
+
+// Load reads template or config text from source. A source starting with
+// "ssm:" is read from that SSM parameter; anything else is a file path.
+func Load(sess *session.Session, source string) (string, error) {
+	if strings.HasPrefix(source, SSMPrefix) {
+		return ssm.DecryptValue(sess, strings.TrimPrefix(source, SSMPrefix))
+	}
+	b, err := os.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// LoadTemplate loads and parses the template at source.
+func LoadTemplate(sess *session.Session, source string) (*template.Template, error) {
+	text, err := Load(sess, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", source, err)
+	}
+	return Parse(source, text)
+}
+
+// Execute renders tmpl with data and converts the result into a Slack
+// attachment.
+func Execute(tmpl *template.Template, data interface{}) (*slackhook.Attachment, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	var a Attachment
+	if err := json.Unmarshal(buf.Bytes(), &a); err != nil {
+		return nil, fmt.Errorf("template %s did not render a json attachment: %w", tmpl.Name(), err)
+	}
+
+	attachment := &slackhook.Attachment{
+		Color:     a.Color,
+		Footer:    a.Footer,
+		Text:      a.Text,
+		Title:     a.Title,
+		TitleLink: a.TitleLink,
+	}
+	for _, f := range a.Fields {
+		attachment.Fields = append(attachment.Fields, slackhook.Field(f))
+	}
+	return attachment, nil
+}
+
+// ConsoleURL returns the AWS console home page of service in region,
+// falling back to DefaultRegion.
+func ConsoleURL(service, region string) string {
+	if region == "" {
+		region = DefaultRegion
+	}
+	return fmt.Sprintf("https://console.aws.amazon.com/%s/home?region=%s", service, region)
+}
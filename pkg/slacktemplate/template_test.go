@@ -0,0 +1,67 @@
+package slacktemplate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lytics/slackhook"
+)
+
+func TestExecute(t *testing.T) {
+	text, err := Load(nil, "testdata/attachment.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := Parse("attachment", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := struct {
+		Title  string
+		Fields []string
+		Values []string
+	}{
+		Title:  `"quoted" title`,
+		Fields: []string{"One", "Two"},
+		Values: []string{"a", "b"},
+	}
+	attachment, err := Execute(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &slackhook.Attachment{
+		Title:  `"quoted" title`,
+		Color:  "good",
+		Footer: "tests",
+		Fields: []slackhook.Field{
+			{Title: "One", Value: "a, b", Short: true},
+			{Title: "Two", Value: "a, b", Short: true},
+		},
+	}
+	if !reflect.DeepEqual(attachment, want) {
+		t.Errorf("Execute() = %+v, want %+v", attachment, want)
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	if _, err := Load(nil, "testdata/missing.tmpl"); err == nil {
+		t.Error("Load() of a missing file did not return an error")
+	}
+	if _, err := Execute(Must("bad", "not json"), nil); err == nil {
+		t.Error("Execute() of a template rendering invalid json did not return an error")
+	}
+	if _, err := Execute(Must("bad", "{{ .Missing }}"), struct{}{}); err == nil {
+		t.Error("Execute() of a template with a missing field did not return an error")
+	}
+}
+
+func TestConsoleURL(t *testing.T) {
+	if have := ConsoleURL("iam", "us-east-2"); have != "https://console.aws.amazon.com/iam/home?region=us-east-2" {
+		t.Errorf("ConsoleURL() = %q", have)
+	}
+	if have := ConsoleURL("iam", ""); have != "https://console.aws.amazon.com/iam/home?region=us-west-2" {
+		t.Errorf("ConsoleURL() without a region = %q", have)
+	}
+}
@@ -0,0 +1,151 @@
+// Package slackweb is a small client for the Slack Web API methods the
+// notifiers need: posting, threading and updating Block Kit messages with a
+// bot token.
+package slackweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// DefaultBaseURL is the Slack Web API endpoint.
+const DefaultBaseURL = "https://slack.com/api/"
+
+// Text is a Block Kit text object.
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// PlainText returns a plain_text text object.
+func PlainText(s string) *Text {
+	return &Text{Type: "plain_text", Text: s}
+}
+
+// Markdown returns a mrkdwn text object.
+func Markdown(s string) *Text {
+	return &Text{Type: "mrkdwn", Text: s}
+}
+
+// Block is a Block Kit layout block. Only the fields used by the block's
+// Type are set.
+type Block struct {
+	Type     string  `json:"type"`
+	Text     *Text   `json:"text,omitempty"`
+	Fields   []*Text `json:"fields,omitempty"`
+	Elements []*Text `json:"elements,omitempty"`
+}
+
+// Header returns a header block.
+func Header(s string) Block {
+	return Block{Type: "header", Text: PlainText(s)}
+}
+
+// Section returns a section block with mrkdwn text.
+func Section(s string) Block {
+	return Block{Type: "section", Text: Markdown(s)}
+}
+
+// Fields returns a section block of mrkdwn fields.
+func Fields(fields ...string) Block {
+	b := Block{Type: "section"}
+	for _, f := range fields {
+		b.Fields = append(b.Fields, Markdown(f))
+	}
+	return b
+}
+
+// Context returns a context block of mrkdwn elements.
+func Context(elements ...string) Block {
+	b := Block{Type: "context"}
+	for _, e := range elements {
+		b.Elements = append(b.Elements, Markdown(e))
+	}
+	return b
+}
+
+// Message is a chat.postMessage or chat.update request.
+type Message struct {
+	Channel   string  `json:"channel"`
+	Text      string  `json:"text,omitempty"`
+	Blocks    []Block `json:"blocks,omitempty"`
+	IconEmoji string  `json:"icon_emoji,omitempty"`
+	// ThreadTS posts the message as a reply in that thread.
+	ThreadTS string `json:"thread_ts,omitempty"`
+	// TS is the message chat.update replaces.
+	TS string `json:"ts,omitempty"`
+}
+
+// Response is the part of a Web API response the client uses.
+type Response struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// Client calls the Slack Web API with a bot token.
+type Client struct {
+	// BaseURL defaults to DefaultBaseURL.
+	BaseURL    string
+	HTTPClient *http.Client
+	Token      string
+}
+
+// PostMessage posts a message and returns Slack's response, whose Channel
+// and TS identify the message for replies and updates.
+func (c *Client) PostMessage(ctx context.Context, m *Message) (*Response, error) {
+	return c.call(ctx, "chat.postMessage", m)
+}
+
+// UpdateMessage replaces the message identified by m.Channel and m.TS.
+func (c *Client) UpdateMessage(ctx context.Context, m *Message) (*Response, error) {
+	if m.TS == "" {
+		return nil, errors.New("message ts is required to update a message")
+	}
+	return c.call(ctx, "chat.update", m)
+}
+
+func (c *Client) call(ctx context.Context, method string, body interface{}) (*Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+method, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned http status %d", method, resp.StatusCode)
+	}
+	var r Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if !r.OK {
+		return &r, fmt.Errorf("%s failed: %s", method, r.Error)
+	}
+	return &r, nil
+}
@@ -0,0 +1,60 @@
+package slackweb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	var method, auth string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if body["channel"] == "#missing" {
+			w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "channel": "C123", "ts": "1.000100"}`))
+	}))
+	defer server.Close()
+
+	c := Client{BaseURL: server.URL + "/", Token: "xoxb-test"}
+	resp, err := c.PostMessage(context.Background(), &Message{
+		Channel: "#ops",
+		Blocks:  []Block{Header("Hello"), Fields("*A*\n1", "*B*\n2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Channel != "C123" || resp.TS != "1.000100" {
+		t.Errorf("PostMessage() = %+v", resp)
+	}
+	if method != "/chat.postMessage" || auth != "Bearer xoxb-test" {
+		t.Errorf("called %s with %q", method, auth)
+	}
+	blocks := body["blocks"].([]interface{})
+	if len(blocks) != 2 || blocks[0].(map[string]interface{})["type"] != "header" {
+		t.Errorf("posted blocks = %v", blocks)
+	}
+
+	if _, err := c.UpdateMessage(context.Background(), &Message{Channel: "C123", TS: "1.000100"}); err != nil {
+		t.Fatal(err)
+	}
+	if method != "/chat.update" || body["ts"] != "1.000100" {
+		t.Errorf("called %s with ts %v", method, body["ts"])
+	}
+
+	if _, err := c.UpdateMessage(context.Background(), &Message{Channel: "C123"}); err == nil {
+		t.Error("UpdateMessage() without ts did not return an error")
+	}
+	if _, err := c.PostMessage(context.Background(), &Message{Channel: "#missing"}); err == nil {
+		t.Error("PostMessage() to a missing channel did not return an error")
+	}
+}
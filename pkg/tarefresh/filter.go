@@ -0,0 +1,69 @@
+package tarefresh
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/support"
+)
+
+// Trusted Advisor check categories.
+const (
+	CategoryCostOptimizing = "cost_optimizing"
+	CategorySecurity       = "security"
+	CategoryFaultTolerance = "fault_tolerance"
+	CategoryPerformance    = "performance"
+	CategoryServiceLimits  = "service_limits"
+)
+
+// DefaultLanguage is the language checks are described in when none is set.
+const DefaultLanguage = "en"
+
+// Filter selects checks by category and check ID. Excluded checks never
+// match. When anything is included, only checks in an included category or
+// with an included ID match; otherwise every check does.
+type Filter struct {
+	IncludeCategories []string
+	ExcludeCategories []string
+	IncludeCheckIDs   []string
+	ExcludeCheckIDs   []string
+}
+
+// Matches reports whether the filter selects the check.
+func (f Filter) Matches(check *support.TrustedAdvisorCheckDescription) bool {
+	category := aws.StringValue(check.Category)
+	id := aws.StringValue(check.Id)
+	if contains(f.ExcludeCategories, category) || contains(f.ExcludeCheckIDs, id) {
+		return false
+	}
+	if len(f.IncludeCategories) == 0 && len(f.IncludeCheckIDs) == 0 {
+		return true
+	}
+	return contains(f.IncludeCategories, category) || contains(f.IncludeCheckIDs, id)
+}
+
+// Checks returns the checks the filter selects.
+func (f Filter) Checks(checks []*support.TrustedAdvisorCheckDescription) []*support.TrustedAdvisorCheckDescription {
+	var selected []*support.TrustedAdvisorCheckDescription
+	for _, c := range checks {
+		if f.Matches(c) {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// language returns lang, or DefaultLanguage when it is empty.
+func language(lang string) *string {
+	if lang == "" {
+		return aws.String(DefaultLanguage)
+	}
+	return aws.String(lang)
+}
@@ -0,0 +1,33 @@
+package tarefresh
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/support"
+)
+
+func TestFilterMatches(t *testing.T) {
+	security := &support.TrustedAdvisorCheckDescription{Id: aws.String("HCP4007jGY"), Category: aws.String(CategorySecurity)}
+	cost := &support.TrustedAdvisorCheckDescription{Id: aws.String("Qch7DwouX1"), Category: aws.String(CategoryCostOptimizing)}
+	limits := &support.TrustedAdvisorCheckDescription{Id: aws.String("eW7HH0l7J9"), Category: aws.String(CategoryServiceLimits)}
+
+	var tests = []struct {
+		name   string
+		filter Filter
+		want   []bool
+	}{
+		{"empty", Filter{}, []bool{true, true, true}},
+		{"include category", Filter{IncludeCategories: []string{CategorySecurity}}, []bool{true, false, false}},
+		{"include category and id", Filter{IncludeCategories: []string{CategorySecurity}, IncludeCheckIDs: []string{"eW7HH0l7J9"}}, []bool{true, false, true}},
+		{"exclude category", Filter{ExcludeCategories: []string{CategoryCostOptimizing}}, []bool{true, false, true}},
+		{"exclude id wins", Filter{IncludeCategories: []string{CategorySecurity}, ExcludeCheckIDs: []string{"HCP4007jGY"}}, []bool{false, false, false}},
+	}
+	for _, test := range tests {
+		for i, check := range []*support.TrustedAdvisorCheckDescription{security, cost, limits} {
+			if have := test.filter.Matches(check); have != test.want[i] {
+				t.Errorf("%s: Matches(%s) = %v, want %v", test.name, aws.StringValue(check.Id), have, test.want[i])
+			}
+		}
+	}
+}
@@ -1,64 +1,169 @@
 package tarefresh
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
 	"go.uber.org/zap"
 )
 
+// SupportRegion is the only region the Support API, and so Trusted Advisor,
+// is served from.
+const SupportRegion = "us-east-1"
+
+// Reasons a check is not refreshed.
+const (
+	// SkipNotRefreshable checks are refreshed automatically by AWS, or
+	// can't be refreshed at all, and have no refresh status.
+	SkipNotRefreshable = "not refreshable"
+	// SkipRefreshing checks already have a refresh enqueued or processing.
+	SkipRefreshing = "already refreshing"
+	// SkipTooSoon checks were refreshed within their minimum refresh
+	// interval.
+	SkipTooSoon = "refreshed too recently"
+)
+
+// errCodeInvalidParameterValue is the error code of refresh status requests
+// for checks that have no refresh status. The SDK has no constant for it.
+const errCodeInvalidParameterValue = "InvalidParameterValueException"
+
 // TrustedAdvisorRefresh is a AWS support session for refreshing Trusted Advisor
 type TrustedAdvisorRefresh struct {
+	// Filter selects the checks refreshed. Every check is when it is empty.
+	Filter Filter
+	// Language is the language checks are described in, or
+	// DefaultLanguage when it is empty.
+	Language      string
 	Logger        *zap.Logger
-	SupportClient *support.Support
+	SupportClient supportiface.SupportAPI
+}
+
+// CheckRefresh is what Refresh did with a single check.
+type CheckRefresh struct {
+	CheckID string
+	Name    string
+	// Reason is why a skipped check was not refreshed.
+	Reason string
+	// NextRefreshable is how long until a check skipped as refreshed too
+	// recently can be refreshed again.
+	NextRefreshable time.Duration
+	// Err is why a failed check could not be refreshed.
+	Err error
 }
 
-func isCheckRefreshable(check string) bool {
-	unrefreshableChecks := []string{
-		"AWS Direct Connect Connection Redundancy",
-		"AWS Direct Connect Location Redundancy",
-		"AWS Direct Connect Virtual Interface Redundancy",
-		"PV Driver Version for EC2 Windows Instances",
-		"EC2Config Service for EC2 Windows Instances",
-		"Amazon EBS Public Snapshots",
-		"Amazon RDS Public Snapshots",
+// RefreshSummary lists the checks Refresh refreshed, skipped and failed to
+// refresh.
+type RefreshSummary struct {
+	Refreshed []CheckRefresh
+	Skipped   []CheckRefresh
+	Failed    []CheckRefresh
+}
+
+// Err returns an error naming the checks that failed, or nil if none did.
+func (s *RefreshSummary) Err() error {
+	if len(s.Failed) == 0 {
+		return nil
 	}
+	return fmt.Errorf("failed to refresh %d checks, first %s: %w", len(s.Failed), s.Failed[0].Name, s.Failed[0].Err)
+}
 
-	for _, u := range unrefreshableChecks {
-		if u == check {
-			return false
-		}
+// RefreshedIDs returns the IDs of the refreshed checks.
+func (s *RefreshSummary) RefreshedIDs() []string {
+	var ids []string
+	for _, c := range s.Refreshed {
+		ids = append(ids, c.CheckID)
 	}
-	return true
+	return ids
 }
 
-// Refresh iterates through all Trusted Advisor checks and triggers a refresh.
-// Unrefreshable checks will be logged as an error
-func (r *TrustedAdvisorRefresh) Refresh() error {
+// Refresh triggers a refresh of every Trusted Advisor check the filter
+// selects that can be refreshed now. Checks AWS refreshes automatically, checks already being
+// refreshed and checks inside their minimum refresh interval are skipped.
+// The returned error is only set when the checks could not be listed;
+// failures to refresh single checks are in the summary.
+func (r *TrustedAdvisorRefresh) Refresh() (*RefreshSummary, error) {
 	describeParams := &support.DescribeTrustedAdvisorChecksInput{
-		Language: aws.String("en"),
+		Language: language(r.Language),
 	}
 	resp, err := r.SupportClient.DescribeTrustedAdvisorChecks(describeParams)
 	if err != nil {
 		r.Logger.Error("failed to call DescribeTrustedAdvisorChecks", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	for _, s := range resp.Checks {
-		checkParams := &support.RefreshTrustedAdvisorCheckInput{
-			CheckId: aws.String(*s.Id), // Required
+	summary := &RefreshSummary{}
+	for _, s := range r.Filter.Checks(resp.Checks) {
+		c := r.refreshCheck(s)
+		fields := []zap.Field{
+			zap.String("name", c.Name),
+			zap.String("id", c.CheckID),
 		}
-
-		r.Logger.Info("refreshing",
-			zap.String("name", *s.Name),
-			zap.String("id", *s.Id),
-		)
-		if isCheckRefreshable(*s.Name) {
-			_, err = r.SupportClient.RefreshTrustedAdvisorCheck(checkParams)
-			if err != nil {
-				r.Logger.Error("unable to refresh", zap.Error(err))
-			}
+		switch {
+		case c.Err != nil:
+			r.Logger.Error("unable to refresh", append(fields, zap.Error(c.Err))...)
+			summary.Failed = append(summary.Failed, c)
+		case c.Reason != "":
+			r.Logger.Info("skipping refresh", append(fields,
+				zap.String("reason", c.Reason),
+				zap.Duration("next-refreshable", c.NextRefreshable),
+			)...)
+			summary.Skipped = append(summary.Skipped, c)
+		default:
+			r.Logger.Info("refreshing", fields...)
+			summary.Refreshed = append(summary.Refreshed, c)
 		}
+	}
+	return summary, nil
+}
+
+// refreshCheck refreshes a check if its refresh status allows it.
+func (r *TrustedAdvisorRefresh) refreshCheck(check *support.TrustedAdvisorCheckDescription) CheckRefresh {
+	c := CheckRefresh{
+		CheckID: aws.StringValue(check.Id),
+		Name:    aws.StringValue(check.Name),
+	}
+
+	// Asking for the status of a check AWS refreshes automatically fails
+	// with InvalidParameterValue, so each check is asked about alone.
+	output, err := r.SupportClient.DescribeTrustedAdvisorCheckRefreshStatuses(&support.DescribeTrustedAdvisorCheckRefreshStatusesInput{
+		CheckIds: []*string{check.Id},
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == errCodeInvalidParameterValue {
+		c.Reason = SkipNotRefreshable
+		return c
+	}
+	if err != nil {
+		c.Err = fmt.Errorf("failed to get refresh status: %w", err)
+		return c
+	}
+	if len(output.Statuses) == 0 {
+		c.Reason = SkipNotRefreshable
+		return c
+	}
+
+	status := output.Statuses[0]
+	switch aws.StringValue(status.Status) {
+	case RefreshStatusEnqueued, RefreshStatusProcessing:
+		c.Reason = SkipRefreshing
+		return c
+	}
+	if next := aws.Int64Value(status.MillisUntilNextRefreshable); next > 0 {
+		c.Reason = SkipTooSoon
+		c.NextRefreshable = time.Duration(next) * time.Millisecond
+		return c
+	}
 
+	_, err = r.SupportClient.RefreshTrustedAdvisorCheck(&support.RefreshTrustedAdvisorCheckInput{
+		CheckId: check.Id,
+	})
+	if err != nil {
+		c.Err = err
 	}
-	return err
+	return c
 }
@@ -1,17 +1,144 @@
 package tarefresh
 
 import (
+	"errors"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
 )
 
-func TestIsCheckRefreshable(t *testing.T) {
-	refreshable := isCheckRefreshable("I'm Refreshable")
-	if !refreshable {
-		t.Fatalf("isCheckRefreshable() is false, \n want true")
+// mockRefreshClient serves refresh statuses by check ID. Checks without a
+// status are refreshed automatically.
+type mockRefreshClient struct {
+	supportiface.SupportAPI
+	Checks     []*support.TrustedAdvisorCheckDescription
+	Statuses   map[string]*support.TrustedAdvisorCheckRefreshStatus
+	FailIDs    map[string]bool
+	Refreshed  []string
+	StatusErrs map[string]error
+	Language   string
+}
+
+func (m *mockRefreshClient) DescribeTrustedAdvisorChecks(input *support.DescribeTrustedAdvisorChecksInput) (*support.DescribeTrustedAdvisorChecksOutput, error) {
+	m.Language = aws.StringValue(input.Language)
+	return &support.DescribeTrustedAdvisorChecksOutput{Checks: m.Checks}, nil
+}
+
+func (m *mockRefreshClient) DescribeTrustedAdvisorCheckRefreshStatuses(input *support.DescribeTrustedAdvisorCheckRefreshStatusesInput) (*support.DescribeTrustedAdvisorCheckRefreshStatusesOutput, error) {
+	id := aws.StringValue(input.CheckIds[0])
+	if err, ok := m.StatusErrs[id]; ok {
+		return nil, err
+	}
+	status, ok := m.Statuses[id]
+	if !ok {
+		return nil, awserr.New(errCodeInvalidParameterValue, "check is not refreshable", nil)
+	}
+	return &support.DescribeTrustedAdvisorCheckRefreshStatusesOutput{
+		Statuses: []*support.TrustedAdvisorCheckRefreshStatus{status},
+	}, nil
+}
+
+func (m *mockRefreshClient) RefreshTrustedAdvisorCheck(input *support.RefreshTrustedAdvisorCheckInput) (*support.RefreshTrustedAdvisorCheckOutput, error) {
+	id := aws.StringValue(input.CheckId)
+	if m.FailIDs[id] {
+		return nil, errors.New("refresh failed")
 	}
-	notRefreshable := isCheckRefreshable("AWS Direct Connect Connection Redundancy")
-	if notRefreshable {
-		t.Fatalf("isCheckRefreshable() is true, \n want false")
+	m.Refreshed = append(m.Refreshed, id)
+	return &support.RefreshTrustedAdvisorCheckOutput{}, nil
+}
+
+func refreshStatus(status string, millisUntilNext int64) *support.TrustedAdvisorCheckRefreshStatus {
+	return &support.TrustedAdvisorCheckRefreshStatus{
+		Status:                     aws.String(status),
+		MillisUntilNextRefreshable: aws.Int64(millisUntilNext),
 	}
+}
 
+func TestRefresh(t *testing.T) {
+	m := &mockRefreshClient{
+		Statuses: map[string]*support.TrustedAdvisorCheckRefreshStatus{
+			"ready":      refreshStatus(RefreshStatusSuccess, 0),
+			"never":      refreshStatus(RefreshStatusNone, 0),
+			"processing": refreshStatus(RefreshStatusProcessing, 0),
+			"recent":     refreshStatus(RefreshStatusSuccess, 60000),
+			"fails":      refreshStatus(RefreshStatusSuccess, 0),
+		},
+		FailIDs:    map[string]bool{"fails": true},
+		StatusErrs: map[string]error{"throttled": awserr.New(support.ErrCodeThrottlingException, "slow down", nil)},
+	}
+	for _, id := range []string{"ready", "never", "automatic", "processing", "recent", "fails", "throttled"} {
+		m.Checks = append(m.Checks, &support.TrustedAdvisorCheckDescription{Id: aws.String(id), Name: aws.String(id + " check")})
+	}
+
+	r := TrustedAdvisorRefresh{Logger: logger, SupportClient: m}
+	summary, err := r.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"ready", "never"}; !reflect.DeepEqual(m.Refreshed, want) {
+		t.Errorf("refreshed %v, want %v", m.Refreshed, want)
+	}
+	if want := []string{"ready", "never"}; !reflect.DeepEqual(summary.RefreshedIDs(), want) {
+		t.Errorf("RefreshedIDs() = %v, want %v", summary.RefreshedIDs(), want)
+	}
+
+	var skipped []string
+	for _, c := range summary.Skipped {
+		skipped = append(skipped, c.CheckID+": "+c.Reason)
+	}
+	wantSkipped := []string{
+		"automatic: " + SkipNotRefreshable,
+		"processing: " + SkipRefreshing,
+		"recent: " + SkipTooSoon,
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped %v, want %v", skipped, wantSkipped)
+	}
+	if summary.Skipped[2].NextRefreshable != time.Minute {
+		t.Errorf("next refreshable = %v, want 1m", summary.Skipped[2].NextRefreshable)
+	}
+
+	if len(summary.Failed) != 2 || summary.Failed[0].CheckID != "fails" || summary.Failed[1].CheckID != "throttled" {
+		t.Errorf("failed = %+v", summary.Failed)
+	}
+	if summary.Err() == nil {
+		t.Error("Err() = nil with failed checks")
+	}
+	if (&RefreshSummary{}).Err() != nil {
+		t.Error("Err() of an empty summary is not nil")
+	}
+}
+
+func TestRefreshFilter(t *testing.T) {
+	m := &mockRefreshClient{
+		Checks: []*support.TrustedAdvisorCheckDescription{
+			{Id: aws.String("sec"), Name: aws.String("security check"), Category: aws.String(CategorySecurity)},
+			{Id: aws.String("cost"), Name: aws.String("cost check"), Category: aws.String(CategoryCostOptimizing)},
+		},
+		Statuses: map[string]*support.TrustedAdvisorCheckRefreshStatus{
+			"sec":  refreshStatus(RefreshStatusSuccess, 0),
+			"cost": refreshStatus(RefreshStatusSuccess, 0),
+		},
+	}
+	r := TrustedAdvisorRefresh{
+		Filter:        Filter{IncludeCategories: []string{CategorySecurity}},
+		Language:      "ja",
+		Logger:        logger,
+		SupportClient: m,
+	}
+	if _, err := r.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sec"}; !reflect.DeepEqual(m.Refreshed, want) {
+		t.Errorf("refreshed %v, want %v", m.Refreshed, want)
+	}
+	if m.Language != "ja" {
+		t.Errorf("language = %q, want ja", m.Language)
+	}
 }
@@ -0,0 +1,252 @@
+package tarefresh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
+	"go.uber.org/zap"
+)
+
+// Trusted Advisor check statuses.
+const (
+	StatusNotAvailable = "not_available"
+	StatusOK           = "ok"
+	StatusWarning      = "warning"
+	StatusError        = "error"
+)
+
+// Trusted Advisor refresh statuses.
+const (
+	RefreshStatusNone       = "none"
+	RefreshStatusEnqueued   = "enqueued"
+	RefreshStatusProcessing = "processing"
+	RefreshStatusSuccess    = "success"
+	RefreshStatusAbandoned  = "abandoned"
+)
+
+// DefaultPollInterval is how often refresh statuses are checked.
+const DefaultPollInterval = 5 * time.Second
+
+// statusRank orders check statuses from best to worst.
+func statusRank(status string) int {
+	switch status {
+	case StatusOK:
+		return 1
+	case StatusWarning:
+		return 2
+	case StatusError:
+		return 3
+	}
+	return 0
+}
+
+// FlaggedResource is a resource a check flagged.
+type FlaggedResource struct {
+	ResourceID string   `json:"resource_id"`
+	Region     string   `json:"region,omitempty"`
+	Status     string   `json:"status"`
+	Metadata   []string `json:"metadata,omitempty"`
+}
+
+// CheckResult is the latest result of a Trusted Advisor check.
+type CheckResult struct {
+	CheckID                 string            `json:"check_id"`
+	Name                    string            `json:"name"`
+	Category                string            `json:"category"`
+	Status                  string            `json:"status"`
+	ResourcesFlagged        int64             `json:"resources_flagged"`
+	EstimatedMonthlySavings float64           `json:"estimated_monthly_savings,omitempty"`
+	FlaggedResources        []FlaggedResource `json:"flagged_resources,omitempty"`
+	// PreviousStatus is set by Worsened to the status of the last run.
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
+
+// TrustedAdvisorReport collects Trusted Advisor check results once their
+// refreshes have finished.
+type TrustedAdvisorReport struct {
+	// Filter selects the checks reported. Every check is when it is empty.
+	Filter Filter
+	// Language is the language checks are described in, or
+	// DefaultLanguage when it is empty.
+	Language      string
+	Logger        *zap.Logger
+	SupportClient supportiface.SupportAPI
+	// PollInterval is how often refresh statuses are checked, or
+	// DefaultPollInterval when it is 0.
+	PollInterval time.Duration
+	// Timeout limits how long to wait for refreshes. There is no limit
+	// when it is 0.
+	Timeout time.Duration
+}
+
+// Report waits for the refreshes of the refreshed check IDs, usually from
+// RefreshSummary.RefreshedIDs, to finish and returns the result of every
+// check the filter selects.
+func (r *TrustedAdvisorReport) Report(ctx context.Context, refreshed []string) ([]CheckResult, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	output, err := r.SupportClient.DescribeTrustedAdvisorChecksWithContext(ctx, &support.DescribeTrustedAdvisorChecksInput{
+		Language: language(r.Language),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trusted advisor checks: %w", err)
+	}
+
+	if err := r.WaitForRefresh(ctx, aws.StringSlice(refreshed)); err != nil {
+		return nil, err
+	}
+
+	var results []CheckResult
+	for _, c := range r.Filter.Checks(output.Checks) {
+		result, err := r.checkResult(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// WaitForRefresh polls the refresh status of the checks until none of them
+// is enqueued or processing.
+func (r *TrustedAdvisorReport) WaitForRefresh(ctx context.Context, checkIDs []*string) error {
+	if len(checkIDs) == 0 {
+		return nil
+	}
+	interval := r.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		output, err := r.SupportClient.DescribeTrustedAdvisorCheckRefreshStatusesWithContext(ctx,
+			&support.DescribeTrustedAdvisorCheckRefreshStatusesInput{CheckIds: checkIDs})
+		if err != nil {
+			return fmt.Errorf("failed to describe trusted advisor refresh statuses: %w", err)
+		}
+
+		pending := 0
+		for _, s := range output.Statuses {
+			switch aws.StringValue(s.Status) {
+			case RefreshStatusEnqueued, RefreshStatusProcessing:
+				pending++
+			}
+		}
+		if pending == 0 {
+			return nil
+		}
+		r.Logger.Info("waiting for trusted advisor refreshes", zap.Int("pending", pending))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting for %d trusted advisor refreshes: %w", pending, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (r *TrustedAdvisorReport) checkResult(ctx context.Context, check *support.TrustedAdvisorCheckDescription) (CheckResult, error) {
+	result := CheckResult{
+		CheckID:  aws.StringValue(check.Id),
+		Name:     aws.StringValue(check.Name),
+		Category: aws.StringValue(check.Category),
+	}
+	output, err := r.SupportClient.DescribeTrustedAdvisorCheckResultWithContext(ctx, &support.DescribeTrustedAdvisorCheckResultInput{
+		CheckId:  check.Id,
+		Language: language(r.Language),
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to get result of %s: %w", result.Name, err)
+	}
+
+	c := output.Result
+	if c == nil {
+		result.Status = StatusNotAvailable
+		return result, nil
+	}
+	result.Status = aws.StringValue(c.Status)
+	if c.ResourcesSummary != nil {
+		result.ResourcesFlagged = aws.Int64Value(c.ResourcesSummary.ResourcesFlagged)
+	}
+	if c.CategorySpecificSummary != nil && c.CategorySpecificSummary.CostOptimizing != nil {
+		result.EstimatedMonthlySavings = aws.Float64Value(c.CategorySpecificSummary.CostOptimizing.EstimatedMonthlySavings)
+	}
+	for _, f := range c.FlaggedResources {
+		if aws.BoolValue(f.IsSuppressed) {
+			continue
+		}
+		result.FlaggedResources = append(result.FlaggedResources, FlaggedResource{
+			ResourceID: aws.StringValue(f.ResourceId),
+			Region:     aws.StringValue(f.Region),
+			Status:     aws.StringValue(f.Status),
+			Metadata:   aws.StringValueSlice(f.Metadata),
+		})
+	}
+	return result, nil
+}
+
+// Statuses updates the previous statuses with results, for the next run to
+// compare against. Checks that are not in results keep their previous
+// status, so runs that report on different checks can share the state.
+// Only statuses worse than ok are kept: Worsened treats missing checks as
+// ok, and leaving the rest out keeps the stored state small.
+func Statuses(previous map[string]string, results []CheckResult) map[string]string {
+	statuses := map[string]string{}
+	for id, status := range previous {
+		statuses[id] = status
+	}
+	for _, r := range results {
+		if statusRank(r.Status) > statusRank(StatusOK) {
+			statuses[r.CheckID] = r.Status
+		} else {
+			delete(statuses, r.CheckID)
+		}
+	}
+	return statuses
+}
+
+// Worsened returns the results whose status is worse than in previous, with
+// PreviousStatus set. Checks missing from previous are compared against ok,
+// so the first run reports every check that is not ok.
+func Worsened(results []CheckResult, previous map[string]string) []CheckResult {
+	var worsened []CheckResult
+	for _, r := range results {
+		before, ok := previous[r.CheckID]
+		if !ok {
+			before = StatusOK
+		}
+		if statusRank(r.Status) > statusRank(before) {
+			r.PreviousStatus = before
+			worsened = append(worsened, r)
+		}
+	}
+	return worsened
+}
+
+// WriteReport writes a table of check results to w, followed by a table of
+// the resources they flagged.
+func WriteReport(w io.Writer, results []CheckResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCATEGORY\tCHECK\tFLAGGED\tEST. MONTHLY SAVINGS")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f\n", r.Status, r.Category, r.Name, r.ResourcesFlagged, r.EstimatedMonthlySavings)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CHECK\tREGION\tRESOURCE\tSTATUS")
+	for _, r := range results {
+		for _, f := range r.FlaggedResources {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, f.Region, f.ResourceID, f.Status)
+		}
+	}
+	return tw.Flush()
+}
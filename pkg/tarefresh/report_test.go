@@ -0,0 +1,194 @@
+package tarefresh
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/support"
+	"github.com/aws/aws-sdk-go/service/support/supportiface"
+	"go.uber.org/zap"
+)
+
+var logger, _ = zap.NewProduction()
+
+type mockSupportClient struct {
+	supportiface.SupportAPI
+	Checks  []*support.TrustedAdvisorCheckDescription
+	Results map[string]*support.TrustedAdvisorCheckResult
+	// Pending is how many refresh status polls report a check still
+	// processing.
+	Pending      int
+	StatusPolls  int
+	StatusChecks []string
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorChecksWithContext(ctx aws.Context, input *support.DescribeTrustedAdvisorChecksInput, opts ...request.Option) (*support.DescribeTrustedAdvisorChecksOutput, error) {
+	return &support.DescribeTrustedAdvisorChecksOutput{Checks: m.Checks}, nil
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorCheckRefreshStatusesWithContext(ctx aws.Context, input *support.DescribeTrustedAdvisorCheckRefreshStatusesInput, opts ...request.Option) (*support.DescribeTrustedAdvisorCheckRefreshStatusesOutput, error) {
+	m.StatusPolls++
+	m.StatusChecks = aws.StringValueSlice(input.CheckIds)
+	status := RefreshStatusSuccess
+	if m.StatusPolls <= m.Pending {
+		status = RefreshStatusProcessing
+	}
+	output := &support.DescribeTrustedAdvisorCheckRefreshStatusesOutput{}
+	for _, id := range input.CheckIds {
+		output.Statuses = append(output.Statuses, &support.TrustedAdvisorCheckRefreshStatus{
+			CheckId: id,
+			Status:  aws.String(status),
+		})
+	}
+	return output, nil
+}
+
+func (m *mockSupportClient) DescribeTrustedAdvisorCheckResultWithContext(ctx aws.Context, input *support.DescribeTrustedAdvisorCheckResultInput, opts ...request.Option) (*support.DescribeTrustedAdvisorCheckResultOutput, error) {
+	return &support.DescribeTrustedAdvisorCheckResultOutput{Result: m.Results[aws.StringValue(input.CheckId)]}, nil
+}
+
+func testSupportClient() *mockSupportClient {
+	return &mockSupportClient{
+		Checks: []*support.TrustedAdvisorCheckDescription{
+			{Id: aws.String("Qch7DwouX1"), Name: aws.String("Low Utilization Amazon EC2 Instances"), Category: aws.String("cost_optimizing")},
+			{Id: aws.String("HCP4007jGY"), Name: aws.String("Security Groups - Specific Ports Unrestricted"), Category: aws.String("security")},
+			{Id: aws.String("ePs02jT06w"), Name: aws.String("Amazon EBS Public Snapshots"), Category: aws.String("security")},
+		},
+		Results: map[string]*support.TrustedAdvisorCheckResult{
+			"Qch7DwouX1": {
+				Status: aws.String(StatusWarning),
+				CategorySpecificSummary: &support.TrustedAdvisorCategorySpecificSummary{
+					CostOptimizing: &support.TrustedAdvisorCostOptimizingSummary{EstimatedMonthlySavings: aws.Float64(41.5)},
+				},
+				ResourcesSummary: &support.TrustedAdvisorResourcesSummary{ResourcesFlagged: aws.Int64(2)},
+				FlaggedResources: []*support.TrustedAdvisorResourceDetail{
+					{ResourceId: aws.String("i-1"), Region: aws.String("us-west-2"), Status: aws.String(StatusWarning)},
+					{ResourceId: aws.String("i-2"), Region: aws.String("us-west-2"), Status: aws.String(StatusWarning), IsSuppressed: aws.Bool(true)},
+				},
+			},
+			"HCP4007jGY": {
+				Status:           aws.String(StatusOK),
+				ResourcesSummary: &support.TrustedAdvisorResourcesSummary{ResourcesFlagged: aws.Int64(0)},
+			},
+		},
+		Pending: 2,
+	}
+}
+
+func TestTrustedAdvisorReport(t *testing.T) {
+	m := testSupportClient()
+	r := TrustedAdvisorReport{Logger: logger, SupportClient: m, PollInterval: time.Millisecond}
+
+	results, err := r.Report(context.Background(), []string{"Qch7DwouX1", "HCP4007jGY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.StatusPolls != 3 {
+		t.Errorf("refresh status polls = %d, want 3", m.StatusPolls)
+	}
+	if want := []string{"Qch7DwouX1", "HCP4007jGY"}; !reflect.DeepEqual(m.StatusChecks, want) {
+		t.Errorf("waited on %v, want %v", m.StatusChecks, want)
+	}
+
+	want := []CheckResult{
+		{
+			CheckID:                 "Qch7DwouX1",
+			Name:                    "Low Utilization Amazon EC2 Instances",
+			Category:                "cost_optimizing",
+			Status:                  StatusWarning,
+			ResourcesFlagged:        2,
+			EstimatedMonthlySavings: 41.5,
+			FlaggedResources:        []FlaggedResource{{ResourceID: "i-1", Region: "us-west-2", Status: StatusWarning, Metadata: []string{}}},
+		},
+		{CheckID: "HCP4007jGY", Name: "Security Groups - Specific Ports Unrestricted", Category: "security", Status: StatusOK},
+		{CheckID: "ePs02jT06w", Name: "Amazon EBS Public Snapshots", Category: "security", Status: StatusNotAvailable},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Report() = %+v, want %+v", results, want)
+	}
+}
+
+func TestTrustedAdvisorReportTimeout(t *testing.T) {
+	m := testSupportClient()
+	m.Pending = 1000
+	r := TrustedAdvisorReport{Logger: logger, SupportClient: m, PollInterval: time.Millisecond, Timeout: 20 * time.Millisecond}
+	if _, err := r.Report(context.Background(), []string{"Qch7DwouX1"}); err == nil {
+		t.Error("Report() with refreshes that never finish returned no error")
+	}
+}
+
+func TestWorsened(t *testing.T) {
+	results := []CheckResult{
+		{CheckID: "a", Status: StatusWarning},
+		{CheckID: "b", Status: StatusError},
+		{CheckID: "c", Status: StatusWarning},
+		{CheckID: "d", Status: StatusOK},
+		{CheckID: "e", Status: StatusWarning},
+	}
+	previous := map[string]string{"a": StatusOK, "b": StatusWarning, "c": StatusError, "d": StatusWarning}
+
+	var have []string
+	for _, r := range Worsened(results, previous) {
+		have = append(have, r.CheckID+":"+r.PreviousStatus+">"+r.Status)
+	}
+	want := []string{"a:ok>warning", "b:warning>error", "e:ok>warning"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("Worsened() = %v, want %v", have, want)
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	results := []CheckResult{
+		{CheckID: "a", Status: StatusOK},
+		{CheckID: "b", Status: StatusWarning},
+		{CheckID: "c", Status: StatusError},
+		{CheckID: "d", Status: "not_available"},
+	}
+	previous := map[string]string{"a": StatusWarning, "c": StatusWarning, "z": StatusError}
+	want := map[string]string{"b": StatusWarning, "c": StatusError, "z": StatusError}
+	if have := Statuses(previous, results); !reflect.DeepEqual(have, want) {
+		t.Errorf("Statuses() = %v, want %v", have, want)
+	}
+}
+
+func TestFileStatusStore(t *testing.T) {
+	s := FileStatusStore{Path: filepath.Join(t.TempDir(), "statuses.json")}
+	statuses, err := s.GetStatuses()
+	if err != nil || statuses != nil {
+		t.Fatalf("GetStatuses() before the first run = %v, %v", statuses, err)
+	}
+	want := map[string]string{"a": StatusOK, "b": StatusError}
+	if err := s.PutStatuses(want); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err = s.GetStatuses()
+	if err != nil || !reflect.DeepEqual(statuses, want) {
+		t.Errorf("GetStatuses() = %v, %v, want %v", statuses, err, want)
+	}
+}
+
+func TestSlackMessage(t *testing.T) {
+	s := SlackAlert{Channel: "#aws", Emoji: ":mag:"}
+	m := s.Message([]CheckResult{
+		{Name: "Low Utilization Amazon EC2 Instances", Category: "cost_optimizing", Status: StatusWarning, PreviousStatus: StatusOK, ResourcesFlagged: 2, EstimatedMonthlySavings: 41.5},
+		{Name: "IAM Use", Category: "security", Status: StatusError, PreviousStatus: StatusWarning},
+	})
+	if len(m.Attachments) != 2 {
+		t.Fatalf("attachments = %d, want 2", len(m.Attachments))
+	}
+	a := m.Attachments[0]
+	if a.Text != "Status went from ok to warning" || a.Color != "warning" || len(a.Fields) != 3 {
+		t.Errorf("first attachment = %+v", a)
+	}
+	if a.TitleLink != ConsoleURL+"#/category/cost-optimizing" {
+		t.Errorf("title link = %s", a.TitleLink)
+	}
+	if m.Attachments[1].Color != "danger" || len(m.Attachments[1].Fields) != 2 {
+		t.Errorf("second attachment = %+v", m.Attachments[1])
+	}
+}
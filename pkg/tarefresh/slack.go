@@ -0,0 +1,67 @@
+package tarefresh
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lytics/slackhook"
+)
+
+// ConsoleURL is the Trusted Advisor console.
+const ConsoleURL = "https://console.aws.amazon.com/trustedadvisor/home"
+
+// SlackAlert defines where to post checks whose status got worse.
+type SlackAlert struct {
+	Channel    string
+	Emoji      string
+	WebhookURL string
+}
+
+// statusColor is the attachment color of a check status.
+func statusColor(status string) string {
+	if status == StatusError {
+		return "danger"
+	}
+	return "warning"
+}
+
+// categoryURL links to a check category in the Trusted Advisor console.
+func categoryURL(category string) string {
+	return fmt.Sprintf("%s#/category/%s", ConsoleURL, strings.ReplaceAll(category, "_", "-"))
+}
+
+// Message builds the Slack message with one attachment per worsened check.
+func (s *SlackAlert) Message(worsened []CheckResult) *slackhook.Message {
+	message := &slackhook.Message{
+		Channel:   s.Channel,
+		IconEmoji: s.Emoji,
+	}
+	for _, r := range worsened {
+		attachment := &slackhook.Attachment{
+			Title:     r.Name,
+			TitleLink: categoryURL(r.Category),
+			Text:      fmt.Sprintf("Status went from %s to %s", r.PreviousStatus, r.Status),
+			Color:     statusColor(r.Status),
+			Footer:    "Trusted Advisor",
+			Fields: []slackhook.Field{
+				{Title: "Category", Value: r.Category, Short: true},
+				{Title: "Resources Flagged", Value: fmt.Sprint(r.ResourcesFlagged), Short: true},
+			},
+		}
+		if r.EstimatedMonthlySavings > 0 {
+			attachment.Fields = append(attachment.Fields, slackhook.Field{
+				Title: "Estimated Monthly Savings",
+				Value: fmt.Sprintf("$%.2f", r.EstimatedMonthlySavings),
+				Short: true,
+			})
+		}
+		message.AddAttachment(attachment)
+	}
+	return message
+}
+
+// Send posts the worsened checks to the Slack webhook.
+func (s *SlackAlert) Send(worsened []CheckResult) error {
+	slack := slackhook.New(s.WebhookURL)
+	return slack.Send(s.Message(worsened))
+}
@@ -0,0 +1,101 @@
+package tarefresh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// StatusStore remembers each check's status between runs.
+type StatusStore interface {
+	// GetStatuses returns the statuses of the last run, or nil before the
+	// first run.
+	GetStatuses() (map[string]string, error)
+	PutStatuses(statuses map[string]string) error
+}
+
+// FileStatusStore keeps check statuses in a local JSON file.
+type FileStatusStore struct {
+	Path string
+}
+
+// GetStatuses implements StatusStore.
+func (f *FileStatusStore) GetStatuses() (map[string]string, error) {
+	content, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read check statuses: %w", err)
+	}
+	return parseStatuses(content)
+}
+
+// PutStatuses implements StatusStore.
+func (f *FileStatusStore) PutStatuses(statuses map[string]string) error {
+	content, err := json.Marshal(statuses)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(f.Path, append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write check statuses: %w", err)
+	}
+	return nil
+}
+
+// SSMStatusStore keeps check statuses as JSON in a Parameter Store string
+// parameter. The parameter uses intelligent tiering, so it stays in the
+// free standard tier until the statuses outgrow its 4 KB limit.
+type SSMStatusStore struct {
+	SSMClient ssmiface.SSMAPI
+	Name      string
+}
+
+// GetStatuses implements StatusStore.
+func (s *SSMStatusStore) GetStatuses() (map[string]string, error) {
+	output, err := s.SSMClient.GetParameter(&ssm.GetParameterInput{
+		Name: aws.String(s.Name),
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get check statuses parameter %s: %w", s.Name, err)
+	}
+	return parseStatuses([]byte(aws.StringValue(output.Parameter.Value)))
+}
+
+// PutStatuses implements StatusStore.
+func (s *SSMStatusStore) PutStatuses(statuses map[string]string) error {
+	content, err := json.Marshal(statuses)
+	if err != nil {
+		return err
+	}
+	_, err = s.SSMClient.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(s.Name),
+		Overwrite: aws.Bool(true),
+		Tier:      aws.String(ssm.ParameterTierIntelligentTiering),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Value:     aws.String(string(content)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put check statuses parameter %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+func parseStatuses(content []byte) (map[string]string, error) {
+	var statuses map[string]string
+	if err := json.Unmarshal(content, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to parse check statuses: %w", err)
+	}
+	return statuses, nil
+}